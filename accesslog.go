@@ -0,0 +1,197 @@
+package log
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// AccessFormat selects the Apache access log format.
+type AccessFormat int
+
+const (
+	// CombinedFormat is the NCSA combined format, with referer and user
+	// agent.
+	CombinedFormat AccessFormat = iota
+	// CommonFormat is the NCSA common log format.
+	CommonFormat
+)
+
+// AccessEntry is the data of one HTTP request for the access log.
+type AccessEntry struct {
+	Time       time.Time
+	RemoteAddr string
+	User       string
+	Method     string
+	URI        string
+	Proto      string
+	Status     int
+	Size       int64
+	Referer    string
+	UserAgent  string
+}
+
+// AccessLog writes HTTP requests as Apache access log lines to an output,
+// for analytics tools that do not ingest JSON.
+type AccessLog struct {
+	mu     sync.Mutex
+	out    zapcore.WriteSyncer
+	format AccessFormat
+}
+
+// NewAccessLog returns an AccessLog writing to out in the given format.
+func NewAccessLog(out zapcore.WriteSyncer, format AccessFormat) *AccessLog {
+	return &AccessLog{out: out, format: format}
+}
+
+// Log writes one line for e.
+func (a *AccessLog) Log(e AccessEntry) error {
+	buf := bufferPool.Get()
+	defer buf.Free()
+	appendAccessLine(buf, e, a.format)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, err := a.out.Write(buf.Bytes())
+	return err
+}
+
+// Sync syncs the output.
+func (a *AccessLog) Sync() error {
+	return a.out.Sync()
+}
+
+// Handler returns middleware logging every request served by next.
+func (a *AccessLog) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		user := "-"
+		if r.URL.User != nil {
+			user = r.URL.User.Username()
+		} else if u, _, ok := r.BasicAuth(); ok {
+			user = u
+		}
+		a.Log(AccessEntry{
+			Time:       start,
+			RemoteAddr: r.RemoteAddr,
+			User:       user,
+			Method:     r.Method,
+			URI:        r.RequestURI,
+			Proto:      r.Proto,
+			Status:     rec.status(),
+			Size:       rec.size,
+			Referer:    r.Referer(),
+			UserAgent:  r.UserAgent(),
+		})
+	})
+}
+
+// statusRecorder captures the status and body size of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+	size int64
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if r.code == 0 {
+		r.code = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(p []byte) (int, error) {
+	if r.code == 0 {
+		r.code = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *statusRecorder) status() int {
+	if r.code == 0 {
+		return http.StatusOK
+	}
+	return r.code
+}
+
+// Flush lets streaming handlers flush through the recorder.
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// appendAccessLine formats e as
+//
+//	host ident user [time] "request" status size "referer" "user-agent"
+//
+// leaving out the last two fields in the common format.
+func appendAccessLine(buf *buffer.Buffer, e AccessEntry, format AccessFormat) {
+	host := e.RemoteAddr
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	appendAccessField(buf, host)
+	buf.AppendString(" - ")
+	appendAccessField(buf, e.User)
+	buf.AppendString(" [")
+	buf.AppendTime(e.Time, "02/Jan/2006:15:04:05 -0700")
+	buf.AppendString(`] "`)
+	appendAccessQuoted(buf, e.Method+" "+e.URI+" "+e.Proto)
+	buf.AppendString(`" `)
+	buf.AppendInt(int64(e.Status))
+	buf.AppendByte(' ')
+	if e.Size > 0 {
+		buf.AppendInt(e.Size)
+	} else {
+		buf.AppendByte('-')
+	}
+	if format == CombinedFormat {
+		buf.AppendString(` "`)
+		appendAccessField(buf, e.Referer)
+		buf.AppendString(`" "`)
+		appendAccessField(buf, e.UserAgent)
+		buf.AppendByte('"')
+	}
+	buf.AppendByte('\n')
+}
+
+// appendAccessField writes a field, "-" if it is empty.
+func appendAccessField(buf *buffer.Buffer, s string) {
+	if s == "" {
+		buf.AppendByte('-')
+		return
+	}
+	appendAccessQuoted(buf, s)
+}
+
+// appendAccessQuoted escapes quotes, backslashes and control characters
+// the way Apache does.
+func appendAccessQuoted(buf *buffer.Buffer, s string) {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			buf.AppendByte('\\')
+			buf.AppendByte(c)
+		case c < 0x20 || c == 0x7f:
+			buf.AppendString(`\x`)
+			if c < 0x10 {
+				buf.AppendByte('0')
+			}
+			buf.AppendString(strconv.FormatUint(uint64(c), 16))
+		default:
+			buf.AppendByte(c)
+		}
+	}
+}
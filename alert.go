@@ -0,0 +1,211 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// AlertConfig describes the error burst alert, see NewAlertCore.
+type AlertConfig struct {
+	// URL is the webhook the alerts are posted to.
+	URL string
+	// Threshold is the number of errors within Window that raises an
+	// alert. Defaults to 10 errors per minute.
+	Threshold int
+	Window    time.Duration
+	// Entries is the number of most recent errors attached to an alert.
+	// Defaults to 10.
+	Entries int
+	// Cooldown is the minimum time between two alerts. Defaults to Window.
+	Cooldown time.Duration
+	// Headers are added to the requests, e.g. for authentication.
+	Headers    map[string]string
+	HTTPClient *http.Client
+}
+
+// Alert is the JSON body posted to the webhook: Threshold errors were
+// logged within Window, the last of which are in Entries.
+type Alert struct {
+	Host      string            `json:"host"`
+	Program   string            `json:"program"`
+	Time      time.Time         `json:"time"`
+	Window    string            `json:"window"`
+	Threshold int               `json:"threshold"`
+	Entries   []json.RawMessage `json:"entries"`
+}
+
+// alerter tracks the errors of all the clones of an alert core.
+type alerter struct {
+	cfg  AlertConfig
+	host string
+
+	mu      sync.Mutex
+	times   []time.Time // ring of the times of the last Threshold errors
+	next    int
+	recent  [][]byte // ring of the last Entries errors
+	last    time.Time
+	sending bool
+	err     error
+}
+
+type alertCore struct {
+	a   *alerter
+	enc zapcore.Encoder
+}
+
+// NewAlertCore returns a core posting an alert to a webhook when the
+// errors logged exceed a rate, with the last of them attached, for
+// deployments without an alerting stack of their own. Attach it with
+// WithCore; entries below ERROR are ignored. Alerts are sent in the
+// background, one at a time; the error of the last failed one is returned
+// by Sync.
+func NewAlertCore(cfg AlertConfig) (zapcore.Core, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("log: alert webhook URL is required")
+	}
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = 10
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = time.Minute
+	}
+	if cfg.Entries <= 0 {
+		cfg.Entries = 10
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = cfg.Window
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	host, _ := os.Hostname()
+	a := &alerter{
+		cfg:   cfg,
+		host:  host,
+		times: make([]time.Time, 0, cfg.Threshold),
+	}
+	return &alertCore{a: a, enc: zapcore.NewJSONEncoder(NewEncoderConfig())}, nil
+}
+
+func (c *alertCore) Enabled(lvl zapcore.Level) bool {
+	return lvl >= zapcore.ErrorLevel
+}
+
+func (c *alertCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &alertCore{a: c.a, enc: c.enc.Clone()}
+	for i := range fields {
+		fields[i].AddTo(clone.enc)
+	}
+	return clone
+}
+
+func (c *alertCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *alertCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	entry := append([]byte(nil), bytes.TrimSpace(buf.Bytes())...)
+	buf.Free()
+	c.a.add(ent.Time, entry)
+	return nil
+}
+
+// Sync returns the error of the last alert that could not be sent since
+// the previous call.
+func (c *alertCore) Sync() error {
+	c.a.mu.Lock()
+	defer c.a.mu.Unlock()
+	err := c.a.err
+	c.a.err = nil
+	return err
+}
+
+// add records an error and sends an alert if the threshold is reached.
+func (a *alerter) add(t time.Time, entry []byte) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.recent) == a.cfg.Entries {
+		a.recent = append(a.recent[:0], a.recent[1:]...)
+	}
+	a.recent = append(a.recent, entry)
+
+	// The oldest of the last Threshold errors tells whether they all fall
+	// within the window.
+	if len(a.times) < a.cfg.Threshold {
+		a.times = append(a.times, t)
+	} else {
+		a.times[a.next] = t
+	}
+	a.next = (a.next + 1) % a.cfg.Threshold
+	if len(a.times) < a.cfg.Threshold {
+		return
+	}
+	oldest := a.times[a.next]
+	if t.Sub(oldest) > a.cfg.Window || a.sending || t.Sub(a.last) < a.cfg.Cooldown {
+		return
+	}
+
+	alert := Alert{
+		Host:      a.host,
+		Program:   os.Args[0],
+		Time:      t,
+		Window:    a.cfg.Window.String(),
+		Threshold: a.cfg.Threshold,
+	}
+	for _, e := range a.recent {
+		alert.Entries = append(alert.Entries, json.RawMessage(e))
+	}
+	a.last = t
+	a.sending = true
+	go func() {
+		err := a.send(alert)
+		a.mu.Lock()
+		a.sending = false
+		if err != nil {
+			a.err = err
+		}
+		a.mu.Unlock()
+	}()
+}
+
+func (a *alerter) send(alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, a.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range a.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := a.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("log: alert webhook returned %s: %s", resp.Status, msg)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package log
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// AnomalyConfig describes the error rate anomaly detection.
+type AnomalyConfig struct {
+	// Interval is the period the errors are counted over. Defaults to 1
+	// minute.
+	Interval time.Duration
+	// Baseline is the number of intervals the baseline error rate is
+	// averaged over, as an exponentially weighted moving average, and the
+	// number of intervals observed before anomalies are reported.
+	// Defaults to 30.
+	Baseline int
+	// Factor is how many standard deviations above the baseline the count
+	// of an interval must be to be an anomaly. Defaults to 3.
+	Factor float64
+	// MinErrors is the minimum count of an anomalous interval, so that a
+	// few errors after a quiet period are not reported. Defaults to 10.
+	MinErrors int
+	// OnAnomaly, if set, is called at the start of every anomaly, from
+	// the goroutine of the detector.
+	OnAnomaly func(Anomaly)
+}
+
+// Anomaly describes an interval with an unusual count of errors.
+type Anomaly struct {
+	Time     time.Time
+	Errors   int
+	Baseline float64
+	StdDev   float64
+}
+
+// WithAnomalyDetection counts the entries at ERROR and above and logs a
+// WARN entry, "Error rate anomaly", when their count over an interval
+// rises sharply above the baseline learnt from the previous intervals,
+// and an INFO entry once it is back to normal. It gives a daemon some
+// self-monitoring without external tooling.
+func WithAnomalyDetection(cfg AnomalyConfig) Option {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.Baseline <= 0 {
+		cfg.Baseline = 30
+	}
+	if cfg.Factor <= 0 {
+		cfg.Factor = 3
+	}
+	if cfg.MinErrors <= 0 {
+		cfg.MinErrors = 10
+	}
+	return func(o *options) {
+		d := &anomalyDetector{cfg: cfg, stop: make(chan struct{})}
+		o.anomaly = append(o.anomaly, d)
+		o.cores = append(o.cores, &errorCounter{&d.count})
+	}
+}
+
+// anomalyDetector compares the count of errors of every interval with an
+// exponentially weighted mean and variance of the previous counts.
+type anomalyDetector struct {
+	count uint64 // atomic, first for alignment on 32-bit platforms
+	cfg   AnomalyConfig
+
+	mean, variance float64
+	seen           int
+	active         bool
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// run checks every interval until close is called.
+func (d *anomalyDetector) run() {
+	t := time.NewTicker(d.cfg.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case now := <-t.C:
+			d.check(now, int(atomic.SwapUint64(&d.count, 0)))
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+func (d *anomalyDetector) check(now time.Time, n int) {
+	stddev := math.Sqrt(d.variance)
+	// A deviation of at least one error keeps a perfectly steady rate
+	// from making any increase an anomaly.
+	limit := d.mean + d.cfg.Factor*math.Max(stddev, 1)
+	anomalous := d.seen >= d.cfg.Baseline && n >= d.cfg.MinErrors && float64(n) > limit
+
+	lg := std().base.WithOptions(zap.WithCaller(false))
+	switch {
+	case anomalous && !d.active:
+		a := Anomaly{Time: now, Errors: n, Baseline: d.mean, StdDev: stddev}
+		lg.Warn("Error rate anomaly",
+			zap.Int("errors", n),
+			zap.Duration("interval", d.cfg.Interval),
+			zap.Float64("baseline", math.Round(d.mean*100)/100),
+			zap.Float64("stddev", math.Round(stddev*100)/100))
+		if d.cfg.OnAnomaly != nil {
+			d.cfg.OnAnomaly(a)
+		}
+	case !anomalous && d.active:
+		lg.Info("Error rate back to normal", zap.Int("errors", n), zap.Duration("interval", d.cfg.Interval))
+	}
+	d.active = anomalous
+
+	alpha := 2 / (float64(d.cfg.Baseline) + 1)
+	if d.seen == 0 {
+		d.mean = float64(n)
+	} else {
+		diff := float64(n) - d.mean
+		d.mean += alpha * diff
+		d.variance = (1 - alpha) * (d.variance + alpha*diff*diff)
+	}
+	d.seen++
+}
+
+func (d *anomalyDetector) close() {
+	d.once.Do(func() { close(d.stop) })
+}
+
+// errorCounter counts the entries at ERROR and above.
+type errorCounter struct {
+	n *uint64
+}
+
+func (c *errorCounter) Enabled(lvl zapcore.Level) bool {
+	return lvl >= zapcore.ErrorLevel
+}
+
+func (c *errorCounter) With([]zapcore.Field) zapcore.Core {
+	return c
+}
+
+func (c *errorCounter) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *errorCounter) Write(zapcore.Entry, []zapcore.Field) error {
+	atomic.AddUint64(c.n, 1)
+	return nil
+}
+
+func (c *errorCounter) Sync() error {
+	return nil
+}
@@ -0,0 +1,156 @@
+package log
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// AsyncConfig configures an AsyncWriter.
+type AsyncConfig struct {
+	// BufferSize is the number of entries queued for the output.
+	// Defaults to 1024.
+	BufferSize int
+	// Overflow decides what happens when the queue is full: drop the new
+	// or the oldest entry, block, or coalesce. Defaults to DropNewest.
+	Overflow OverflowPolicy
+	// BlockTimeout bounds the wait of the BlockWithTimeout policy.
+	// Defaults to 100 milliseconds.
+	BlockTimeout time.Duration
+	// FlushTimeout bounds Sync and Close. Defaults to 5 seconds.
+	FlushTimeout time.Duration
+}
+
+// AsyncWriter is a zapcore.WriteSyncer that queues writes for another
+// WriteSyncer and performs them on its own goroutine, so a slow or stuck
+// output never blocks the caller beyond what the overflow policy allows.
+type AsyncWriter struct {
+	ws    zapcore.WriteSyncer
+	cfg   AsyncConfig
+	queue *entryQueue
+	done  chan struct{}
+
+	mu      sync.Mutex
+	err     error
+	written uint64
+}
+
+// NewAsyncWriter starts an AsyncWriter writing to ws.
+func NewAsyncWriter(ws zapcore.WriteSyncer, cfg AsyncConfig) *AsyncWriter {
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 1024
+	}
+	if cfg.BlockTimeout <= 0 {
+		cfg.BlockTimeout = defaultBlockTimeout
+	}
+	if cfg.FlushTimeout <= 0 {
+		cfg.FlushTimeout = 5 * time.Second
+	}
+	w := &AsyncWriter{
+		ws:    ws,
+		cfg:   cfg,
+		queue: newEntryQueue(cfg.BufferSize, cfg.Overflow),
+		done:  make(chan struct{}),
+	}
+	w.queue.timeout = cfg.BlockTimeout
+	go w.run()
+	return w
+}
+
+// Write queues a copy of p. It never fails; entries the overflow policy
+// discards are counted in Stats.
+func (w *AsyncWriter) Write(p []byte) (int, error) {
+	w.queue.push("", p)
+	return len(p), nil
+}
+
+// Sync waits for queued entries to be written, then syncs the output. It
+// reports the last write error.
+func (w *AsyncWriter) Sync() error {
+	if err := w.queue.drain(w.cfg.FlushTimeout); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	err := w.err
+	w.err = nil
+	w.mu.Unlock()
+	if serr := ignoreSyncError(w.ws.Sync()); serr != nil {
+		return serr
+	}
+	return err
+}
+
+// Close writes the queued entries, stops the writer goroutine and closes
+// the output if it is an io.Closer.
+func (w *AsyncWriter) Close() error {
+	err := w.queue.drain(w.cfg.FlushTimeout)
+	w.queue.close()
+	<-w.done
+	if c, ok := w.ws.(io.Closer); ok {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// AsyncStats are the counters of an AsyncWriter.
+type AsyncStats struct {
+	// Written counts writes to the output; entries merged by Coalesce
+	// count once.
+	Written uint64
+	Dropped uint64
+	Queued  int
+}
+
+// Stats returns the number of entries written, dropped and still queued.
+func (w *AsyncWriter) Stats() AsyncStats {
+	w.mu.Lock()
+	written := w.written
+	w.mu.Unlock()
+	return AsyncStats{
+		Written: written,
+		Dropped: w.queue.droppedCount(),
+		Queued:  w.queue.len(),
+	}
+}
+
+func (w *AsyncWriter) run() {
+	defer close(w.done)
+	for {
+		entry, ok := w.queue.next()
+		if !ok {
+			return
+		}
+		_, err := w.ws.Write(entry.data)
+		w.mu.Lock()
+		if err != nil {
+			w.err = err
+		} else {
+			w.written++
+		}
+		w.mu.Unlock()
+		w.queue.done()
+	}
+}
+
+// QueueStats returns the counters of the outputs made asynchronous by
+// WithNonBlocking or WithAsyncSink, summed.
+func QueueStats() AsyncStats {
+	return std().QueueStats()
+}
+
+// QueueStats returns the counters of the outputs made asynchronous by
+// WithNonBlocking or WithAsyncSink, summed.
+func (lg *Logger) QueueStats() AsyncStats {
+	var total AsyncStats
+	for _, w := range lg.async {
+		s := w.Stats()
+		total.Written += s.Written
+		total.Dropped += s.Dropped
+		total.Queued += s.Queued
+	}
+	return total
+}
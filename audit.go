@@ -0,0 +1,59 @@
+package log
+
+import (
+	"errors"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Audit result values.
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+// newAuditLogger builds the audit channel writing JSON entries to paths,
+// and returns it with the function closing them. It has no sampling and no
+// level filtering.
+func newAuditLogger(paths []string, clock zapcore.Clock) (*zap.Logger, func(), error) {
+	if len(paths) == 0 {
+		return nil, nil, errNoAuditPaths
+	}
+	out, closeOut, err := zap.Open(paths...)
+	if err != nil {
+		return nil, nil, err
+	}
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(NewEncoderConfig()), out, zapcore.DebugLevel)
+	return zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1), zap.WithClock(clock)), closeOut, nil
+}
+
+var errNoAuditPaths = errors.New("log: WithAudit without outputs")
+
+// Audit writes an entry to the audit channel configured with WithAudit. The
+// actor, action and result fields are mandatory; event is the message.
+// Audit entries are never sampled and do not depend on the logger level.
+func Audit(event, actor, action, result string, fields ...Field) {
+	lg := std()
+	if lg.audit == nil {
+		return
+	}
+	lg.audit.Info(event, auditFields(actor, action, result, fields)...)
+}
+
+// Audit writes an entry to the audit channel of lg.
+func (lg *Logger) Audit(event, actor, action, result string, fields ...Field) {
+	if lg.audit == nil {
+		return
+	}
+	lg.audit.Info(event, auditFields(actor, action, result, fields)...)
+}
+
+// auditFields returns the fields of an audit entry.
+func auditFields(actor, action, result string, fields []Field) []Field {
+	return append([]Field{
+		zap.String("actor", actor),
+		zap.String("action", action),
+		zap.String("result", result),
+	}, fields...)
+}
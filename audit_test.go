@@ -0,0 +1,36 @@
+package log
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAuditCaller(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	initTest(t, false, WithEncoding("json"), WithSink(discard{}), WithAudit(path))
+	Audit("Login", "admin", "login", AuditSuccess)
+	std().Audit("Login", "admin", "login", AuditSuccess)
+	_ = Sync()
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("%d audit entries, want 2:\n%s", len(lines), b)
+	}
+	for _, l := range lines {
+		if !strings.Contains(l, "audit_test.go:") {
+			t.Errorf("audit entry with the wrong caller: %s", l)
+		}
+	}
+}
+
+func TestAuditWithoutPaths(t *testing.T) {
+	if err := InitE(false, WithSink(discard{}), WithAudit()); err != errNoAuditPaths {
+		t.Errorf("InitE: %v, want %v", err, errNoAuditPaths)
+	}
+}
@@ -0,0 +1,51 @@
+package log
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// jitter is shared by the backoffs and seeded per process, so that devices
+// restarted together do not reconnect in lockstep.
+var (
+	jitterMu sync.Mutex
+	jitter   = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// backoff computes the delays between reconnection attempts of a sink:
+// doubling from min up to max, each randomized to between half and all of
+// its value.
+type backoff struct {
+	min, max time.Duration
+	attempt  uint
+}
+
+func newBackoff(min, max time.Duration) *backoff {
+	if max < min {
+		max = min
+	}
+	return &backoff{min: min, max: max}
+}
+
+// next returns the delay before the next attempt.
+func (b *backoff) next() time.Duration {
+	d := b.max
+	if b.attempt < 32 {
+		if exp := b.min << b.attempt; exp > 0 && exp < b.max {
+			d = exp
+		}
+	}
+	b.attempt++
+
+	jitterMu.Lock()
+	half := d / 2
+	d = half + time.Duration(jitter.Int63n(int64(d-half)+1))
+	jitterMu.Unlock()
+	return d
+}
+
+// reset starts over from min after a successful attempt.
+func (b *backoff) reset() {
+	b.attempt = 0
+}
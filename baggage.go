@@ -0,0 +1,44 @@
+package log
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// BaggageLookup returns the value of the baggage member key carried by
+// ctx, if any.
+type BaggageLookup func(ctx context.Context, key string) (string, bool)
+
+type baggage struct {
+	lookup BaggageLookup
+	keys   []string
+}
+
+// WithBaggage makes FromContext add the baggage members named by keys, and
+// only those, as fields, so that business context such as the tenant or
+// the experiment propagated by tracing ends up in the logs. The package
+// does not depend on OpenTelemetry; lookup reads its baggage:
+//
+//	log.Init(false, log.WithBaggage(func(ctx context.Context, key string) (string, bool) {
+//		m := baggage.FromContext(ctx).Member(key)
+//		return m.Value(), m.Key() != ""
+//	}, "tenant", "experiment"))
+func WithBaggage(lookup BaggageLookup, keys ...string) Option {
+	return func(o *options) {
+		o.baggage = &baggage{lookup: lookup, keys: keys}
+	}
+}
+
+// appendArgs appends the allowed members found in ctx to args.
+func (b *baggage) appendArgs(ctx context.Context, args []interface{}) []interface{} {
+	if b == nil {
+		return args
+	}
+	for _, key := range b.keys {
+		if v, ok := b.lookup(ctx, key); ok {
+			args = append(args[:len(args):len(args)], zap.String(key, v))
+		}
+	}
+	return args
+}
@@ -0,0 +1,222 @@
+package log
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BatchConfig configures a Batcher.
+type BatchConfig struct {
+	// MaxEntries and MaxBytes bound a single batch. Default to 100 entries
+	// and 1 MiB.
+	MaxEntries int
+	MaxBytes   int
+	// MaxLatency is the maximum time an entry waits before being sent.
+	// Defaults to 5 seconds.
+	MaxLatency time.Duration
+	// Breaker, if set, stops deliveries for a while after repeated
+	// failures and keeps up to Breaker.Buffer entries meanwhile.
+	Breaker *BreakerConfig
+}
+
+// BatchEntry is an entry handed to the send function of a Batcher.
+type BatchEntry struct {
+	// Time is when the entry was written.
+	Time time.Time
+	// Data is the encoded entry.
+	Data []byte
+}
+
+// Batcher is a zapcore.WriteSyncer accumulating encoded entries and handing
+// them to a send function in batches bounded by entry count, byte size and
+// latency. It is the building block of the remote sinks: a new sink only
+// has to implement the delivery of one batch.
+//
+//	b := log.NewBatcher(log.BatchConfig{MaxEntries: 500}, func(batch []log.BatchEntry) error {
+//		return postToCollector(batch)
+//	})
+//	log.Init(false, log.WithSink(b))
+//
+// Batches are sent from a background goroutine, and on Sync and Close.
+// Delivery errors are reported by the next Sync; the batch is then dropped
+// and counted in Stats.DroppedBatches.
+type Batcher struct {
+	maxEntries int
+	maxBytes   int
+	maxLatency time.Duration
+	send       func(batch []BatchEntry) error
+	breaker    *breaker
+	keep       int
+
+	mu      sync.Mutex
+	pending []BatchEntry
+	size    int
+	err     error
+	closed  bool
+
+	sendMu    sync.Mutex
+	flush     chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewBatcher starts a Batcher delivering batches with send. send is never
+// called concurrently.
+func NewBatcher(cfg BatchConfig, send func(batch []BatchEntry) error) *Batcher {
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = 100
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = 1 << 20
+	}
+	if cfg.MaxLatency <= 0 {
+		cfg.MaxLatency = 5 * time.Second
+	}
+	b := &Batcher{
+		maxEntries: cfg.MaxEntries,
+		maxBytes:   cfg.MaxBytes,
+		maxLatency: cfg.MaxLatency,
+		send:       send,
+		flush:      make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+	if cfg.Breaker != nil {
+		b.breaker, b.keep = newBreaker(*cfg.Breaker), cfg.Breaker.Buffer
+	}
+	b.wg.Add(1)
+	go b.run()
+	return b
+}
+
+// Write queues a copy of p. It fails with os.ErrClosed after Close.
+func (b *Batcher) Write(p []byte) (int, error) {
+	entry := BatchEntry{Time: time.Now(), Data: make([]byte, len(p))}
+	copy(entry.Data, p)
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return 0, os.ErrClosed
+	}
+	b.pending = append(b.pending, entry)
+	b.size += len(entry.Data)
+	full := len(b.pending) >= b.maxEntries || b.size >= b.maxBytes
+	b.mu.Unlock()
+
+	if full {
+		select {
+		case b.flush <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+// Sync sends everything queued and reports the last delivery error.
+func (b *Batcher) Sync() error {
+	b.sendPending()
+	b.mu.Lock()
+	err := b.err
+	b.err = nil
+	b.mu.Unlock()
+	return err
+}
+
+// Close stops the background flusher and sends the remaining entries.
+// Later calls return os.ErrClosed.
+func (b *Batcher) Close() error {
+	err := os.ErrClosed
+	b.closeOnce.Do(func() {
+		close(b.done)
+		b.wg.Wait()
+		b.mu.Lock()
+		b.closed = true
+		b.mu.Unlock()
+		err = b.Sync()
+	})
+	return err
+}
+
+func (b *Batcher) run() {
+	defer b.wg.Done()
+	t := time.NewTicker(b.maxLatency)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+		case <-b.flush:
+		case <-b.done:
+			return
+		}
+		b.sendPending()
+	}
+}
+
+func (b *Batcher) sendPending() {
+	b.sendMu.Lock()
+	defer b.sendMu.Unlock()
+
+	if b.breaker != nil && !b.breaker.allow() {
+		b.requeue(nil)
+		return
+	}
+	first := true
+
+	b.mu.Lock()
+	entries := b.pending
+	b.pending, b.size = nil, 0
+	b.mu.Unlock()
+
+	for len(entries) > 0 {
+		n, size := 0, 0
+		for n < len(entries) && n < b.maxEntries {
+			if n > 0 && size+len(entries[n].Data) > b.maxBytes {
+				break
+			}
+			size += len(entries[n].Data)
+			n++
+		}
+		if b.breaker != nil && !first && !b.breaker.allow() {
+			b.requeue(entries)
+			return
+		}
+		first = false
+		err := b.send(entries[:n])
+		if b.breaker != nil {
+			b.breaker.done(err)
+		}
+		if err != nil {
+			atomic.AddUint64(&counters.droppedBatches, 1)
+			atomic.AddUint64(&counters.unsent, uint64(n))
+			b.mu.Lock()
+			b.err = err
+			b.mu.Unlock()
+		}
+		entries = entries[n:]
+	}
+}
+
+// requeue puts back entries not sent because the breaker is open, ahead of
+// those written since, keeping at most the newest b.keep.
+func (b *Batcher) requeue(entries []BatchEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.err = errBreakerOpen
+	if len(entries) > 0 {
+		b.pending = append(entries, b.pending...)
+		b.size = 0
+		for _, e := range b.pending {
+			b.size += len(e.Data)
+		}
+	}
+	if drop := len(b.pending) - b.keep; drop > 0 {
+		atomic.AddUint64(&counters.unsent, uint64(drop))
+		for _, e := range b.pending[:drop] {
+			b.size -= len(e.Data)
+		}
+		b.pending = b.pending[drop:]
+	}
+}
@@ -0,0 +1,108 @@
+package log
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// batchRecorder is a send function keeping the batches it is handed.
+type batchRecorder struct {
+	mu      sync.Mutex
+	batches [][]BatchEntry
+	err     error
+}
+
+func (r *batchRecorder) send(batch []BatchEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches = append(r.batches, append([]BatchEntry(nil), batch...))
+	return r.err
+}
+
+func (r *batchRecorder) sizes() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var n []int
+	for _, b := range r.batches {
+		n = append(n, len(b))
+	}
+	return n
+}
+
+func TestBatcherBounds(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  BatchConfig
+		max  int // entries per batch
+	}{
+		{"entries", BatchConfig{MaxEntries: 2}, 2},
+		{"bytes", BatchConfig{MaxBytes: 6}, 2},
+		{"oversized", BatchConfig{MaxBytes: 1}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.MaxLatency = time.Hour
+			var r batchRecorder
+			b := NewBatcher(tt.cfg, r.send)
+			for i := 0; i < 5; i++ {
+				if _, err := b.Write([]byte("abc")); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := b.Close(); err != nil {
+				t.Fatal(err)
+			}
+			got := r.sizes()
+			total := 0
+			for _, n := range got {
+				total += n
+			}
+			// The background flusher may send smaller batches.
+			if total != 5 {
+				t.Fatalf("sent %v, want 5 entries", got)
+			}
+			for _, n := range got {
+				if n > tt.max {
+					t.Errorf("sent %v, want batches of at most %d", got, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestBatcherCloseTwice(t *testing.T) {
+	var r batchRecorder
+	b := NewBatcher(BatchConfig{}, r.send)
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Close(); err != os.ErrClosed {
+		t.Errorf("second Close: %v, want %v", err, os.ErrClosed)
+	}
+	if _, err := b.Write([]byte("late")); err != os.ErrClosed {
+		t.Errorf("Write after Close: %v, want %v", err, os.ErrClosed)
+	}
+}
+
+func TestBatcherCountsFailedBatches(t *testing.T) {
+	r := batchRecorder{err: errors.New("unreachable")}
+	b := NewBatcher(BatchConfig{MaxEntries: 2, MaxLatency: time.Hour}, r.send)
+	before := ReadStats()
+	for i := 0; i < 3; i++ {
+		b.Write([]byte("entry"))
+	}
+	if err := b.Sync(); err != r.err {
+		t.Errorf("Sync: %v, want %v", err, r.err)
+	}
+	b.Close()
+	after := ReadStats()
+	if n := after.DroppedBatches - before.DroppedBatches; n != 2 {
+		t.Errorf("dropped %d batches, want 2", n)
+	}
+	if n := after.Dropped - before.Dropped; n != 3 {
+		t.Errorf("dropped %d entries, want 3", n)
+	}
+}
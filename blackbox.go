@@ -0,0 +1,218 @@
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// BlackBox is persistent storage for the last entries logged before a
+// crash, such as an NVRAM or flash region that survives a reboot. Store is
+// only called on Panic, Fatal and HandleCrash, so implementations may be
+// slow and need not care about wear.
+type BlackBox interface {
+	// Store replaces the stored entries with entries, oldest first. Each
+	// is a JSON entry terminated by a newline.
+	Store(entries [][]byte) error
+	// Load returns the stored entries, oldest first.
+	Load() ([][]byte, error)
+}
+
+// WithBlackBox keeps the last n entries logged in memory and stores them
+// in box on Panic and Fatal entries, and in HandleCrash, so that crashes
+// in the field can be diagnosed after a power cycle. Read them back with
+// box.Load when the program starts.
+func WithBlackBox(box BlackBox, n int) Option {
+	return func(o *options) {
+		if n <= 0 {
+			n = 100
+		}
+		o.blackBox = &blackBox{box: box, ring: newRingBuffer(n)}
+		o.cores = append(o.cores, &blackBoxCore{
+			bb:  o.blackBox,
+			enc: zapcore.NewJSONEncoder(NewEncoderConfig()),
+		})
+	}
+}
+
+// blackBox is the memory ring of the entries to store in a BlackBox.
+type blackBox struct {
+	box  BlackBox
+	ring *ringBuffer
+}
+
+// persist stores the entries of the ring. It does nothing on a nil
+// receiver.
+func (b *blackBox) persist() error {
+	if b == nil {
+		return nil
+	}
+	return b.box.Store(b.ring.snapshot())
+}
+
+type blackBoxCore struct {
+	bb  *blackBox
+	enc zapcore.Encoder
+}
+
+func (c *blackBoxCore) Enabled(zapcore.Level) bool {
+	return true
+}
+
+func (c *blackBoxCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &blackBoxCore{bb: c.bb, enc: c.enc.Clone()}
+	for i := range fields {
+		fields[i].AddTo(clone.enc)
+	}
+	return clone
+}
+
+func (c *blackBoxCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, c)
+}
+
+func (c *blackBoxCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	c.bb.ring.add(buf.Bytes())
+	buf.Free()
+	if ent.Level >= zapcore.PanicLevel {
+		return c.bb.persist()
+	}
+	return nil
+}
+
+func (c *blackBoxCore) Sync() error {
+	return nil
+}
+
+// blackBoxMagic starts the header of a FileBlackBox region, followed by
+// the length and the CRC-32 of the entries.
+const (
+	blackBoxMagic  = "GLBB"
+	blackBoxHeader = len(blackBoxMagic) + 8
+)
+
+// FileBlackBox is a BlackBox storing the entries in a region of fixed size
+// at the start of a file or device, e.g. an MTD partition or a file on a
+// persistent flash filesystem. The region holds a header with a checksum,
+// so a region never written or torn by a power loss is detected; the
+// oldest entries are dropped when they do not all fit.
+type FileBlackBox struct {
+	path string
+	size int
+}
+
+// NewFileBlackBox returns a black box using the first size bytes of path,
+// creating it if needed.
+func NewFileBlackBox(path string, size int) (*FileBlackBox, error) {
+	if size <= blackBoxHeader {
+		return nil, errors.New("log: black box region is too small")
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if fi, err := f.Stat(); err == nil && fi.Mode().IsRegular() && fi.Size() < int64(size) {
+		if err := f.Truncate(int64(size)); err != nil {
+			return nil, err
+		}
+	}
+	return &FileBlackBox{path: path, size: size}, nil
+}
+
+// Store writes entries to the region and syncs it.
+func (b *FileBlackBox) Store(entries [][]byte) error {
+	room := b.size - blackBoxHeader
+	var total int
+	start := len(entries)
+	for start > 0 && total+len(entries[start-1]) <= room {
+		start--
+		total += len(entries[start])
+	}
+
+	region := make([]byte, b.size)
+	payload := region[blackBoxHeader:blackBoxHeader]
+	for _, e := range entries[start:] {
+		payload = append(payload, e...)
+	}
+	copy(region, blackBoxMagic)
+	binary.BigEndian.PutUint32(region[4:], uint32(len(payload)))
+	binary.BigEndian.PutUint32(region[8:], crc32.ChecksumIEEE(payload))
+
+	f, err := os.OpenFile(b.path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteAt(region, 0); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Load reads the entries back from the region. It returns none if the
+// region was never written or was cleared, and an error if it is
+// corrupted.
+func (b *FileBlackBox) Load() ([][]byte, error) {
+	f, err := os.Open(b.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	region := make([]byte, b.size)
+	if _, err := io.ReadFull(f, region); err != nil {
+		return nil, err
+	}
+	if string(region[:4]) != blackBoxMagic {
+		return nil, nil
+	}
+	n := int(binary.BigEndian.Uint32(region[4:]))
+	if n > b.size-blackBoxHeader {
+		return nil, errors.New("log: black box region is corrupted")
+	}
+	payload := region[blackBoxHeader : blackBoxHeader+n]
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(region[8:]) {
+		return nil, errors.New("log: black box region is corrupted")
+	}
+	var entries [][]byte
+	for len(payload) > 0 {
+		i := bytes.IndexByte(payload, '\n') + 1
+		if i == 0 {
+			i = len(payload)
+		}
+		entries = append(entries, payload[:i])
+		payload = payload[i:]
+	}
+	return entries, nil
+}
+
+// Clear erases the region, typically once its entries have been read
+// back after a crash.
+func (b *FileBlackBox) Clear() error {
+	f, err := os.OpenFile(b.path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteAt(make([]byte, blackBoxHeader), 0); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
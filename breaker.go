@@ -0,0 +1,180 @@
+package log
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// errBreakerOpen is returned for deliveries not attempted because the
+// circuit breaker of the sink is open.
+var errBreakerOpen = errors.New("log: circuit breaker open")
+
+// BreakerConfig configures the circuit breaker of a remote sink: after
+// Failures consecutive failed deliveries no attempt is made for Cooldown,
+// then a single attempt decides whether to resume or wait again. This
+// keeps a fleet of devices from hammering a collector that is down and
+// keeps a dead sink from delaying the others.
+type BreakerConfig struct {
+	// Failures is the number of consecutive failures that opens the
+	// breaker. Defaults to 5.
+	Failures int
+	// Cooldown is how long the breaker stays open. Defaults to 30
+	// seconds.
+	Cooldown time.Duration
+	// Buffer is the number of entries kept while the breaker is open and
+	// delivered once it closes; older entries are dropped when it is full.
+	// Zero drops every entry while the breaker is open.
+	Buffer int
+}
+
+// breaker is the state of a circuit breaker.
+type breaker struct {
+	failures int
+	cooldown time.Duration
+
+	mu          sync.Mutex
+	consecutive int
+	openUntil   time.Time
+	trial       bool
+}
+
+func newBreaker(cfg BreakerConfig) *breaker {
+	if cfg.Failures <= 0 {
+		cfg.Failures = 5
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = 30 * time.Second
+	}
+	return &breaker{failures: cfg.Failures, cooldown: cfg.Cooldown}
+}
+
+// allow reports whether a delivery may be attempted now. Once the cooldown
+// has passed a single attempt is allowed until its result is reported.
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.consecutive < b.failures {
+		return true
+	}
+	if b.trial || time.Now().Before(b.openUntil) {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+// done reports the result of an attempt allowed by allow.
+func (b *breaker) done(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+	if err == nil {
+		b.consecutive = 0
+		return
+	}
+	b.consecutive++
+	if b.consecutive >= b.failures {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
+
+// BreakerWriter is a zapcore.WriteSyncer guarding a remote output with a
+// circuit breaker, see BreakerConfig. Writes never fail: entries that
+// cannot be delivered are buffered or dropped, and the last error is
+// reported by Sync.
+type BreakerWriter struct {
+	ws      zapcore.WriteSyncer
+	breaker *breaker
+	buffer  int
+
+	mu      sync.Mutex
+	pending [][]byte
+	dropped uint64
+	err     error
+}
+
+// NewBreakerWriter returns a BreakerWriter writing to ws.
+func NewBreakerWriter(ws zapcore.WriteSyncer, cfg BreakerConfig) *BreakerWriter {
+	return &BreakerWriter{ws: ws, breaker: newBreaker(cfg), buffer: cfg.Buffer}
+}
+
+// Write writes p, after the entries buffered while the breaker was open.
+func (w *BreakerWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.breaker.allow() {
+		err := w.flush()
+		if err == nil {
+			_, err = w.ws.Write(p)
+		}
+		w.breaker.done(err)
+		if err == nil {
+			return len(p), nil
+		}
+		w.fail(err)
+	}
+	w.hold(p)
+	return len(p), nil
+}
+
+// Sync delivers the buffered entries if the breaker allows it, syncs the
+// output and reports the last delivery error.
+func (w *BreakerWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.pending) > 0 && w.breaker.allow() {
+		err := w.flush()
+		w.breaker.done(err)
+		if err != nil {
+			w.fail(err)
+		}
+	}
+	err := w.err
+	w.err = nil
+	if serr := ignoreSyncError(w.ws.Sync()); serr != nil && err == nil {
+		err = serr
+	}
+	return err
+}
+
+// Dropped returns the number of entries lost while the breaker was open.
+func (w *BreakerWriter) Dropped() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.dropped
+}
+
+func (w *BreakerWriter) flush() error {
+	for len(w.pending) > 0 {
+		if _, err := w.ws.Write(w.pending[0]); err != nil {
+			return err
+		}
+		w.pending[0] = nil
+		w.pending = w.pending[1:]
+	}
+	return nil
+}
+
+func (w *BreakerWriter) fail(err error) {
+	w.err = err
+	atomic.AddUint64(&counters.sinkErrors, 1)
+}
+
+// hold buffers a copy of p, dropping the oldest entry if the buffer is
+// full.
+func (w *BreakerWriter) hold(p []byte) {
+	if w.buffer <= 0 {
+		w.dropped++
+		return
+	}
+	if len(w.pending) >= w.buffer {
+		w.pending[0] = nil
+		w.pending = w.pending[1:]
+		w.dropped++
+	}
+	w.pending = append(w.pending, append([]byte(nil), p...))
+}
@@ -0,0 +1,52 @@
+package log
+
+import (
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// packageKey is the field added by WithPackageField.
+const packageKey = "pkg"
+
+// callerPackages caches the import path of the calling package by program
+// counter: resolving it on every entry would cost a symbol table lookup.
+var callerPackages sync.Map // uintptr -> string
+
+// WithPackageField adds a "pkg" field holding the import path of the
+// calling package to every entry, e.g. "github.com/ndmsystems/ndmd/wifi",
+// so entries can be filtered by package without setting up named loggers.
+func WithPackageField() Option {
+	return withEnricher(func(ent zapcore.Entry, fields []zapcore.Field) []zapcore.Field {
+		if pkg := callerPackage(ent.Caller); pkg != "" {
+			fields = append(fields, zap.String(packageKey, pkg))
+		}
+		return fields
+	})
+}
+
+// callerPackage returns the import path of the package of the calling
+// function.
+func callerPackage(caller zapcore.EntryCaller) string {
+	if !caller.Defined || caller.PC == 0 {
+		return ""
+	}
+	if pkg, ok := callerPackages.Load(caller.PC); ok {
+		return pkg.(string)
+	}
+	pkg := packageOf(caller.Function)
+	callerPackages.Store(caller.PC, pkg)
+	return pkg
+}
+
+// packageOf extracts the import path from a function name such as
+// "github.com/a/b/pkg.(*T).Method".
+func packageOf(fn string) string {
+	i := strings.LastIndexByte(fn, '/') + 1
+	if j := strings.IndexByte(fn[i:], '.'); j >= 0 {
+		return fn[:i+j]
+	}
+	return fn
+}
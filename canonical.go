@@ -0,0 +1,115 @@
+package log
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type canonicalKey struct{}
+
+// canonicalLine holds the fields of a canonical log line, in the order
+// they were first set.
+type canonicalLine struct {
+	mu   sync.Mutex
+	keys []string
+	vals map[string]interface{}
+}
+
+// WithCanonical returns a copy of ctx collecting the fields set with Set
+// and Add, to be logged as a single summarizing entry by EmitCanonical at
+// the end of the request. CanonicalHandler does this for HTTP handlers.
+func WithCanonical(ctx context.Context) context.Context {
+	return context.WithValue(ctx, canonicalKey{}, &canonicalLine{vals: map[string]interface{}{}})
+}
+
+func canonical(ctx context.Context) *canonicalLine {
+	c, _ := ctx.Value(canonicalKey{}).(*canonicalLine)
+	return c
+}
+
+// Set records a field of the canonical log line of ctx, replacing an
+// earlier value of key. It does nothing if ctx does not come from
+// WithCanonical, so library code may call it unconditionally.
+//
+//	log.Set(ctx, "db_ms", elapsed.Milliseconds())
+func Set(ctx context.Context, key string, value interface{}) {
+	c := canonical(ctx)
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.vals[key]; !ok {
+		c.keys = append(c.keys, key)
+	}
+	c.vals[key] = value
+}
+
+// Add adds delta to a counter field of the canonical log line of ctx, e.g.
+// the number of queries or the time spent in them. A field previously set
+// to something else than an int64 is replaced.
+func Add(ctx context.Context, key string, delta int64) {
+	c := canonical(ctx)
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	prev, ok := c.vals[key]
+	if !ok {
+		c.keys = append(c.keys, key)
+	}
+	n, _ := prev.(int64)
+	c.vals[key] = n + delta
+}
+
+// CanonicalFields returns the fields of the canonical log line of ctx.
+func CanonicalFields(ctx context.Context) []Field {
+	c := canonical(ctx)
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fields := make([]Field, len(c.keys))
+	for i, k := range c.keys {
+		fields[i] = zap.Any(k, c.vals[k])
+	}
+	return fields
+}
+
+// EmitCanonical logs the canonical log line of ctx at INFO, with the
+// fields added to ctx by AppendCtx and then those given.
+func EmitCanonical(ctx context.Context, msg string, fields ...Field) {
+	all := append(fields[:len(fields):len(fields)], CanonicalFields(ctx)...)
+	std().FromContext(ctx).base.Info(msg, all...)
+}
+
+// EmitCanonical logs the canonical log line of ctx through lg.
+func (lg *Logger) EmitCanonical(ctx context.Context, msg string, fields ...Field) {
+	all := append(fields[:len(fields):len(fields)], CanonicalFields(ctx)...)
+	lg.FromContext(ctx).base.Info(msg, all...)
+}
+
+// CanonicalHandler returns middleware giving every request served by next a
+// canonical log line, emitted through the package logger once next returns
+// with the method, path, status and duration of the request.
+func CanonicalHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx := WithCanonical(r.Context())
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		EmitCanonical(ctx, "request",
+			zap.String("method", r.Method),
+			zap.String("path", r.URL.Path),
+			zap.Int("status", rec.status()),
+			zap.Duration("duration", time.Since(start)),
+		)
+	})
+}
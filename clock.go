@@ -0,0 +1,63 @@
+package log
+
+import (
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// WithClock sets the clock stamping entries, zapcore.DefaultClock by
+// default. Tests use it to freeze time, so that encoded output can be
+// compared with golden files:
+//
+//	log.Init(false, log.WithClock(log.FixedClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))))
+//
+// Loggers obtained with GetLogger use the clock of the package logger.
+func WithClock(clock zapcore.Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// entryClock returns the clock set with WithClock, or the default one.
+func (o *options) entryClock() zapcore.Clock {
+	if o.clock != nil {
+		return o.clock
+	}
+	return zapcore.DefaultClock
+}
+
+// FixedClock returns a clock always telling t.
+func FixedClock(t time.Time) zapcore.Clock {
+	return fixedClock{t}
+}
+
+type fixedClock struct {
+	t time.Time
+}
+
+func (c fixedClock) Now() time.Time {
+	return c.t
+}
+
+func (c fixedClock) NewTicker(d time.Duration) *time.Ticker {
+	return time.NewTicker(d)
+}
+
+// namedClock is the clock of the named loggers, which follow the package
+// logger.
+type namedClock struct{}
+
+func (namedClock) Now() time.Time {
+	if c := std().clock; c != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+func (namedClock) NewTicker(d time.Duration) *time.Ticker {
+	if c := std().clock; c != nil {
+		return c.NewTicker(d)
+	}
+	return time.NewTicker(d)
+}
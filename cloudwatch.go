@@ -0,0 +1,312 @@
+package log
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CloudWatch Logs limits for a single PutLogEvents call.
+const (
+	cloudWatchMaxBatchBytes  = 1048576
+	cloudWatchMaxBatchEvents = 10000
+	cloudWatchEventOverhead  = 26
+	cloudWatchMaxEventBytes  = 262144 - cloudWatchEventOverhead
+)
+
+// AWSCredentials are the keys used to sign CloudWatch requests.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// EnvAWSCredentials reads credentials from the standard AWS_* environment
+// variables.
+func EnvAWSCredentials() (AWSCredentials, error) {
+	c := AWSCredentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if c.AccessKeyID == "" || c.SecretAccessKey == "" {
+		return c, errors.New("log: AWS credentials are not set in the environment")
+	}
+	return c, nil
+}
+
+// CloudWatchConfig describes a CloudWatch Logs sink.
+type CloudWatchConfig struct {
+	Region    string
+	LogGroup  string
+	LogStream string
+	// Credentials is called before each request, so rotating credentials
+	// are picked up. Defaults to EnvAWSCredentials.
+	Credentials func() (AWSCredentials, error)
+	// Endpoint overrides the regional endpoint.
+	Endpoint string
+	// FlushInterval is the maximum time entries wait before being sent.
+	// Defaults to 5 seconds.
+	FlushInterval time.Duration
+	// MaxRetries is the number of retries of a failed request. Defaults
+	// to 3.
+	MaxRetries int
+	HTTPClient *http.Client
+	// Breaker, if set, stops requests for a while after repeated failures.
+	Breaker *BreakerConfig
+	// Spool, if set, keeps entries on disk until they are delivered, so
+	// they survive network outages and restarts, see Spool.
+	Spool *SpoolConfig
+}
+
+type cloudWatchEvent struct {
+	Timestamp int64  `json:"timestamp"`
+	Message   string `json:"message"`
+}
+
+// CloudWatchSink is a zap.Sink shipping entries to CloudWatch Logs with
+// PutLogEvents. Entries are batched in memory and sent when a batch is full,
+// every FlushInterval, and on Sync and Close. Attach it with WithSink.
+type CloudWatchSink struct {
+	remoteQueue
+	cfg CloudWatchConfig
+
+	// seqToken is only used from send, which is never run
+	// concurrently.
+	seqToken string
+}
+
+// NewCloudWatchSink returns a sink writing to the configured log group and
+// stream. The stream is created on first use if it does not exist.
+func NewCloudWatchSink(cfg CloudWatchConfig) (*CloudWatchSink, error) {
+	if cfg.Region == "" || cfg.LogGroup == "" || cfg.LogStream == "" {
+		return nil, errors.New("log: CloudWatch region, log group and stream are required")
+	}
+	if cfg.Credentials == nil {
+		cfg.Credentials = EnvAWSCredentials
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://logs." + cfg.Region + ".amazonaws.com"
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 5 * time.Second
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = 3
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	s := &CloudWatchSink{cfg: cfg}
+	q, err := newRemoteQueue(BatchConfig{
+		MaxEntries: cloudWatchMaxBatchEvents,
+		// Leaves room for the per-event overhead of a full batch.
+		MaxBytes:   cloudWatchMaxBatchBytes - cloudWatchMaxBatchEvents*cloudWatchEventOverhead,
+		MaxLatency: cfg.FlushInterval,
+		Breaker:    cfg.Breaker,
+	}, cfg.Spool, s.send)
+	if err != nil {
+		return nil, err
+	}
+	s.remoteQueue = q
+	return s, nil
+}
+
+// send delivers one batch within the service limits.
+func (s *CloudWatchSink) send(batch []BatchEntry) error {
+	events := make([]cloudWatchEvent, len(batch))
+	for i, e := range batch {
+		msg := cutRunes(strings.TrimRight(string(e.Data), "\n"), cloudWatchMaxEventBytes)
+		events[i] = cloudWatchEvent{Timestamp: e.Time.UnixNano() / 1e6, Message: msg}
+	}
+	return s.putWithRetry(events)
+}
+
+func (s *CloudWatchSink) putWithRetry(events []cloudWatchEvent) error {
+	var err error
+	backoff := 200 * time.Millisecond
+	for attempt := 0; attempt <= s.cfg.MaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		var awsErr *awsError
+		err = s.putLogEvents(events)
+		switch {
+		case err == nil:
+			return nil
+		case errors.As(err, &awsErr) && awsErr.Type == "ResourceNotFoundException":
+			if err = s.createLogStream(); err != nil {
+				return err
+			}
+		case errors.As(err, &awsErr) && awsErr.Type == "InvalidSequenceTokenException":
+			s.seqToken = awsErr.ExpectedSequenceToken
+		case errors.As(err, &awsErr) && awsErr.Type == "DataAlreadyAcceptedException":
+			s.seqToken = awsErr.ExpectedSequenceToken
+			return nil
+		case errors.As(err, &awsErr) && awsErr.Type == "InvalidParameterException":
+			// The events are rejected, e.g. as too old.
+			return PermanentError(err)
+		case errors.As(err, &awsErr) && !awsErr.retryable():
+			return err
+		}
+	}
+	return err
+}
+
+func (s *CloudWatchSink) putLogEvents(events []cloudWatchEvent) error {
+	req := struct {
+		LogGroupName  string            `json:"logGroupName"`
+		LogStreamName string            `json:"logStreamName"`
+		LogEvents     []cloudWatchEvent `json:"logEvents"`
+		SequenceToken string            `json:"sequenceToken,omitempty"`
+	}{s.cfg.LogGroup, s.cfg.LogStream, events, s.seqToken}
+	var resp struct {
+		NextSequenceToken string `json:"nextSequenceToken"`
+	}
+	if err := s.call("PutLogEvents", req, &resp); err != nil {
+		return err
+	}
+	s.seqToken = resp.NextSequenceToken
+	return nil
+}
+
+func (s *CloudWatchSink) createLogStream() error {
+	req := struct {
+		LogGroupName  string `json:"logGroupName"`
+		LogStreamName string `json:"logStreamName"`
+	}{s.cfg.LogGroup, s.cfg.LogStream}
+	err := s.call("CreateLogStream", req, nil)
+	var awsErr *awsError
+	if errors.As(err, &awsErr) && awsErr.Type == "ResourceAlreadyExistsException" {
+		return nil
+	}
+	return err
+}
+
+// awsError is an error response of the CloudWatch Logs JSON protocol.
+type awsError struct {
+	Status                int
+	Type                  string `json:"__type"`
+	Message               string `json:"message"`
+	ExpectedSequenceToken string `json:"expectedSequenceToken"`
+}
+
+func (e *awsError) Error() string {
+	return fmt.Sprintf("log: CloudWatch %s (%d): %s", e.Type, e.Status, e.Message)
+}
+
+func (e *awsError) retryable() bool {
+	return e.Status >= 500 || e.Type == "ThrottlingException" || e.Type == "ServiceUnavailableException"
+}
+
+func (s *CloudWatchSink) call(action string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	creds, err := s.cfg.Credentials()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "Logs_20140328."+action)
+	signAWSv4(req, body, creds, s.cfg.Region, "logs", time.Now())
+
+	resp, err := s.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		awsErr := &awsError{Status: resp.StatusCode}
+		_ = json.Unmarshal(data, awsErr)
+		if i := strings.LastIndexByte(awsErr.Type, '#'); i >= 0 {
+			awsErr.Type = awsErr.Type[i+1:]
+		}
+		return awsErr
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// signAWSv4 signs req with AWS Signature Version 4.
+func signAWSv4(req *http.Request, body []byte, creds AWSCredentials, region, service string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	headers := []string{"host"}
+	for h := range req.Header {
+		headers = append(headers, strings.ToLower(h))
+	}
+	sort.Strings(headers)
+
+	var canonical strings.Builder
+	canonical.WriteString(req.Method + "\n")
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonical.WriteString(path + "\n")
+	canonical.WriteString(req.URL.RawQuery + "\n")
+	for _, h := range headers {
+		v := req.Header.Get(h)
+		if h == "host" {
+			v = req.URL.Host
+		}
+		canonical.WriteString(h + ":" + strings.TrimSpace(v) + "\n")
+	}
+	signed := strings.Join(headers, ";")
+	canonical.WriteString("\n" + signed + "\n")
+	canonical.WriteString(hexSHA256(body))
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hexSHA256([]byte(canonical.String()))
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	sig := hex.EncodeToString(hmacSHA256(key, toSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+creds.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signed+", Signature="+sig)
+}
+
+func hexSHA256(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,65 @@
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeCloudWatch records the events of the PutLogEvents calls it serves.
+type fakeCloudWatch struct {
+	mu     sync.Mutex
+	events []cloudWatchEvent
+}
+
+func (f *fakeCloudWatch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		LogEvents []cloudWatchEvent `json:"logEvents"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	f.events = append(f.events, req.LogEvents...)
+	f.mu.Unlock()
+	w.Write([]byte(`{"nextSequenceToken":"1"}`))
+}
+
+func TestCloudWatchTruncatesOnRuneBoundary(t *testing.T) {
+	var f fakeCloudWatch
+	srv := httptest.NewServer(&f)
+	defer srv.Close()
+	s, err := NewCloudWatchSink(CloudWatchConfig{
+		Region:    "eu-west-1",
+		LogGroup:  "devices",
+		LogStream: "test",
+		Endpoint:  srv.URL,
+		Credentials: func() (AWSCredentials, error) {
+			return AWSCredentials{AccessKeyID: "id", SecretAccessKey: "secret"}, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// 3-byte runes, so that the limit falls inside one.
+	msg := strings.Repeat("€", cloudWatchMaxEventBytes/3+1)
+	s.Write([]byte(msg + "\n"))
+	if err := s.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.events) != 1 {
+		t.Fatalf("got %d events, want 1", len(f.events))
+	}
+	got := f.events[0].Message
+	if len(got) > cloudWatchMaxEventBytes || !strings.HasPrefix(msg, got) || len(got) < cloudWatchMaxEventBytes-3 {
+		t.Errorf("sent %d bytes of the message, want a prefix of whole runes up to %d bytes", len(got), cloudWatchMaxEventBytes)
+	}
+}
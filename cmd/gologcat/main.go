@@ -0,0 +1,324 @@
+// Command gologcat renders the JSON output of the log package as colored,
+// human-readable lines, optionally filtered.
+//
+//	gologcat [flags] [file ...]
+//
+// It reads the named files, or stdin if there are none. Lines that are not
+// log entries are passed through unchanged.
+//
+//	gologcat -level warn -logger net -since 15m /var/log/daemon.log
+//	tail -f /var/log/daemon.log | gologcat -field user=alice
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/ndmsystems/golog"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// fieldFilters collects the repeated -field flags.
+type fieldFilters map[string]string
+
+func (f fieldFilters) String() string {
+	return ""
+}
+
+func (f fieldFilters) Set(s string) error {
+	i := strings.IndexByte(s, '=')
+	if i <= 0 {
+		return fmt.Errorf("want key=value, got %q", s)
+	}
+	f[s[:i]] = s[i+1:]
+	return nil
+}
+
+type filter struct {
+	level  zapcore.Level
+	logger string
+	since  time.Time
+	fields fieldFilters
+}
+
+func main() {
+	var (
+		level  = flag.String("level", "trace", "minimum `level` shown")
+		logger = flag.String("logger", "", "show only the logger `name` and its children")
+		since  = flag.String("since", "", "show only entries newer than a `duration` ago or a time (RFC 3339 or \"Jan 02 15:04:05\")")
+		color  = flag.String("color", "auto", "colorize the output: auto, always or never")
+		f      = filter{fields: fieldFilters{}}
+	)
+	flag.Var(f.fields, "field", "show only entries with the field `key=value` (repeatable)")
+	flag.Parse()
+
+	lvl, err := parseLevel(*level)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	f.level = lvl
+	f.logger = *logger
+	if *since != "" {
+		if f.since, err = parseSince(*since, time.Now()); err != nil {
+			fatalf("invalid -since: %v", err)
+		}
+	}
+	var useColor bool
+	switch *color {
+	case "always":
+		useColor = true
+	case "never":
+	case "auto":
+		useColor = isTerminal(os.Stdout)
+	default:
+		fatalf("invalid -color %q", *color)
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	r := newRenderer(f, log.NewPrettyEncoder(useColor), out)
+	if flag.NArg() == 0 {
+		if err := r.render(os.Stdin); err != nil {
+			fatalf("%v", err)
+		}
+		return
+	}
+	for _, name := range flag.Args() {
+		file, err := os.Open(name)
+		if err != nil {
+			fatalf("%v", err)
+		}
+		err = r.render(file)
+		file.Close()
+		if err != nil {
+			fatalf("%s: %v", name, err)
+		}
+	}
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "gologcat: "+format+"\n", args...)
+	os.Exit(2)
+}
+
+// renderer filters and re-encodes entries. The keys it looks for are those
+// of log.NewEncoderConfig, so it follows the encoder.
+type renderer struct {
+	filter
+	keys zapcore.EncoderConfig
+	enc  zapcore.Encoder
+	out  *bufio.Writer
+	now  time.Time
+}
+
+func newRenderer(f filter, enc zapcore.Encoder, out *bufio.Writer) *renderer {
+	return &renderer{
+		filter: f,
+		keys:   log.NewEncoderConfig(),
+		enc:    enc,
+		out:    out,
+		now:    time.Now(),
+	}
+}
+
+func (r *renderer) render(in io.Reader) error {
+	sc := bufio.NewScanner(in)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		line := sc.Bytes()
+		ent, fields, ok := r.parse(line)
+		if !ok {
+			if r.filtering() {
+				// Filtering on what cannot be read would hide it anyway.
+				continue
+			}
+			r.out.Write(line)
+			r.out.WriteByte('\n')
+			continue
+		}
+		if !r.match(ent, fields) {
+			continue
+		}
+		zf := make([]zapcore.Field, len(fields))
+		for i, f := range fields {
+			zf[i] = zap.Any(f.key, f.val)
+		}
+		buf, err := r.enc.EncodeEntry(ent, zf)
+		if err != nil {
+			return err
+		}
+		r.out.Write(buf.Bytes())
+		buf.Free()
+		if err := r.out.Flush(); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
+
+// field is a field of a parsed entry.
+type field struct {
+	key string
+	val interface{}
+}
+
+// parse splits a JSON entry into the entry metadata and its fields, in the
+// order they were written.
+func (r *renderer) parse(line []byte) (zapcore.Entry, []field, bool) {
+	var ent zapcore.Entry
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 || line[0] != '{' {
+		return ent, nil, false
+	}
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return ent, nil, false
+	}
+	var fields []field
+	seenMsg := false
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return ent, nil, false
+		}
+		key, _ := tok.(string)
+		var val interface{}
+		if err := dec.Decode(&val); err != nil {
+			return ent, nil, false
+		}
+		s, isString := val.(string)
+		switch {
+		case key == r.keys.MessageKey && isString:
+			ent.Message, seenMsg = s, true
+		case key == r.keys.LevelKey && isString:
+			lvl, err := parseLevel(s)
+			if err != nil {
+				return ent, nil, false
+			}
+			ent.Level = lvl
+		case key == r.keys.TimeKey:
+			if ent.Time, err = parseTime(val, r.now); err != nil {
+				return ent, nil, false
+			}
+		case key == r.keys.NameKey && isString:
+			ent.LoggerName = s
+		case key == r.keys.CallerKey && isString:
+			ent.Caller = parseCaller(s)
+		case key == r.keys.StacktraceKey && isString:
+			ent.Stack = s
+		default:
+			fields = append(fields, field{key, val})
+		}
+	}
+	return ent, fields, seenMsg
+}
+
+// filtering reports whether any filter is set.
+func (r *renderer) filtering() bool {
+	return r.level > zapcore.Level(log.TraceLevel) || r.logger != "" || !r.since.IsZero() || len(r.fields) > 0
+}
+
+func (r *renderer) match(ent zapcore.Entry, fields []field) bool {
+	if ent.Level < r.level {
+		return false
+	}
+	if r.logger != "" && ent.LoggerName != r.logger && !strings.HasPrefix(ent.LoggerName, r.logger+".") {
+		return false
+	}
+	if !r.since.IsZero() && ent.Time.Before(r.since) {
+		return false
+	}
+	for key, want := range r.fields {
+		found := false
+		for _, f := range fields {
+			if f.key == key && fmt.Sprint(f.val) == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func parseLevel(s string) (zapcore.Level, error) {
+	if lvl, err := log.ParseLevel(s); err == nil {
+		return zapcore.Level(lvl), nil
+	}
+	var lvl zapcore.Level
+	err := lvl.UnmarshalText([]byte(s))
+	return lvl, err
+}
+
+// stampLayout is the time layout of the default encoder configuration. It
+// has no year, which is taken from now.
+const stampLayout = "Jan 02 15:04:05"
+
+func parseTime(v interface{}, now time.Time) (time.Time, error) {
+	switch v := v.(type) {
+	case json.Number:
+		// Epoch seconds, as written by zapcore.EpochTimeEncoder.
+		f, err := v.Float64()
+		if err != nil {
+			return time.Time{}, err
+		}
+		sec := int64(f)
+		return time.Unix(sec, int64((f-float64(sec))*1e9)), nil
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t, nil
+		}
+		t, err := time.ParseInLocation(stampLayout, v, time.Local)
+		if err != nil {
+			return time.Time{}, err
+		}
+		t = t.AddDate(now.Year(), 0, 0)
+		if t.After(now.Add(24 * time.Hour)) {
+			// Written last year.
+			t = t.AddDate(-1, 0, 0)
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("unsupported time %v", v)
+}
+
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	return parseTime(s, now)
+}
+
+// parseCaller parses the "dir/file.go:line.Func()" form of the caller.
+func parseCaller(s string) zapcore.EntryCaller {
+	i := strings.LastIndexByte(s, ':')
+	if i < 0 {
+		return zapcore.EntryCaller{Defined: true, File: s}
+	}
+	rest := s[i+1:]
+	if j := strings.IndexByte(rest, '.'); j >= 0 {
+		rest = rest[:j]
+	}
+	line, _ := strconv.Atoi(rest)
+	return zapcore.EntryCaller{Defined: true, File: s[:i], Line: line}
+}
+
+func isTerminal(f *os.File) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,320 @@
+// Command gologgen generates typed logging functions from an event schema,
+// so that call sites are checked by the compiler and use the same keys:
+//
+//	//go:generate go run github.com/ndmsystems/golog/cmd/gologgen -o events_gen.go events.yaml
+//
+// The schema is a YAML (or JSON) document listing the events:
+//
+//	events:
+//	  - name: DHCP_LEASE_EXPIRED
+//	    code: 1201
+//	    level: warning
+//	    message: DHCP lease expired
+//	    go_name: LeaseExpired
+//	    fields:
+//	      - {name: mac, type: string}
+//	      - {name: iface, type: string}
+//
+// For each event it generates a variable defining the event with
+// log.DefineEvent, the registration of its schema with log.RegisterSchema,
+// and a function logging it:
+//
+//	var EventLeaseExpired = log.DefineEvent(...)
+//	func LogLeaseExpired(mac string, iface string)
+//
+// go_name defaults to the name in CamelCase. The field types are string,
+// int, int64, uint, uint64, float, bool, duration, time, error and any.
+// The package of the generated file is given by -package, or else by the
+// package running go generate.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode"
+
+	log "github.com/ndmsystems/golog"
+	"gopkg.in/yaml.v3"
+)
+
+type schema struct {
+	Events []event `yaml:"events"`
+}
+
+type event struct {
+	Name    string  `yaml:"name"`
+	Code    int     `yaml:"code"`
+	Level   string  `yaml:"level"`
+	Message string  `yaml:"message"`
+	GoName  string  `yaml:"go_name"`
+	Fields  []field `yaml:"fields"`
+
+	// Filled in by resolve.
+	LevelConst string
+	Params     []param
+}
+
+type field struct {
+	Name string `yaml:"name"`
+	Type string `yaml:"type"`
+}
+
+type param struct {
+	Key   string
+	Name  string
+	Type  string
+	Field string
+	Kind  string
+}
+
+// fieldType describes how a field type of the schema is generated.
+type fieldType struct {
+	goType string
+	ctor   string
+	kind   string
+}
+
+var fieldTypes = map[string]fieldType{
+	"string":   {"string", "zap.String", "log.StringKind"},
+	"int":      {"int", "zap.Int", "log.IntKind"},
+	"int64":    {"int64", "zap.Int64", "log.IntKind"},
+	"uint":     {"uint", "zap.Uint", "log.IntKind"},
+	"uint64":   {"uint64", "zap.Uint64", "log.IntKind"},
+	"float":    {"float64", "zap.Float64", "log.FloatKind"},
+	"bool":     {"bool", "zap.Bool", "log.BoolKind"},
+	"duration": {"time.Duration", "zap.Duration", "log.DurationKind"},
+	"time":     {"time.Time", "zap.Time", "log.TimeKind"},
+	"error":    {"error", "zap.NamedError", "log.ErrorKind"},
+	"any":      {"interface{}", "zap.Any", "log.AnyKind"},
+}
+
+var levelConsts = map[log.Level]string{
+	log.TraceLevel:   "log.TraceLevel",
+	log.DebugLevel:   "log.DebugLevel",
+	log.InfoLevel:    "log.InfoLevel",
+	log.WarningLevel: "log.WarningLevel",
+	log.ErrorLevel:   "log.ErrorLevel",
+	log.PanicLevel:   "log.PanicLevel",
+	log.FatalLevel:   "log.FatalLevel",
+}
+
+func main() {
+	var (
+		out     = flag.String("o", "", "write the generated code to `file` instead of stdout")
+		pkgName = flag.String("package", os.Getenv("GOPACKAGE"), "`name` of the generated package")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: gologgen [flags] schema.yaml\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 || *pkgName == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	src, err := generate(flag.Arg(0), *pkgName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gologgen: %v\n", err)
+		os.Exit(1)
+	}
+	if *out == "" {
+		os.Stdout.Write(src)
+		return
+	}
+	if err := ioutil.WriteFile(*out, src, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "gologgen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// generate returns the formatted code generated from the schema in path.
+func generate(path, pkgName string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s schema
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if len(s.Events) == 0 {
+		return nil, fmt.Errorf("%s: no events", path)
+	}
+	usesTime := false
+	for i := range s.Events {
+		e := &s.Events[i]
+		if err := e.resolve(); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		for _, p := range e.Params {
+			usesTime = usesTime || strings.HasPrefix(p.Type, "time.")
+		}
+	}
+
+	var buf bytes.Buffer
+	err = fileTemplate.Execute(&buf, map[string]interface{}{
+		"Source":   filepath.Base(path),
+		"Package":  pkgName,
+		"UsesTime": usesTime,
+		"Events":   s.Events,
+	})
+	if err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %v", err)
+	}
+	return src, nil
+}
+
+// resolve checks e and fills in the names and types used by the template.
+func (e *event) resolve() error {
+	if e.Name == "" {
+		return errors.New("event without a name")
+	}
+	if e.GoName == "" {
+		e.GoName = camelCase(e.Name, true)
+	}
+	if !token.IsIdentifier(e.GoName) {
+		return fmt.Errorf("event %s: invalid go_name %q", e.Name, e.GoName)
+	}
+	if e.Level == "" {
+		e.Level = "info"
+	}
+	lvl, err := log.ParseLevel(e.Level)
+	if err != nil {
+		return fmt.Errorf("event %s: %v", e.Name, err)
+	}
+	e.LevelConst = levelConsts[lvl]
+	if e.LevelConst == "" {
+		return fmt.Errorf("event %s: invalid level %q", e.Name, e.Level)
+	}
+
+	seen := make(map[string]bool)
+	for _, f := range e.Fields {
+		t, ok := fieldTypes[f.Type]
+		if !ok {
+			return fmt.Errorf("event %s: field %q has unknown type %q", e.Name, f.Name, f.Type)
+		}
+		name := camelCase(f.Name, false)
+		if name == "" {
+			return fmt.Errorf("event %s: field without a name", e.Name)
+		}
+		if token.IsKeyword(name) || reserved[name] {
+			name += "_"
+		}
+		if seen[name] {
+			return fmt.Errorf("event %s: duplicate field %q", e.Name, f.Name)
+		}
+		seen[name] = true
+		e.Params = append(e.Params, param{
+			Key:   f.Name,
+			Name:  name,
+			Type:  t.goType,
+			Field: fmt.Sprintf("%s(%q, %s)", t.ctor, f.Name, name),
+			Kind:  t.kind,
+		})
+	}
+	return nil
+}
+
+// reserved are the names used by the generated functions that parameters
+// must not shadow.
+var reserved = map[string]bool{
+	"append": true, "ce": true, "log": true, "time": true, "zap": true,
+}
+
+// initialisms are the words kept in upper case in generated names.
+var initialisms = map[string]bool{
+	"API": true, "DHCP": true, "DNS": true, "HTTP": true, "ID": true,
+	"IP": true, "MAC": true, "TCP": true, "UDP": true, "URL": true,
+}
+
+// camelCase converts a name such as "DHCP_LEASE_EXPIRED" or "device_id"
+// into an identifier, "DHCPLeaseExpired" or "deviceID".
+func camelCase(name string, exported bool) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var b strings.Builder
+	for i, w := range words {
+		upper := strings.ToUpper(w)
+		switch {
+		case i == 0 && !exported:
+			b.WriteString(strings.ToLower(w))
+		case initialisms[upper]:
+			b.WriteString(upper)
+		default:
+			r := []rune(strings.ToLower(w))
+			r[0] = unicode.ToUpper(r[0])
+			b.WriteString(string(r))
+		}
+	}
+	s := b.String()
+	if s != "" && unicode.IsDigit(rune(s[0])) {
+		s = "_" + s
+	}
+	return s
+}
+
+var fileTemplate = template.Must(template.New("").Parse(`// Code generated by gologgen from {{.Source}}. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- if .UsesTime}}
+	"time"
+{{end}}
+	log "github.com/ndmsystems/golog"
+	"go.uber.org/zap"
+)
+
+var (
+{{- range .Events}}
+	Event{{.GoName}} = log.DefineEvent(log.EventType{
+		Code:    {{.Code}},
+		Name:    {{printf "%q" .Name}},
+		Level:   {{.LevelConst}},
+		{{- if .Message}}
+		Message: {{printf "%q" .Message}},
+		{{- end}}
+	})
+{{- end}}
+)
+
+func init() {
+{{- range .Events}}{{if .Params}}
+	log.RegisterSchema({{printf "%q" .Name}}, log.Schema{
+		{{- range .Params}}
+		{{printf "%q" .Key}}: {{.Kind}},
+		{{- end}}
+	})
+{{- end}}{{end}}
+}
+{{range .Events}}
+// Log{{.GoName}} logs the {{.Name}} event.
+func Log{{.GoName}}({{range $i, $p := .Params}}{{if $i}}, {{end}}{{$p.Name}} {{$p.Type}}{{end}}) {
+	if ce := log.CheckEvent(Event{{.GoName}}); ce != nil {
+	{{- if not .Params}}
+		ce.Write(Event{{.GoName}}.Fields()...)
+	{{- else}}
+		ce.Write(append(Event{{.GoName}}.Fields(),
+		{{- range .Params}}
+			{{.Field}},
+		{{- end}}
+		)...)
+	{{- end}}
+	}
+}
+{{end}}`))
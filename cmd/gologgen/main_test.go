@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// TestGenerate compares the code generated from testdata/events.yaml with
+// the package testdata/events and builds it.
+func TestGenerate(t *testing.T) {
+	golden := filepath.Join("testdata", "events", "events_gen.go")
+	src, err := generate(filepath.Join("testdata", "events.yaml"), "events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		if err := ioutil.WriteFile(golden, src, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, want) {
+		t.Errorf("generated code differs from %s, run go test -update:\n%s", golden, src)
+	}
+
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go not found")
+	}
+	if out, err := exec.Command(goTool, "build", "./testdata/events").CombinedOutput(); err != nil {
+		t.Errorf("generated code does not build: %v\n%s", err, out)
+	}
+}
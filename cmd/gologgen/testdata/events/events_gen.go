@@ -0,0 +1,83 @@
+// Code generated by gologgen from events.yaml. DO NOT EDIT.
+
+package events
+
+import (
+	"time"
+
+	log "github.com/ndmsystems/golog"
+	"go.uber.org/zap"
+)
+
+var (
+	EventDHCPLeaseExpired = log.DefineEvent(log.EventType{
+		Code:    1201,
+		Name:    "DHCP_LEASE_EXPIRED",
+		Level:   log.WarningLevel,
+		Message: "DHCP lease expired",
+	})
+	EventRebooted = log.DefineEvent(log.EventType{
+		Code:  1301,
+		Name:  "DEVICE_REBOOTED",
+		Level: log.InfoLevel,
+	})
+	EventShadowing = log.DefineEvent(log.EventType{
+		Code:  1401,
+		Name:  "SHADOWING",
+		Level: log.DebugLevel,
+	})
+)
+
+func init() {
+	log.RegisterSchema("DHCP_LEASE_EXPIRED", log.Schema{
+		"mac":        log.StringKind,
+		"lease_time": log.DurationKind,
+		"expired_at": log.TimeKind,
+	})
+	log.RegisterSchema("SHADOWING", log.Schema{
+		"type":   log.StringKind,
+		"log":    log.IntKind,
+		"zap":    log.IntKind,
+		"time":   log.IntKind,
+		"ce":     log.IntKind,
+		"append": log.FloatKind,
+		"ok":     log.BoolKind,
+		"err":    log.ErrorKind,
+		"data":   log.AnyKind,
+	})
+}
+
+// LogDHCPLeaseExpired logs the DHCP_LEASE_EXPIRED event.
+func LogDHCPLeaseExpired(mac string, leaseTime time.Duration, expiredAt time.Time) {
+	if ce := log.CheckEvent(EventDHCPLeaseExpired); ce != nil {
+		ce.Write(append(EventDHCPLeaseExpired.Fields(),
+			zap.String("mac", mac),
+			zap.Duration("lease_time", leaseTime),
+			zap.Time("expired_at", expiredAt),
+		)...)
+	}
+}
+
+// LogRebooted logs the DEVICE_REBOOTED event.
+func LogRebooted() {
+	if ce := log.CheckEvent(EventRebooted); ce != nil {
+		ce.Write(EventRebooted.Fields()...)
+	}
+}
+
+// LogShadowing logs the SHADOWING event.
+func LogShadowing(type_ string, log_ int, zap_ int64, time_ uint, ce_ uint64, append_ float64, ok bool, err error, data interface{}) {
+	if ce := log.CheckEvent(EventShadowing); ce != nil {
+		ce.Write(append(EventShadowing.Fields(),
+			zap.String("type", type_),
+			zap.Int("log", log_),
+			zap.Int64("zap", zap_),
+			zap.Uint("time", time_),
+			zap.Uint64("ce", ce_),
+			zap.Float64("append", append_),
+			zap.Bool("ok", ok),
+			zap.NamedError("err", err),
+			zap.Any("data", data),
+		)...)
+	}
+}
@@ -0,0 +1,138 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConsulConfig describes a Consul key holding a level spec.
+type ConsulConfig struct {
+	// Address is the URL of the Consul agent. Defaults to
+	// "http://127.0.0.1:8500".
+	Address string
+	// Key is the KV key holding the spec, e.g. "ndmd/log-levels".
+	Key string
+	// Token is the ACL token, if any.
+	Token string
+	// Datacenter selects a datacenter other than the agent's.
+	Datacenter string
+	// WaitTime bounds a single blocking query. Defaults to 5 minutes.
+	WaitTime   time.Duration
+	TLS        *TLSConfig
+	HTTPClient *http.Client
+}
+
+// ConsulLevelSource is a LevelSource reading a Consul key with blocking
+// queries.
+type ConsulLevelSource struct {
+	cfg     ConsulConfig
+	url     string
+	index   uint64
+	started bool
+	last    string
+}
+
+// NewConsulLevelSource returns a source watching cfg.Key.
+func NewConsulLevelSource(cfg ConsulConfig) (*ConsulLevelSource, error) {
+	if cfg.Key == "" {
+		return nil, errors.New("log: Consul key is required")
+	}
+	if cfg.Address == "" {
+		cfg.Address = "http://127.0.0.1:8500"
+	}
+	if cfg.WaitTime <= 0 {
+		cfg.WaitTime = 5 * time.Minute
+	}
+	if cfg.HTTPClient == nil {
+		client, err := newWatchClient(cfg.Address, cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		cfg.HTTPClient = client
+	}
+	return &ConsulLevelSource{
+		cfg: cfg,
+		url: strings.TrimRight(cfg.Address, "/") + "/v1/kv/" + strings.TrimLeft(cfg.Key, "/"),
+	}, nil
+}
+
+// Next implements LevelSource.
+func (s *ConsulLevelSource) Next(ctx context.Context) (string, error) {
+	for {
+		spec, index, err := s.get(ctx)
+		if err != nil {
+			return "", err
+		}
+		if index < s.index {
+			// The index went backwards, e.g. after a snapshot restore.
+			index = 0
+		}
+		if index < 1 {
+			// A query waiting on index 0 returns at once.
+			index = 1
+		}
+		s.index = index
+		if !s.started || spec != s.last {
+			s.started, s.last = true, spec
+			return spec, nil
+		}
+	}
+}
+
+// get runs a blocking query for the key, returning its value and the index
+// to wait on next.
+func (s *ConsulLevelSource) get(ctx context.Context) (string, uint64, error) {
+	q := url.Values{}
+	if s.started {
+		q.Set("index", strconv.FormatUint(s.index, 10))
+		q.Set("wait", strconv.Itoa(int(s.cfg.WaitTime/time.Second))+"s")
+	}
+	if s.cfg.Datacenter != "" {
+		q.Set("dc", s.cfg.Datacenter)
+	}
+	u := s.url
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	req = req.WithContext(ctx)
+	if s.cfg.Token != "" {
+		req.Header.Set("X-Consul-Token", s.cfg.Token)
+	}
+	resp, err := s.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+	index, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", index, nil
+	default:
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", 0, fmt.Errorf("log: Consul returned %s: %s", resp.Status, msg)
+	}
+	var kvs []struct {
+		Value []byte
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&kvs); err != nil {
+		return "", 0, err
+	}
+	if len(kvs) == 0 {
+		return "", index, nil
+	}
+	return strings.TrimSpace(string(kvs[0].Value)), index, nil
+}
@@ -0,0 +1,55 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestConsulIndexAtLeastOne(t *testing.T) {
+	// Each response gives the index and value of a request; the index
+	// goes backwards, then is missing.
+	responses := []struct{ index, value string }{
+		{"10", "info"}, {"5", "debug"}, {"", "warn"}, {"0", "error"},
+	}
+	var mu sync.Mutex
+	var queried []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		n := len(queried)
+		queried = append(queried, r.URL.Query().Get("index"))
+		if n >= len(responses) {
+			http.Error(w, "done", http.StatusInternalServerError)
+			return
+		}
+		if i := responses[n].index; i != "" {
+			w.Header().Set("X-Consul-Index", i)
+		}
+		json.NewEncoder(w).Encode([]struct{ Value []byte }{{[]byte(responses[n].value)}})
+	}))
+	defer srv.Close()
+
+	s, err := NewConsulLevelSource(ConsulConfig{Address: srv.URL, Key: "levels"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range responses {
+		spec, err := s.Next(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if spec != r.value {
+			t.Errorf("Next: %q, want %q", spec, r.value)
+		}
+	}
+	want := []string{"", "10", "1", "1"}
+	for i, q := range queried {
+		if q != want[i] {
+			t.Errorf("query %d waited on index %q, want %q", i, q, want[i])
+		}
+	}
+}
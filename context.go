@@ -0,0 +1,72 @@
+package log
+
+import "context"
+
+type ctxArgsKey struct{}
+
+// AppendCtx returns a copy of ctx carrying args, alternating keys and
+// values or Fields, in addition to those added by earlier calls. Loggers
+// obtained with FromContext add them to every entry, so request scoped
+// values such as the tenant or the session need not be passed down
+// explicitly.
+func AppendCtx(ctx context.Context, args ...interface{}) context.Context {
+	prev := ctxArgs(ctx)
+	merged := make([]interface{}, 0, len(prev)+len(args))
+	merged = append(append(merged, prev...), args...)
+	return context.WithValue(ctx, ctxArgsKey{}, merged)
+}
+
+func ctxArgs(ctx context.Context) []interface{} {
+	args, _ := ctx.Value(ctxArgsKey{}).([]interface{})
+	return args
+}
+
+// FromContext returns the package logger with the fields accumulated in
+// ctx by AppendCtx, and the baggage members selected by WithBaggage.
+func FromContext(ctx context.Context) *Logger {
+	return std().FromContext(ctx)
+}
+
+// FromContext returns lg with the fields accumulated in ctx by AppendCtx,
+// and the baggage members selected by WithBaggage.
+func (lg *Logger) FromContext(ctx context.Context) *Logger {
+	b := lg.baggage
+	if lg.named != nil {
+		// Named loggers follow the package logger.
+		b = std().baggage
+	}
+	args := b.appendArgs(ctx, ctxArgs(ctx))
+	if len(args) == 0 {
+		return lg
+	}
+	return lg.With(args...)
+}
+
+// With returns a child of the package logger adding args, alternating keys
+// and values or Fields, to every entry.
+func With(args ...interface{}) *Logger {
+	return std().With(args...)
+}
+
+// With returns a child of lg adding args, alternating keys and values or
+// Fields, to every entry.
+func (lg *Logger) With(args ...interface{}) *Logger {
+	child := *lg
+	child.zap = lg.zap.With(args...)
+	child.base = child.zap.Desugar()
+	if lg.security != nil {
+		child.security = lg.security.With(args...)
+	}
+	return &child
+}
+
+// WithFields returns a child of the package logger adding fields to every
+// entry, like logrus' WithFields.
+func WithFields(fields map[string]interface{}) *Logger {
+	return std().WithFields(fields)
+}
+
+// WithFields returns a child of lg adding fields to every entry.
+func (lg *Logger) WithFields(fields map[string]interface{}) *Logger {
+	return lg.With(MapToArgs(fields)...)
+}
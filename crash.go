@@ -0,0 +1,82 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// maxCrashGoroutines caps the goroutine dump in a crash report.
+const maxCrashGoroutines = 256 << 10
+
+// HandleCrash writes a crash report for a panic of the calling goroutine,
+// stores the last entries in the black box (see WithBlackBox) and
+// re-raises it. It must be deferred directly, typically first thing in
+// main:
+//
+//	defer log.HandleCrash()
+func HandleCrash() {
+	if r := recover(); r != nil {
+		lg := std()
+		_ = lg.blackBox.persist()
+		_ = lg.writeCrashReport(fmt.Sprint("panic: ", r), debug.Stack())
+		panic(r)
+	}
+}
+
+// WriteCrashReport writes a crash report with the given reason to the
+// location configured with WithCrashReport. Fatal* functions call it before
+// exiting.
+func WriteCrashReport(reason string) error {
+	return std().writeCrashReport(reason, nil)
+}
+
+// writeCrashReport writes the recent entries, the goroutine dump and build
+// information to the crash report path. It is a no-op when no path is
+// configured.
+func (lg *Logger) writeCrashReport(reason string, stack []byte) error {
+	if lg.crashPath == "" {
+		return nil
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "crash: %s\n", reason)
+	fmt.Fprintf(&b, "time: %s\n", time.Now().Format(time.RFC3339Nano))
+	fmt.Fprintf(&b, "go: %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	if info, ok := debug.ReadBuildInfo(); ok {
+		fmt.Fprintf(&b, "build: %s %s\n", info.Main.Path, info.Main.Version)
+	}
+	if len(stack) > 0 {
+		b.WriteString("\n--- stack ---\n")
+		b.Write(stack)
+	}
+	if lg.recorder != nil {
+		b.WriteString("\n--- recent entries ---\n")
+		for _, p := range lg.recorder.snapshot() {
+			b.Write(p)
+		}
+	}
+	b.WriteString("\n--- goroutines ---\n")
+	dump := goroutineDump()
+	if len(dump) > maxCrashGoroutines {
+		dump = dump[:maxCrashGoroutines]
+	}
+	b.Write(dump)
+
+	f, err := os.OpenFile(lg.crashPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,174 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func init() {
+	registerEncoder("datadog", func(zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		return NewDatadogEncoder(), nil
+	})
+}
+
+// NewDatadogEncoder returns a JSON encoder using Datadog's standard
+// attribute names, so no remapping pipeline is needed.
+func NewDatadogEncoder() zapcore.Encoder {
+	cfg := NewEncoderConfig()
+	cfg.TimeKey = "timestamp"
+	cfg.LevelKey = "status"
+	cfg.NameKey = "logger.name"
+	cfg.CallerKey = "logger.caller"
+	cfg.MessageKey = "message"
+	cfg.StacktraceKey = "error.stack"
+	cfg.EncodeLevel = zapcore.LowercaseLevelEncoder
+	cfg.EncodeTime = zapcore.EpochMillisTimeEncoder
+	cfg.EncodeDuration = zapcore.NanosDurationEncoder
+	return zapcore.NewJSONEncoder(cfg)
+}
+
+// DDTrace returns the fields correlating an entry with a Datadog APM trace.
+func DDTrace(traceID, spanID uint64) []Field {
+	return []Field{
+		zap.String("dd.trace_id", strconv.FormatUint(traceID, 10)),
+		zap.String("dd.span_id", strconv.FormatUint(spanID, 10)),
+	}
+}
+
+// DatadogConfig describes the Datadog HTTP intake sink.
+type DatadogConfig struct {
+	APIKey string
+	// Site is the Datadog site, e.g. "datadoghq.eu". Defaults to
+	// "datadoghq.com".
+	Site string
+	// Service, Source, Hostname and Tags are sent as ddsource, service,
+	// hostname and ddtags query parameters.
+	Service  string
+	Source   string
+	Hostname string
+	Tags     string
+	// FlushInterval is the maximum time entries wait before being sent.
+	// Defaults to 5 seconds.
+	FlushInterval time.Duration
+	// Gzip compresses request bodies.
+	Gzip       bool
+	HTTPClient *http.Client
+	// Breaker, if set, stops requests for a while after repeated failures.
+	Breaker *BreakerConfig
+	// Spool, if set, keeps entries on disk until they are delivered, so
+	// they survive network outages and restarts, see Spool.
+	Spool *SpoolConfig
+}
+
+// DatadogSink is a zap.Sink posting JSON entries to the Datadog logs intake.
+// Attach it with WithSink together with the "datadog" encoding.
+type DatadogSink struct {
+	remoteQueue
+	cfg DatadogConfig
+	url string
+}
+
+// Datadog intake limits.
+const (
+	datadogMaxBatchEntries = 1000
+	datadogMaxBatchBytes   = 5 << 20
+)
+
+// NewDatadogSink returns a sink sending entries to the Datadog intake.
+func NewDatadogSink(cfg DatadogConfig) (*DatadogSink, error) {
+	if cfg.APIKey == "" {
+		return nil, errors.New("log: Datadog API key is required")
+	}
+	if cfg.Site == "" {
+		cfg.Site = "datadoghq.com"
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 5 * time.Second
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	s := &DatadogSink{cfg: cfg, url: "https://http-intake.logs." + cfg.Site + "/api/v2/logs"}
+	q, err := newRemoteQueue(BatchConfig{
+		MaxEntries: datadogMaxBatchEntries,
+		MaxBytes:   datadogMaxBatchBytes,
+		MaxLatency: cfg.FlushInterval,
+		Breaker:    cfg.Breaker,
+	}, cfg.Spool, s.send)
+	if err != nil {
+		return nil, err
+	}
+	s.remoteQueue = q
+	return s, nil
+}
+
+func (s *DatadogSink) send(batch []BatchEntry) error {
+	var body bytes.Buffer
+	var w io.Writer = &body
+	var gz *gzip.Writer
+	if s.cfg.Gzip {
+		gz = gzip.NewWriter(&body)
+		w = gz
+	}
+	io.WriteString(w, "[")
+	for i, entry := range batch {
+		if i > 0 {
+			io.WriteString(w, ",")
+		}
+		w.Write(bytes.TrimRight(entry.Data, "\n"))
+	}
+	io.WriteString(w, "]")
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.url, &body)
+	if err != nil {
+		return err
+	}
+	q := req.URL.Query()
+	for k, v := range map[string]string{
+		"service":  s.cfg.Service,
+		"ddsource": s.cfg.Source,
+		"hostname": s.cfg.Hostname,
+		"ddtags":   s.cfg.Tags,
+	} {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	req.URL.RawQuery = q.Encode()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("DD-API-KEY", s.cfg.APIKey)
+	if gz != nil {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	resp, err := s.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		err := fmt.Errorf("log: Datadog intake returned %s: %s", resp.Status, msg)
+		if rejectedStatus(resp.StatusCode) {
+			return PermanentError(err)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package log
+
+import (
+	"strconv"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// DuplicateKeys selects what happens to fields sharing a key, as produced
+// by With chains repeating a key or call sites repeating one set by With.
+type DuplicateKeys int
+
+const (
+	// DuplicateKeep writes every field, which yields JSON objects with
+	// repeated keys. It is the default.
+	DuplicateKeep DuplicateKeys = iota
+	// DuplicateLastWins keeps only the last field with a given key, at the
+	// position of the first.
+	DuplicateLastWins
+	// DuplicateSuffix keeps every field, renaming the repeated ones to
+	// "key_2", "key_3" and so on.
+	DuplicateSuffix
+)
+
+// WithDuplicateKeys sets how fields sharing a key are written by every
+// output of the logger. Keys are compared within the same namespace.
+func WithDuplicateKeys(policy DuplicateKeys) Option {
+	return func(o *options) {
+		o.dupKeys = policy
+	}
+}
+
+// NewDedupEncoder wraps enc to resolve fields sharing a key according to
+// policy.
+func NewDedupEncoder(enc zapcore.Encoder, policy DuplicateKeys) zapcore.Encoder {
+	return newFieldListEncoder(enc, func(fields []zapcore.Field) []zapcore.Field {
+		return resolveDuplicates(fields, policy)
+	})
+}
+
+// resolveDuplicates applies policy to fields, which it may modify.
+func resolveDuplicates(fields []zapcore.Field, policy DuplicateKeys) []zapcore.Field {
+	if policy == DuplicateKeep {
+		return fields
+	}
+	// Keys are qualified by the namespaces opened before them.
+	type slot struct {
+		index int
+		count int
+	}
+	seen := make(map[string]*slot, len(fields))
+	scope := ""
+	out := fields[:0]
+	for _, f := range fields {
+		key := scope + f.Key
+		if f.Type == zapcore.NamespaceType {
+			scope = key + "."
+			out = append(out, f)
+			continue
+		}
+		s, dup := seen[key]
+		if !dup {
+			seen[key] = &slot{index: len(out), count: 1}
+			out = append(out, f)
+			continue
+		}
+		switch policy {
+		case DuplicateLastWins:
+			out[s.index] = f
+		case DuplicateSuffix:
+			for {
+				s.count++
+				name := f.Key + "_" + strconv.Itoa(s.count)
+				if _, taken := seen[scope+name]; !taken {
+					f.Key = name
+					break
+				}
+			}
+			seen[scope+f.Key] = &slot{index: len(out), count: 1}
+			out = append(out, f)
+		default:
+			out = append(out, f)
+		}
+	}
+	return out
+}
@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !freebsd && !windows
+// +build !linux,!darwin,!freebsd,!windows
+
+package log
+
+import "errors"
+
+// diskFree is not implemented on this platform; the disk guard then never
+// changes the level.
+func diskFree(path string) (uint64, error) {
+	return 0, errors.New("log: free space check not supported on this platform")
+}
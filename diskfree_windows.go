@@ -0,0 +1,20 @@
+//go:build windows
+// +build windows
+
+package log
+
+import "golang.org/x/sys/windows"
+
+// diskFree returns the bytes available to the calling user on the volume
+// holding path.
+func diskFree(path string) (uint64, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	var free, total, totalFree uint64
+	if err := windows.GetDiskFreeSpaceEx(p, &free, &total, &totalFree); err != nil {
+		return 0, err
+	}
+	return free, nil
+}
@@ -0,0 +1,102 @@
+package log
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// diskGuard watches the free space of the partition holding path. While it
+// is below minFree, DEBUG and INFO entries are dropped.
+type diskGuard struct {
+	path     string
+	minFree  uint64
+	interval time.Duration
+
+	// core receives the state change announcements. It is set when the
+	// logger is built.
+	core zapcore.Core
+
+	low  uint32
+	stop chan struct{}
+	once sync.Once
+}
+
+func newDiskGuard(path string, minFree uint64, interval time.Duration) *diskGuard {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	return &diskGuard{
+		path:     path,
+		minFree:  minFree,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+}
+
+func (g *diskGuard) isLow() bool {
+	return atomic.LoadUint32(&g.low) == 1
+}
+
+// run polls the free space until close is called, announcing every change
+// of state.
+func (g *diskGuard) run() {
+	t := time.NewTicker(g.interval)
+	defer t.Stop()
+	for {
+		g.check(g.core)
+		select {
+		case <-t.C:
+		case <-g.stop:
+			return
+		}
+	}
+}
+
+func (g *diskGuard) check(core zapcore.Core) {
+	free, err := diskFree(g.path)
+	if err != nil {
+		// Keep the current state; an unreadable partition is no reason
+		// to change the level.
+		return
+	}
+	switch {
+	case free < g.minFree && atomic.CompareAndSwapUint32(&g.low, 0, 1):
+		g.announce(core, zapcore.WarnLevel, "Log partition is low on space, dropping DEBUG and INFO entries", free)
+	case free >= g.minFree && atomic.CompareAndSwapUint32(&g.low, 1, 0):
+		g.announce(core, zapcore.InfoLevel, "Log partition space recovered, resuming normal logging", free)
+	}
+}
+
+func (g *diskGuard) announce(core zapcore.Core, lvl zapcore.Level, msg string, free uint64) {
+	ent := zapcore.Entry{Level: lvl, Time: time.Now(), Message: msg}
+	if ce := core.Check(ent, nil); ce != nil {
+		ce.Write(Str("path", g.path), Any("free", free), Any("threshold", g.minFree))
+	}
+}
+
+func (g *diskGuard) close() {
+	g.once.Do(func() { close(g.stop) })
+}
+
+// diskGuardCore drops entries below WARN while its guard reports low space.
+type diskGuardCore struct {
+	zapcore.Core
+	guard *diskGuard
+}
+
+func (c *diskGuardCore) With(fields []zapcore.Field) zapcore.Core {
+	return &diskGuardCore{c.Core.With(fields), c.guard}
+}
+
+func (c *diskGuardCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level < zapcore.WarnLevel && c.guard.isLow() {
+		if c.Core.Enabled(ent.Level) {
+			atomic.AddUint64(&counters.guarded, 1)
+		}
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
@@ -0,0 +1,62 @@
+package log
+
+import (
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// ECSVersion is the Elastic Common Schema version reported by the ECS
+// encoder.
+const ECSVersion = "1.6.0"
+
+func init() {
+	registerEncoder("ecs", func(zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		return NewECSEncoder(), nil
+	})
+}
+
+type ecsEncoder struct {
+	zapcore.Encoder
+}
+
+// NewECSEncoder returns a JSON encoder using Elastic Common Schema field
+// names, so entries can be indexed by Elastic without ingest pipelines.
+func NewECSEncoder() zapcore.Encoder {
+	cfg := NewEncoderConfig()
+	cfg.TimeKey = "@timestamp"
+	cfg.LevelKey = "log.level"
+	cfg.NameKey = "log.logger"
+	cfg.CallerKey = zapcore.OmitKey
+	cfg.MessageKey = "message"
+	cfg.StacktraceKey = "error.stack_trace"
+	cfg.EncodeLevel = zapcore.LowercaseLevelEncoder
+	cfg.EncodeTime = zapcore.RFC3339NanoTimeEncoder
+	cfg.EncodeDuration = zapcore.NanosDurationEncoder
+
+	enc := zapcore.NewJSONEncoder(cfg)
+	enc.AddString("ecs.version", ECSVersion)
+	return &ecsEncoder{Encoder: enc}
+}
+
+func (e *ecsEncoder) Clone() zapcore.Encoder {
+	return &ecsEncoder{Encoder: e.Encoder.Clone()}
+}
+
+// EncodeEntry spreads the caller over the log.origin fields.
+func (e *ecsEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	if ent.Caller.Defined {
+		file := ent.Caller.TrimmedPath()
+		if i := strings.LastIndexByte(file, ':'); i >= 0 {
+			file = file[:i]
+		}
+		fields = append(fields[:len(fields):len(fields)],
+			zap.String("log.origin.file.name", file),
+			zap.Int("log.origin.file.line", ent.Caller.Line),
+			zap.String("log.origin.function", ent.Caller.Function),
+		)
+	}
+	return e.Encoder.EncodeEntry(ent, fields)
+}
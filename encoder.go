@@ -0,0 +1,59 @@
+package log
+
+import (
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// EncoderConstructor builds an encoder from the package encoder
+// configuration (see NewEncoderConfig).
+type EncoderConstructor func(zapcore.EncoderConfig) (zapcore.Encoder, error)
+
+var (
+	encodersMu sync.RWMutex
+	encoders   = map[string]EncoderConstructor{
+		"json": func(cfg zapcore.EncoderConfig) (zapcore.Encoder, error) {
+			return zapcore.NewJSONEncoder(cfg), nil
+		},
+		"console": func(cfg zapcore.EncoderConfig) (zapcore.Encoder, error) {
+			return zapcore.NewConsoleEncoder(cfg), nil
+		},
+	}
+)
+
+// RegisterEncoder makes an encoder selectable by name with WithEncoding,
+// e.g. for a proprietary format. It fails if the name is already taken.
+func RegisterEncoder(name string, ctor EncoderConstructor) error {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+	if _, ok := encoders[name]; ok {
+		return fmt.Errorf("log: encoder %q is already registered", name)
+	}
+	if err := zap.RegisterEncoder(name, ctor); err != nil {
+		return err
+	}
+	encoders[name] = ctor
+	return nil
+}
+
+// registerEncoder makes a built-in encoder available both to the package's
+// own cores and to zap.Config.
+func registerEncoder(name string, ctor EncoderConstructor) {
+	encodersMu.Lock()
+	encoders[name] = ctor
+	encodersMu.Unlock()
+	_ = zap.RegisterEncoder(name, ctor)
+}
+
+func newEncoder(name string, cfg zapcore.EncoderConfig) (zapcore.Encoder, error) {
+	encodersMu.RLock()
+	ctor, ok := encoders[name]
+	encodersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("log: no encoder registered for name %q", name)
+	}
+	return ctor(cfg)
+}
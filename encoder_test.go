@@ -0,0 +1,104 @@
+package log
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// testEntry returns an entry of a named logger with a caller.
+func testEntry() zapcore.Entry {
+	ent := zapcore.Entry{
+		Level:      zapcore.WarnLevel,
+		Time:       time.Date(2023, 5, 1, 12, 0, 0, 5e6, time.UTC),
+		LoggerName: "net.dhcp",
+		Message:    `lease "expired"`,
+		Caller:     zapcore.NewEntryCaller(0, "/src/net/dhcp.go", 42, true),
+	}
+	ent.Caller.Function = "net.renew"
+	return ent
+}
+
+func TestEncoders(t *testing.T) {
+	fields := []zapcore.Field{
+		zap.String("mac", "00:11"),
+		zap.Int("n", -3),
+		zap.Duration("d", 1500*time.Millisecond),
+		zap.Bool("ok", true),
+		zap.String("note", "two words"),
+	}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"json", `{"level":"WARN","ts":"May 01 12:00:00","logger":"net.dhcp","caller":"net/dhcp.go:42.renew()","msg":"lease \"expired\"",` +
+			`"iface":"eth0","mac":"00:11","n":-3,"d":1.5,"ok":true,"note":"two words"}`},
+		{"console", "May 01 12:00:00\tWARN\tnet.dhcp\tnet/dhcp.go:42.renew()\tlease \"expired\"\t" +
+			`{"iface": "eth0", "mac": "00:11", "n": -3, "d": 1.5, "ok": true, "note": "two words"}`},
+		{"pretty", `May 01 12:00:00 WARN  net/dhcp.go:42               [net.dhcp] lease "expired"                          ` +
+			`iface=eth0 mac=00:11 n=-3 d=1.5s ok=true note="two words"`},
+		{"logfmt", `ts=2023-05-01T12:00:00.005Z level=warn logger=net.dhcp caller=net/dhcp.go:42 msg="lease \"expired\"" ` +
+			`iface=eth0 mac=00:11 n=-3 d=1.5s ok=true note="two words"`},
+		{"ecs", `{"log.level":"warn","@timestamp":"2023-05-01T12:00:00.005Z","log.logger":"net.dhcp","message":"lease \"expired\"",` +
+			`"ecs.version":"` + ECSVersion + `","iface":"eth0","mac":"00:11","n":-3,"d":1500000000,"ok":true,"note":"two words",` +
+			`"log.origin.file.name":"net/dhcp.go","log.origin.file.line":42,"log.origin.function":"net.renew"}`},
+		{"gcp", `{"severity":"WARNING","time":"2023-05-01T12:00:00.005Z","logger":"net.dhcp","message":"lease \"expired\"",` +
+			`"iface":"eth0","mac":"00:11","n":-3,"d":1.5,"ok":true,"note":"two words",` +
+			`"logging.googleapis.com/sourceLocation":{"file":"/src/net/dhcp.go","line":42,"function":"net.renew"}}`},
+		{"datadog", `{"status":"warn","timestamp":1682942400005,"logger.name":"net.dhcp","logger.caller":"net/dhcp.go:42.renew()",` +
+			`"message":"lease \"expired\"","iface":"eth0","mac":"00:11","n":-3,"d":1500000000,"ok":true,"note":"two words"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enc, err := newEncoder(tt.name, NewEncoderConfig())
+			if err != nil {
+				t.Fatal(err)
+			}
+			enc.AddString("iface", "eth0")
+			for i := 0; i < 2; i++ {
+				// The clone encodes the same, and the encoder is unchanged.
+				buf, err := enc.Clone().EncodeEntry(testEntry(), fields)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := buf.String(); got != tt.want+"\n" {
+					t.Errorf("encoded\n%s\nwant\n%s", got, tt.want)
+				}
+				buf.Free()
+			}
+		})
+	}
+}
+
+func TestSIEMEncoders(t *testing.T) {
+	cfg := SIEMConfig{Vendor: "NDM", Product: "ndmd", Version: "2.0"}
+	fields := []zapcore.Field{
+		zap.String("event", "DHCP|1"),
+		zap.String("expr", `a=b\c`),
+		zap.String("note", "two\twords\n"),
+	}
+	tests := []struct {
+		name string
+		enc  zapcore.Encoder
+		want string
+	}{
+		{"CEF", NewCEFEncoder(cfg), `CEF:0|NDM|ndmd|2.0|DHCP\|1|lease "expired"|5|rt=1682942400005 msg=lease "expired" ` +
+			"iface=eth0 expr=a\\=b\\\\c note=two\twords\\n cat=net.dhcp"},
+		{"LEEF", NewLEEFEncoder(cfg), `LEEF:1.0|NDM|ndmd|2.0|DHCP\|1|devTime=1682942400005` +
+			"\tsev=5\tmsg=lease \"expired\"\tiface=eth0\texpr=a=b\\c\tnote=two words \tcat=net.dhcp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.enc.AddString("iface", "eth0")
+			buf, err := tt.enc.EncodeEntry(testEntry(), fields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want+"\n" {
+				t.Errorf("encoded\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
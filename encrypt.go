@@ -0,0 +1,287 @@
+package log
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Encrypted log format. A stream is a sequence of records, each starting
+// with a type byte:
+//
+//	'H' uint16 length, RSA-OAEP(SHA-256) wrapped AES-256 key
+//	'C' uint32 length, AES-GCM sealed chunk
+//
+// Every header starts a segment with a fresh key; chunk nonces are the
+// chunk index within the segment, which is also authenticated, so chunks
+// cannot be reordered or dropped from the middle unnoticed.
+const (
+	encHeaderRecord = 'H'
+	encChunkRecord  = 'C'
+
+	defaultEncChunkSize  = 64 << 10
+	defaultEncFlushDelay = time.Second
+)
+
+// EncryptedWriter is a zapcore.WriteSyncer encrypting everything written
+// to it with a key only the holder of the RSA private key can recover.
+// Data is sealed in chunks of up to 64 KiB, at the latest a second after
+// it is written, so that a crash loses at most that second; Sync seals the
+// current partial chunk at once.
+type EncryptedWriter struct {
+	mu        sync.Mutex
+	out       io.Writer
+	aead      cipher.AEAD
+	buf       []byte
+	chunkSize int
+	delay     time.Duration
+	timer     *time.Timer
+	seq       uint64
+	err       error
+	closed    bool
+}
+
+// NewEncryptedWriter starts a new encrypted segment on out.
+func NewEncryptedWriter(out io.Writer, pub *rsa.PublicKey) (*EncryptedWriter, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	wrapped, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 3, 3+len(wrapped))
+	header[0] = encHeaderRecord
+	binary.BigEndian.PutUint16(header[1:], uint16(len(wrapped)))
+	if _, err := out.Write(append(header, wrapped...)); err != nil {
+		return nil, err
+	}
+	return &EncryptedWriter{
+		out:       out,
+		aead:      aead,
+		chunkSize: defaultEncChunkSize,
+		delay:     defaultEncFlushDelay,
+	}, nil
+}
+
+// OpenEncryptedFile opens path for appending and starts a new encrypted
+// segment in it.
+func OpenEncryptedFile(path string, pub *rsa.PublicKey) (*EncryptedWriter, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	w, err := NewEncryptedWriter(f, pub)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write buffers p and seals every full chunk. The rest is sealed by Sync
+// or after the flush delay.
+func (w *EncryptedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	w.buf = append(w.buf, p...)
+	for len(w.buf) >= w.chunkSize {
+		if err := w.seal(w.buf[:w.chunkSize]); err != nil {
+			return 0, err
+		}
+		w.buf = append(w.buf[:0], w.buf[w.chunkSize:]...)
+	}
+	if len(w.buf) > 0 && w.timer == nil {
+		w.timer = time.AfterFunc(w.delay, w.flushDelayed)
+	}
+	return len(p), nil
+}
+
+// flushDelayed seals the data buffered for the flush delay. Errors are
+// reported by the next Sync.
+func (w *EncryptedWriter) flushDelayed() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.timer = nil
+	if err := w.flush(); err != nil {
+		w.err = err
+	}
+}
+
+// flush seals buffered data. The caller holds w.mu.
+func (w *EncryptedWriter) flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	if err := w.seal(w.buf); err != nil {
+		return err
+	}
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// Sync seals buffered data and syncs the output if it supports it.
+func (w *EncryptedWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.sync()
+}
+
+// sync implements Sync. The caller holds w.mu.
+func (w *EncryptedWriter) sync() error {
+	err := w.err
+	w.err = nil
+	if ferr := w.flush(); ferr != nil {
+		return ferr
+	}
+	if s, ok := w.out.(interface{ Sync() error }); ok {
+		if serr := ignoreSyncError(s.Sync()); serr != nil {
+			return serr
+		}
+	}
+	return err
+}
+
+// Close seals buffered data and closes the output if it is an io.Closer,
+// also when sealing fails. Later writes fail with os.ErrClosed.
+func (w *EncryptedWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+	w.closed = true
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	err := w.sync()
+	if c, ok := w.out.(io.Closer); ok {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+func (w *EncryptedWriter) seal(plain []byte) error {
+	nonce := make([]byte, w.aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], w.seq)
+	sealed := w.aead.Seal(nil, nonce, plain, nonce)
+	w.seq++
+
+	rec := make([]byte, 5, 5+len(sealed))
+	rec[0] = encChunkRecord
+	binary.BigEndian.PutUint32(rec[1:], uint32(len(sealed)))
+	_, err := w.out.Write(append(rec, sealed...))
+	return err
+}
+
+// DecryptLog writes the plain text of an encrypted log read from r to w.
+func DecryptLog(w io.Writer, r io.Reader, priv *rsa.PrivateKey) error {
+	br := bufio.NewReader(r)
+	var aead cipher.AEAD
+	var seq uint64
+	for {
+		kind, err := br.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch kind {
+		case encHeaderRecord:
+			var n uint16
+			if err := binary.Read(br, binary.BigEndian, &n); err != nil {
+				return err
+			}
+			wrapped := make([]byte, n)
+			if _, err := io.ReadFull(br, wrapped); err != nil {
+				return err
+			}
+			key, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, priv, wrapped, nil)
+			if err != nil {
+				return err
+			}
+			block, err := aes.NewCipher(key)
+			if err != nil {
+				return err
+			}
+			if aead, err = cipher.NewGCM(block); err != nil {
+				return err
+			}
+			seq = 0
+		case encChunkRecord:
+			if aead == nil {
+				return errors.New("log: encrypted chunk before header")
+			}
+			var n uint32
+			if err := binary.Read(br, binary.BigEndian, &n); err != nil {
+				return err
+			}
+			sealed := make([]byte, n)
+			if _, err := io.ReadFull(br, sealed); err != nil {
+				return err
+			}
+			nonce := make([]byte, aead.NonceSize())
+			binary.BigEndian.PutUint64(nonce[len(nonce)-8:], seq)
+			plain, err := aead.Open(nil, nonce, sealed, nonce)
+			if err != nil {
+				return fmt.Errorf("log: chunk %d of segment failed authentication: %w", seq, err)
+			}
+			seq++
+			if _, err := w.Write(plain); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("log: unknown encrypted record type %q", kind)
+		}
+	}
+}
+
+// ParsePublicKeyPEM parses an RSA public key in PKIX or PKCS #1 PEM form.
+func ParsePublicKeyPEM(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("log: no PEM block found")
+	}
+	if block.Type == "RSA PUBLIC KEY" {
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	pub, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("log: public key is not an RSA key")
+	}
+	return pub, nil
+}
@@ -0,0 +1,173 @@
+package log
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testKey returns a small RSA key, enough for the tests.
+func testKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// decrypt returns the plain text of the encrypted log in data.
+func decrypt(t *testing.T, data []byte, key *rsa.PrivateKey) string {
+	t.Helper()
+	var plain bytes.Buffer
+	if err := DecryptLog(&plain, bytes.NewReader(data), key); err != nil {
+		t.Fatal(err)
+	}
+	return plain.String()
+}
+
+func TestEncryptedWriterFlushesAfterDelay(t *testing.T) {
+	key := testKey(t)
+	var out syncBuffer
+	w, err := NewEncryptedWriter(&out, &key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.delay = 10 * time.Millisecond
+	if _, err := w.Write([]byte("entry\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for decrypt(t, []byte(out.String()), key) == "" {
+		if time.Now().After(deadline) {
+			t.Fatal("entry not sealed without Sync")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := decrypt(t, []byte(out.String()), key); got != "entry\n" {
+		t.Errorf("decrypted %q, want %q", got, "entry\n")
+	}
+}
+
+func TestEncryptedWriterClose(t *testing.T) {
+	key := testKey(t)
+	var out syncBuffer
+	w, err := NewEncryptedWriter(&out, &key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("last\n"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := decrypt(t, []byte(out.String()), key); got != "last\n" {
+		t.Errorf("decrypted %q, want %q", got, "last\n")
+	}
+	if _, err := w.Write([]byte("late\n")); err != os.ErrClosed {
+		t.Errorf("Write after Close: %v, want %v", err, os.ErrClosed)
+	}
+	if err := w.Close(); err != os.ErrClosed {
+		t.Errorf("second Close: %v, want %v", err, os.ErrClosed)
+	}
+}
+
+func TestEncryptedWriterRoundTrip(t *testing.T) {
+	big := bytes.Repeat([]byte("0123456789abcdef"), defaultEncChunkSize/8)
+	tests := []struct {
+		name   string
+		writes [][]byte
+	}{
+		{"empty", nil},
+		{"entries", [][]byte{[]byte("a\n"), []byte("b\n"), []byte("c\n")}},
+		{"chunks", [][]byte{[]byte("head\n"), big, []byte("tail\n")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := testKey(t)
+			var out syncBuffer
+			w, err := NewEncryptedWriter(&out, &key.PublicKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want []byte
+			for i, p := range tt.writes {
+				if _, err := w.Write(p); err != nil {
+					t.Fatal(err)
+				}
+				want = append(want, p...)
+				if i == 0 {
+					w.Sync()
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Contains([]byte(out.String()), []byte("head")) {
+				t.Error("plain text in the encrypted log")
+			}
+			if got := decrypt(t, []byte(out.String()), key); got != string(want) {
+				t.Errorf("decrypted %d bytes, want %d", len(got), len(want))
+			}
+		})
+	}
+}
+
+func TestEncryptedWriterSegments(t *testing.T) {
+	key := testKey(t)
+	path := filepath.Join(t.TempDir(), "app.log.enc")
+	for _, s := range []string{"first\n", "second\n"} {
+		w, err := OpenEncryptedFile(path, &key.PublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(s))
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := decrypt(t, data, key); got != "first\nsecond\n" {
+		t.Errorf("decrypted %q, want %q", got, "first\nsecond\n")
+	}
+}
+
+func TestDecryptLogRejects(t *testing.T) {
+	key := testKey(t)
+	var out syncBuffer
+	w, err := NewEncryptedWriter(&out, &key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("secret\n"))
+	w.Close()
+	data := []byte(out.String())
+
+	tampered := append([]byte(nil), data...)
+	tampered[len(tampered)-1] ^= 1
+	tests := []struct {
+		name string
+		data []byte
+		key  *rsa.PrivateKey
+	}{
+		{"wrong key", data, testKey(t)},
+		{"tampered", tampered, key},
+		{"truncated", data[:len(data)-1], key},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var plain bytes.Buffer
+			if err := DecryptLog(&plain, bytes.NewReader(tt.data), tt.key); err == nil {
+				t.Errorf("decrypted %q", plain.String())
+			}
+		})
+	}
+}
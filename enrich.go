@@ -0,0 +1,42 @@
+package log
+
+import "go.uber.org/zap/zapcore"
+
+// enricher appends fields computed for each entry to fields, which it may
+// not modify otherwise.
+type enricher func(ent zapcore.Entry, fields []zapcore.Field) []zapcore.Field
+
+// withEnricher returns an option wrapping the core to apply add to every
+// entry.
+func withEnricher(add enricher) Option {
+	return WrapCore(func(c zapcore.Core) zapcore.Core {
+		return &enrichCore{c, add}
+	})
+}
+
+// enrichCore adds fields to the entries written to the core it wraps. It
+// runs in Write, on the goroutine that logs, after the level checks.
+type enrichCore struct {
+	zapcore.Core
+	add enricher
+}
+
+func (c *enrichCore) With(fields []zapcore.Field) zapcore.Core {
+	return &enrichCore{c.Core.With(fields), c.add}
+}
+
+func (c *enrichCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write computes the fields only once the wrapped core has accepted the
+// entry, so that entries dropped by the sampler cost nothing.
+func (c *enrichCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if ce := c.Core.Check(ent, nil); ce != nil {
+		ce.Write(c.add(ent, fields[:len(fields):len(fields)])...)
+	}
+	return nil
+}
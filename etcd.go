@@ -0,0 +1,229 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// EtcdConfig describes an etcd key holding a level spec. The key is read
+// through the JSON gateway of etcd v3, so no etcd client is needed.
+type EtcdConfig struct {
+	// Endpoint is the URL of an etcd member, e.g. "http://127.0.0.1:2379".
+	Endpoint string
+	// Key is the key holding the spec, e.g. "/config/ndmd/log-levels".
+	Key string
+	// Username and Password authenticate, if etcd has auth enabled.
+	Username   string
+	Password   string
+	TLS        *TLSConfig
+	HTTPClient *http.Client
+}
+
+// EtcdLevelSource is a LevelSource reading an etcd key and watching it for
+// changes.
+type EtcdLevelSource struct {
+	cfg      EtcdConfig
+	endpoint string
+	token    string
+	rev      int64
+	started  bool
+	last     string
+	watch    io.ReadCloser
+	dec      *json.Decoder
+}
+
+// NewEtcdLevelSource returns a source watching cfg.Key.
+func NewEtcdLevelSource(cfg EtcdConfig) (*EtcdLevelSource, error) {
+	if cfg.Endpoint == "" || cfg.Key == "" {
+		return nil, errors.New("log: etcd endpoint and key are required")
+	}
+	if cfg.HTTPClient == nil {
+		client, err := newWatchClient(cfg.Endpoint, cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		cfg.HTTPClient = client
+	}
+	return &EtcdLevelSource{cfg: cfg, endpoint: strings.TrimRight(cfg.Endpoint, "/")}, nil
+}
+
+type etcdKV struct {
+	Value       []byte `json:"value"`
+	ModRevision int64  `json:"mod_revision,string"`
+}
+
+type etcdHeader struct {
+	Revision int64 `json:"revision,string"`
+}
+
+// Next implements LevelSource.
+func (s *EtcdLevelSource) Next(ctx context.Context) (string, error) {
+	if !s.started {
+		spec, err := s.get(ctx)
+		if err != nil {
+			return "", err
+		}
+		s.started, s.last = true, spec
+		return spec, nil
+	}
+	for {
+		if s.dec == nil {
+			if err := s.openWatch(ctx); err != nil {
+				return "", err
+			}
+		}
+		var msg struct {
+			Result struct {
+				Header          etcdHeader `json:"header"`
+				Canceled        bool       `json:"canceled"`
+				CompactRevision int64      `json:"compact_revision,string"`
+				Events          []struct {
+					Type string `json:"type"`
+					KV   etcdKV `json:"kv"`
+				} `json:"events"`
+			} `json:"result"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := s.dec.Decode(&msg); err != nil {
+			s.closeWatch()
+			return "", err
+		}
+		if msg.Error != nil {
+			s.closeWatch()
+			return "", fmt.Errorf("log: etcd watch failed: %s", msg.Error.Message)
+		}
+		if msg.Result.Canceled {
+			// The revision was compacted: read the key again.
+			s.closeWatch()
+			s.started = false
+			return s.Next(ctx)
+		}
+		spec := s.last
+		for _, ev := range msg.Result.Events {
+			if ev.Type == "DELETE" {
+				spec = ""
+			} else {
+				spec = strings.TrimSpace(string(ev.KV.Value))
+			}
+			if ev.KV.ModRevision > s.rev {
+				s.rev = ev.KV.ModRevision
+			}
+		}
+		if spec != s.last {
+			s.last = spec
+			return spec, nil
+		}
+	}
+}
+
+// get reads the key and the revision to watch from.
+func (s *EtcdLevelSource) get(ctx context.Context) (string, error) {
+	var out struct {
+		Header etcdHeader `json:"header"`
+		KVs    []etcdKV   `json:"kvs"`
+	}
+	resp, err := s.post(ctx, "/v3/kv/range", map[string]interface{}{"key": []byte(s.cfg.Key)})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", err
+	}
+	s.rev = out.Header.Revision
+	if len(out.KVs) == 0 {
+		return "", nil
+	}
+	return strings.TrimSpace(string(out.KVs[0].Value)), nil
+}
+
+func (s *EtcdLevelSource) openWatch(ctx context.Context) error {
+	resp, err := s.post(ctx, "/v3/watch", map[string]interface{}{
+		"create_request": map[string]interface{}{
+			"key":            []byte(s.cfg.Key),
+			"start_revision": s.rev + 1,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	s.watch, s.dec = resp.Body, json.NewDecoder(resp.Body)
+	return nil
+}
+
+func (s *EtcdLevelSource) closeWatch() {
+	if s.watch != nil {
+		s.watch.Close()
+	}
+	s.watch, s.dec = nil, nil
+}
+
+// post sends a gateway request, authenticating first if needed. The caller
+// closes the body of the response.
+func (s *EtcdLevelSource) post(ctx context.Context, path string, body interface{}) (*http.Response, error) {
+	if s.cfg.Username != "" && s.token == "" {
+		if err := s.authenticate(ctx); err != nil {
+			return nil, err
+		}
+	}
+	resp, err := s.do(ctx, path, body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		// The token may have expired.
+		s.token = ""
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("log: etcd returned %s: %s", resp.Status, msg)
+	}
+	return resp, nil
+}
+
+func (s *EtcdLevelSource) authenticate(ctx context.Context) error {
+	resp, err := s.do(ctx, "/v3/auth/authenticate", map[string]string{
+		"name":     s.cfg.Username,
+		"password": s.cfg.Password,
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("log: etcd authentication returned %s", resp.Status)
+	}
+	var out struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return err
+	}
+	s.token = out.Token
+	return nil
+}
+
+func (s *EtcdLevelSource) do(ctx context.Context, path string, body interface{}) (*http.Response, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.endpoint+path, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", s.token)
+	}
+	return s.cfg.HTTPClient.Do(req)
+}
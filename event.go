@@ -0,0 +1,120 @@
+package log
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// EventType describes an operational event with a stable code, so that
+// dashboards and alerts can key on the code instead of the message:
+//
+//	var LeaseExpired = log.DefineEvent(log.EventType{
+//		Code:    1201,
+//		Name:    "DHCP_LEASE_EXPIRED",
+//		Level:   log.WarningLevel,
+//		Message: "DHCP lease expired",
+//	})
+//
+//	log.Event(LeaseExpired, zap.String("mac", mac))
+type EventType struct {
+	Code int
+	Name string
+	// Level is the level the event is logged at.
+	Level Level
+	// Message is the message of the entries. Defaults to Name.
+	Message string
+}
+
+// Fields returns the fields identifying the event in an entry.
+func (e EventType) Fields() []Field {
+	return []Field{zap.String("event", e.Name), zap.Int("event_code", e.Code)}
+}
+
+func (e EventType) message() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return e.Name
+}
+
+var (
+	eventsMu sync.RWMutex
+	events   = make(map[int]EventType)
+)
+
+// DefineEvent adds e to the catalog of events and returns it. It panics if
+// e has no name or if its code or name is already taken by another event,
+// so that codes stay unique across the packages of a program.
+func DefineEvent(e EventType) EventType {
+	if e.Name == "" {
+		panic(fmt.Sprintf("log: event %d has no name", e.Code))
+	}
+	eventsMu.Lock()
+	defer eventsMu.Unlock()
+	if prev, ok := events[e.Code]; ok && prev != e {
+		panic(fmt.Sprintf("log: event code %d already used by %s", e.Code, prev.Name))
+	}
+	for _, prev := range events {
+		if prev.Name == e.Name && prev.Code != e.Code {
+			panic(fmt.Sprintf("log: event %s already defined with code %d", e.Name, prev.Code))
+		}
+	}
+	events[e.Code] = e
+	return e
+}
+
+// LookupEvent returns the event defined with code.
+func LookupEvent(code int) (EventType, bool) {
+	eventsMu.RLock()
+	defer eventsMu.RUnlock()
+	e, ok := events[code]
+	return e, ok
+}
+
+// Events returns the catalog of events defined with DefineEvent, ordered
+// by code.
+func Events() []EventType {
+	eventsMu.RLock()
+	all := make([]EventType, 0, len(events))
+	for _, e := range events {
+		all = append(all, e)
+	}
+	eventsMu.RUnlock()
+	sort.Slice(all, func(i, j int) bool { return all[i].Code < all[j].Code })
+	return all
+}
+
+// Event logs e at its level with the event and event_code fields, followed
+// by fields.
+func Event(e EventType, fields ...Field) {
+	if ce := std().base.Check(zapcore.Level(e.Level), e.message()); ce != nil {
+		ce.Write(append(e.Fields(), fields...)...)
+	}
+}
+
+// Event logs e through lg.
+func (lg *Logger) Event(e EventType, fields ...Field) {
+	if ce := lg.base.Check(zapcore.Level(e.Level), e.message()); ce != nil {
+		ce.Write(append(e.Fields(), fields...)...)
+	}
+}
+
+// CheckEvent returns the entry of e if its level is enabled, and nil
+// otherwise, for typed wrappers of Event such as those generated by
+// gologgen: the entry reports the caller of the function calling
+// CheckEvent, and is written with the fields of e and those of the event.
+//
+//	if ce := log.CheckEvent(LeaseExpired); ce != nil {
+//		ce.Write(append(LeaseExpired.Fields(), zap.String("mac", mac))...)
+//	}
+func CheckEvent(e EventType) *zapcore.CheckedEntry {
+	lg := std()
+	if !lg.base.Core().Enabled(zapcore.Level(e.Level)) {
+		return nil
+	}
+	return lg.base.WithOptions(zap.AddCallerSkip(1)).Check(zapcore.Level(e.Level), e.message())
+}
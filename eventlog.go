@@ -0,0 +1,39 @@
+package log
+
+import "go.uber.org/zap/zapcore"
+
+// EventLogConfig describes the Windows Event Log core.
+type EventLogConfig struct {
+	// Source is the event source name the entries are reported under.
+	Source string
+	// Level is the minimum level written to the event log.
+	Level Level
+	// InfoID, WarningID and ErrorID are the event IDs used for entries of
+	// the corresponding severity. Debug entries are reported as
+	// information, Panic and Fatal as errors.
+	InfoID    uint32
+	WarningID uint32
+	ErrorID   uint32
+}
+
+func (cfg EventLogConfig) withDefaults() EventLogConfig {
+	if cfg.InfoID == 0 {
+		cfg.InfoID = 1
+	}
+	if cfg.WarningID == 0 {
+		cfg.WarningID = 2
+	}
+	if cfg.ErrorID == 0 {
+		cfg.ErrorID = 3
+	}
+	return cfg
+}
+
+// newEventLogEncoder returns the encoder used for event messages. The event
+// log stamps entries itself, so the time is left out.
+func newEventLogEncoder() zapcore.Encoder {
+	cfg := NewEncoderConfig()
+	cfg.TimeKey = zapcore.OmitKey
+	cfg.LineEnding = ""
+	return zapcore.NewJSONEncoder(cfg)
+}
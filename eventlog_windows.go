@@ -0,0 +1,72 @@
+//go:build windows
+// +build windows
+
+package log
+
+import (
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+type eventLogCore struct {
+	zapcore.LevelEnabler
+	enc zapcore.Encoder
+	log *eventlog.Log
+	cfg EventLogConfig
+}
+
+// NewEventLogCore returns a core writing entries to the Windows Event Log.
+// On other platforms it returns a no-op core, so the same setup code can be
+// used everywhere.
+func NewEventLogCore(cfg EventLogConfig) (zapcore.Core, error) {
+	el, err := eventlog.Open(cfg.Source)
+	if err != nil {
+		return nil, err
+	}
+	return &eventLogCore{
+		LevelEnabler: zapcore.Level(cfg.Level),
+		enc:          newEventLogEncoder(),
+		log:          el,
+		cfg:          cfg.withDefaults(),
+	}, nil
+}
+
+func (c *eventLogCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.enc = c.enc.Clone()
+	for i := range fields {
+		fields[i].AddTo(clone.enc)
+	}
+	return &clone
+}
+
+func (c *eventLogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *eventLogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	msg := strings.TrimSpace(buf.String())
+	buf.Free()
+
+	switch {
+	case ent.Level >= zapcore.ErrorLevel:
+		return c.log.Error(c.cfg.ErrorID, msg)
+	case ent.Level == zapcore.WarnLevel:
+		return c.log.Warning(c.cfg.WarningID, msg)
+	default:
+		return c.log.Info(c.cfg.InfoID, msg)
+	}
+}
+
+func (c *eventLogCore) Sync() error {
+	return nil
+}
@@ -0,0 +1,62 @@
+package log
+
+import (
+	"os"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	exitMu    sync.Mutex
+	exitHooks []func()
+)
+
+// RegisterExitHook registers fn to be run by Fatal* functions before the
+// process exits. Hooks run in registration order.
+func RegisterExitHook(fn func()) {
+	exitMu.Lock()
+	exitHooks = append(exitHooks, fn)
+	exitMu.Unlock()
+}
+
+// exitHook is installed as the zap fatal hook, so that Fatal entries logged
+// through any path run the same exit sequence, that of the logger the hook
+// was built for, even if it is no longer the package logger.
+type exitHook struct {
+	lg *Logger
+}
+
+func (h *exitHook) OnWrite(ce *zapcore.CheckedEntry, _ []zapcore.Field) {
+	lg := h.lg
+	_ = lg.writeCrashReport("fatal: "+ce.Message, nil)
+	if lg.fatalDump {
+		dump := goroutineDump()
+		if len(dump) > maxCrashGoroutines {
+			dump = dump[:maxCrashGoroutines]
+		}
+		lg.base.WithOptions(zap.WithCaller(false)).Error("goroutines at fatal: "+ce.Message,
+			zap.ByteString("goroutines", dump))
+	}
+	lg.exit()
+}
+
+// exit flushes lg, runs the exit hooks and terminates the process with the
+// exit code of lg. The logger is flushed before the hooks as well, so a
+// misbehaving hook cannot lose the fatal entry. Both flushes share the exit
+// timeout.
+func (lg *Logger) exit() {
+	deadline := lg.startExit()
+	_ = within(deadline, lg.Sync)
+
+	exitMu.Lock()
+	hooks := exitHooks
+	exitMu.Unlock()
+	for _, fn := range hooks {
+		fn()
+	}
+
+	_ = within(deadline, lg.Sync)
+	os.Exit(lg.exitCode)
+}
@@ -0,0 +1,39 @@
+package log
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestFatalUsesOwnLogger checks that a logger kept after Init exits with
+// its own crash report and exit code, not those of the package logger.
+func TestFatalUsesOwnLogger(t *testing.T) {
+	if report := os.Getenv("GOLOG_TEST_CRASH_REPORT"); report != "" {
+		Init(false, WithEncoding("json"), WithCrashReport(report), WithExitCode(3))
+		lg := std()
+		Init(false, WithEncoding("json"))
+		lg.Fatal("kept logger")
+		return
+	}
+
+	report := filepath.Join(t.TempDir(), "crash.txt")
+	cmd := exec.Command(os.Args[0], "-test.run=^TestFatalUsesOwnLogger$")
+	cmd.Env = append(os.Environ(), "GOLOG_TEST_CRASH_REPORT="+report)
+	out, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+		t.Fatalf("exited with %v, want code 3\n%s", err, out)
+	}
+	data, err := ioutil.ReadFile(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "crash: fatal: kept logger\n") {
+		t.Errorf("crash report starts with %.40q", data)
+	}
+}
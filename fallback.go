@@ -0,0 +1,95 @@
+package log
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// FallbackWriter is a zapcore.WriteSyncer that writes to a primary output
+// and, when a write to it fails (disk full, network down), to a secondary
+// one instead. The primary is retried periodically and used again as soon
+// as a write succeeds.
+type FallbackWriter struct {
+	primary   zapcore.WriteSyncer
+	secondary zapcore.WriteSyncer
+	retry     time.Duration
+
+	mu       sync.Mutex
+	failing  bool
+	until    time.Time
+	failures uint64
+	err      error
+}
+
+// FallbackStatus is the state of a FallbackWriter.
+type FallbackStatus struct {
+	// Failing is set while entries go to the secondary output.
+	Failing bool
+	// Failures counts the failed writes to the primary output.
+	Failures uint64
+	// LastError is the error of the last failed write.
+	LastError error
+}
+
+// NewFallbackWriter returns a FallbackWriter writing to primary, or to
+// secondary (stderr if nil) for retry (30 seconds if zero) after each
+// failure.
+func NewFallbackWriter(primary, secondary zapcore.WriteSyncer, retry time.Duration) *FallbackWriter {
+	if secondary == nil {
+		secondary = zapcore.Lock(os.Stderr)
+	}
+	if retry <= 0 {
+		retry = 30 * time.Second
+	}
+	return &FallbackWriter{
+		primary:   primary,
+		secondary: secondary,
+		retry:     retry,
+	}
+}
+
+// Write writes p to the primary output, or to the secondary one if the
+// primary has failed recently or fails now. Failures of the primary are
+// recorded rather than returned; see Status and ReadStats.
+func (w *FallbackWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.failing || !time.Now().Before(w.until) {
+		n, err := w.primary.Write(p)
+		if err == nil {
+			w.failing = false
+			return n, nil
+		}
+		w.failing = true
+		w.until = time.Now().Add(w.retry)
+		w.failures++
+		w.err = err
+		atomic.AddUint64(&counters.sinkErrors, 1)
+	}
+	return w.secondary.Write(p)
+}
+
+// Sync syncs the output in use.
+func (w *FallbackWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.failing {
+		return w.secondary.Sync()
+	}
+	return w.primary.Sync()
+}
+
+// Status returns the state of the writer.
+func (w *FallbackWriter) Status() FallbackStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return FallbackStatus{
+		Failing:   w.failing,
+		Failures:  w.failures,
+		LastError: w.err,
+	}
+}
@@ -0,0 +1,57 @@
+package log
+
+import (
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Field is a strongly typed key/value pair attached to an entry.
+type Field = zap.Field
+
+// Str constructs a field with a string value.
+func Str(key string, val string) Field {
+	return zap.String(key, val)
+}
+
+// Int constructs a field with an int value.
+func Int(key string, val int) Field {
+	return zap.Int(key, val)
+}
+
+// Bool constructs a field with a bool value.
+func Bool(key string, val bool) Field {
+	return zap.Bool(key, val)
+}
+
+// Dur constructs a field with a time.Duration value.
+func Dur(key string, val time.Duration) Field {
+	return zap.Duration(key, val)
+}
+
+// Err constructs a field carrying an error under the "error" key.
+func Err(err error) Field {
+	return zap.Error(err)
+}
+
+// Any constructs a field with an arbitrary value, choosing the best
+// representation for its type.
+func Any(key string, val interface{}) Field {
+	return zap.Any(key, val)
+}
+
+// MapToArgs flattens fields into alternating keys and values, sorted by key
+// so the output is stable, for use with the *w functions and With.
+func MapToArgs(fields map[string]interface{}) []interface{} {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	args := make([]interface{}, 0, 2*len(keys))
+	for _, k := range keys {
+		args = append(args, k, fields[k])
+	}
+	return args
+}
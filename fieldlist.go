@@ -0,0 +1,157 @@
+package log
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// fieldsEncoding returns the name of an encoder registered as the encoder
+// called name wrapped to apply WithDuplicateKeys and WithSortedKeys,
+// registering it if needed.
+func (o *options) fieldsEncoding(name string) string {
+	if o.dupKeys == DuplicateKeep && !o.sortKeys {
+		return name
+	}
+	derived := name
+	if o.dupKeys != DuplicateKeep {
+		derived += "+dedup" + strconv.Itoa(int(o.dupKeys))
+	}
+	if o.sortKeys {
+		derived += "+sorted"
+	}
+	encodersMu.RLock()
+	_, done := encoders[derived]
+	ctor, ok := encoders[name]
+	encodersMu.RUnlock()
+	if done || !ok {
+		return derived
+	}
+	policy, sorted := o.dupKeys, o.sortKeys
+	registerEncoder(derived, func(cfg zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		enc, err := ctor(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return newFieldListEncoder(enc, func(fields []zapcore.Field) []zapcore.Field {
+			fields = resolveDuplicates(fields, policy)
+			if sorted {
+				sortFields(fields)
+			}
+			return fields
+		}), nil
+	})
+	return derived
+}
+
+// WithSortedKeys writes the fields of every entry sorted by key, so that
+// captured logs compare equal across runs whatever the order of With calls
+// and call-site fields. Fields inside a namespace are sorted among
+// themselves.
+func WithSortedKeys() Option {
+	return func(o *options) {
+		o.sortKeys = true
+	}
+}
+
+// NewSortedEncoder wraps enc to write fields sorted by key.
+func NewSortedEncoder(enc zapcore.Encoder) zapcore.Encoder {
+	return newFieldListEncoder(enc, func(fields []zapcore.Field) []zapcore.Field {
+		sortFields(fields)
+		return fields
+	})
+}
+
+// sortFields sorts fields by key in place, between namespaces: a namespace
+// holds every field after it, so it stays last.
+func sortFields(fields []zapcore.Field) {
+	start := 0
+	for i, f := range fields {
+		if f.Type == zapcore.NamespaceType {
+			sortKeys(fields[start:i])
+			start = i + 1
+		}
+	}
+	sortKeys(fields[start:])
+}
+
+func sortKeys(fields []zapcore.Field) {
+	sort.SliceStable(fields, func(i, j int) bool {
+		return fields[i].Key < fields[j].Key
+	})
+}
+
+// fieldListEncoder holds the fields added with With rather than encoding
+// them, so that they can be processed along with those of each entry by
+// resolve. This costs encoding them again for every entry.
+type fieldListEncoder struct {
+	enc     zapcore.Encoder
+	resolve func([]zapcore.Field) []zapcore.Field
+	ctx     []zapcore.Field
+}
+
+func newFieldListEncoder(enc zapcore.Encoder, resolve func([]zapcore.Field) []zapcore.Field) zapcore.Encoder {
+	return &fieldListEncoder{enc: enc, resolve: resolve}
+}
+
+func (e *fieldListEncoder) Clone() zapcore.Encoder {
+	return &fieldListEncoder{
+		enc:     e.enc,
+		resolve: e.resolve,
+		ctx:     e.ctx[:len(e.ctx):len(e.ctx)],
+	}
+}
+
+// EncodeEntry hands a fresh slice to resolve, which may modify it.
+func (e *fieldListEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	all := make([]zapcore.Field, 0, len(e.ctx)+len(fields))
+	all = append(all, e.ctx...)
+	all = append(all, fields...)
+	return e.enc.EncodeEntry(ent, e.resolve(all))
+}
+
+func (e *fieldListEncoder) add(f zapcore.Field) {
+	e.ctx = append(e.ctx, f)
+}
+
+func (e *fieldListEncoder) AddArray(k string, v zapcore.ArrayMarshaler) error {
+	e.add(zap.Array(k, v))
+	return nil
+}
+
+func (e *fieldListEncoder) AddObject(k string, v zapcore.ObjectMarshaler) error {
+	e.add(zap.Object(k, v))
+	return nil
+}
+
+func (e *fieldListEncoder) AddBinary(k string, v []byte)          { e.add(zap.Binary(k, v)) }
+func (e *fieldListEncoder) AddByteString(k string, v []byte)      { e.add(zap.ByteString(k, v)) }
+func (e *fieldListEncoder) AddBool(k string, v bool)              { e.add(zap.Bool(k, v)) }
+func (e *fieldListEncoder) AddComplex128(k string, v complex128)  { e.add(zap.Complex128(k, v)) }
+func (e *fieldListEncoder) AddComplex64(k string, v complex64)    { e.add(zap.Complex64(k, v)) }
+func (e *fieldListEncoder) AddDuration(k string, v time.Duration) { e.add(zap.Duration(k, v)) }
+func (e *fieldListEncoder) AddFloat64(k string, v float64)        { e.add(zap.Float64(k, v)) }
+func (e *fieldListEncoder) AddFloat32(k string, v float32)        { e.add(zap.Float32(k, v)) }
+func (e *fieldListEncoder) AddInt(k string, v int)                { e.add(zap.Int(k, v)) }
+func (e *fieldListEncoder) AddInt64(k string, v int64)            { e.add(zap.Int64(k, v)) }
+func (e *fieldListEncoder) AddInt32(k string, v int32)            { e.add(zap.Int32(k, v)) }
+func (e *fieldListEncoder) AddInt16(k string, v int16)            { e.add(zap.Int16(k, v)) }
+func (e *fieldListEncoder) AddInt8(k string, v int8)              { e.add(zap.Int8(k, v)) }
+func (e *fieldListEncoder) AddString(k, v string)                 { e.add(zap.String(k, v)) }
+func (e *fieldListEncoder) AddTime(k string, v time.Time)         { e.add(zap.Time(k, v)) }
+func (e *fieldListEncoder) AddUint(k string, v uint)              { e.add(zap.Uint(k, v)) }
+func (e *fieldListEncoder) AddUint64(k string, v uint64)          { e.add(zap.Uint64(k, v)) }
+func (e *fieldListEncoder) AddUint32(k string, v uint32)          { e.add(zap.Uint32(k, v)) }
+func (e *fieldListEncoder) AddUint16(k string, v uint16)          { e.add(zap.Uint16(k, v)) }
+func (e *fieldListEncoder) AddUint8(k string, v uint8)            { e.add(zap.Uint8(k, v)) }
+func (e *fieldListEncoder) AddUintptr(k string, v uintptr)        { e.add(zap.Uintptr(k, v)) }
+func (e *fieldListEncoder) OpenNamespace(k string)                { e.add(zap.Namespace(k)) }
+
+func (e *fieldListEncoder) AddReflected(k string, v interface{}) error {
+	e.add(zap.Reflect(k, v))
+	return nil
+}
@@ -0,0 +1,127 @@
+package log
+
+import (
+	"net/url"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func init() {
+	// "fifo:///run/log.pipe" can be used wherever an output path is
+	// accepted, e.g. WithAudit or StreamConfig.Paths.
+	_ = zap.RegisterSink("fifo", func(u *url.URL) (zap.Sink, error) {
+		return NewFIFOWriter(u.Path, 0), nil
+	})
+}
+
+// FIFOWriter is a zapcore.WriteSyncer writing to a named pipe without ever
+// blocking. The pipe is opened in non-blocking mode, which fails while no
+// reader is attached; it is retried every retry interval and entries are
+// dropped meanwhile. Entries are also dropped, rather than waiting, when
+// the reader does not keep up and the pipe is full. Both are counted by
+// Dropped. An entry larger than the pipe buffer that fills it partway is
+// finished before the next one is written, so that the reader never gets
+// half a line. FIFOs are supported on Linux, macOS and FreeBSD.
+type FIFOWriter struct {
+	path  string
+	retry time.Duration
+
+	mu      sync.Mutex
+	fd      int
+	open    bool
+	next    time.Time
+	rest    []byte // of a partly written entry
+	dropped uint64
+}
+
+// NewFIFOWriter returns a writer for the FIFO at path, retrying to open it
+// every retry (1 second if zero) while no reader is attached.
+func NewFIFOWriter(path string, retry time.Duration) *FIFOWriter {
+	if retry <= 0 {
+		retry = time.Second
+	}
+	return &FIFOWriter{path: path, retry: retry}
+}
+
+// Write writes p to the pipe, or drops it if the pipe has no reader or is
+// full. It only fails if path cannot be opened as a FIFO at all.
+func (w *FIFOWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.open {
+		if time.Now().Before(w.next) {
+			w.dropped++
+			return len(p), nil
+		}
+		fd, ok, err := openFIFO(w.path)
+		if err != nil {
+			w.next = time.Now().Add(w.retry)
+			return 0, err
+		}
+		if !ok {
+			w.next = time.Now().Add(w.retry)
+			w.dropped++
+			return len(p), nil
+		}
+		w.fd, w.open = fd, true
+	}
+	if len(w.rest) > 0 {
+		if !w.write(w.rest) || len(w.rest) > 0 {
+			w.dropped++
+			return len(p), nil
+		}
+	}
+	if !w.write(p) {
+		w.dropped++
+	}
+	return len(p), nil
+}
+
+// write writes p, keeping what does not fit in the pipe in w.rest if some
+// of it did. It reports whether p was written at least in part. The caller
+// holds w.mu.
+func (w *FIFOWriter) write(p []byte) bool {
+	n, err := writeFIFO(w.fd, p)
+	switch err {
+	case nil:
+		w.rest = w.rest[:0]
+	case errFIFOFull:
+		if n == 0 {
+			return false
+		}
+		w.rest = append(w.rest[:0], p[n:]...)
+	default:
+		// The reader went away.
+		closeFIFO(w.fd)
+		w.open = false
+		w.rest = w.rest[:0]
+		return false
+	}
+	return true
+}
+
+// Sync does nothing: pipes are not buffered on the writing side.
+func (w *FIFOWriter) Sync() error {
+	return nil
+}
+
+// Close closes the pipe.
+func (w *FIFOWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.open {
+		w.open = false
+		return closeFIFO(w.fd)
+	}
+	return nil
+}
+
+// Dropped returns the number of entries dropped because the pipe had no
+// reader or was full.
+func (w *FIFOWriter) Dropped() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.dropped
+}
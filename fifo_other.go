@@ -0,0 +1,21 @@
+//go:build !linux && !darwin && !freebsd
+// +build !linux,!darwin,!freebsd
+
+package log
+
+import "errors"
+
+var errFIFOFull = errors.New("log: FIFO full")
+
+// openFIFO fails: FIFOs are not supported on this platform.
+func openFIFO(path string) (int, bool, error) {
+	return 0, false, errors.New("log: FIFOs are not supported on this platform")
+}
+
+func writeFIFO(fd int, p []byte) (int, error) {
+	return 0, errFIFOFull
+}
+
+func closeFIFO(fd int) error {
+	return nil
+}
@@ -0,0 +1,58 @@
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package log
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+var errFIFOFull = errors.New("log: FIFO full")
+
+// openFIFO opens path for writing without blocking. ok is false if the
+// FIFO has no reader yet. The descriptor is used directly rather than
+// through os.File, whose poller would park the writer on a full pipe.
+func openFIFO(path string) (fd int, ok bool, err error) {
+	var st unix.Stat_t
+	if err := unix.Stat(path, &st); err != nil {
+		return 0, false, err
+	}
+	if st.Mode&unix.S_IFMT != unix.S_IFIFO {
+		return 0, false, fmt.Errorf("log: %s is not a FIFO", path)
+	}
+	fd, err = unix.Open(path, unix.O_WRONLY|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err == unix.ENXIO {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return fd, true, nil
+}
+
+// writeFIFO writes p to fd and returns the number of bytes written, less
+// than len(p) with errFIFOFull if the pipe filled up.
+func writeFIFO(fd int, p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		n, err := unix.Write(fd, p[written:])
+		if err == unix.EINTR {
+			continue
+		}
+		if err == unix.EAGAIN {
+			return written, errFIFOFull
+		}
+		if err != nil {
+			return written, err
+		}
+		written += n
+	}
+	return written, nil
+}
+
+func closeFIFO(fd int) error {
+	return unix.Close(fd)
+}
@@ -0,0 +1,70 @@
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package log
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// drain reads what is in the pipe fd.
+func drain(t *testing.T, fd int) []byte {
+	t.Helper()
+	var out []byte
+	buf := make([]byte, 64<<10)
+	for {
+		n, err := unix.Read(fd, buf)
+		if err == unix.EAGAIN || n == 0 {
+			return out
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, buf[:n]...)
+	}
+}
+
+func TestFIFOFinishesPartialEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.pipe")
+	if err := unix.Mkfifo(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := unix.Open(path, unix.O_RDONLY|unix.O_NONBLOCK, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Close(r)
+	w := NewFIFOWriter(path, 0)
+	defer w.Close()
+
+	big := strings.Repeat("a", 1<<20) + "\n"
+	for _, entry := range []string{big, "dropped\n"} {
+		if _, err := w.Write([]byte(entry)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var got []byte
+	for i := 0; len(got) < len(big) && i < 1000; i++ {
+		got = append(got, drain(t, r)...)
+		w.Write([]byte("next\n"))
+	}
+	got = append(got, drain(t, r)...)
+
+	lines := bytes.SplitAfter(got, []byte("\n"))
+	if len(lines) < 2 || string(lines[0]) != big {
+		t.Fatalf("first line of %d bytes, want the %d of the entry", len(lines[0]), len(big))
+	}
+	for _, l := range lines[1:] {
+		if len(l) > 0 && string(l) != "next\n" {
+			t.Errorf("line %q after the entry", l)
+		}
+	}
+	if w.Dropped() == 0 {
+		t.Error("no entry dropped while the entry was pending")
+	}
+}
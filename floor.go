@@ -0,0 +1,34 @@
+package log
+
+// minLevel is the lowest level that can be logged, set when building, e.g.
+// for release firmware:
+//
+//	go build -ldflags "-X github.com/ndmsystems/golog.minLevel=info"
+//
+// Entries below it are never logged, whatever SetLevel says. The
+// linker can only set a variable, so the calls are not removed: the
+// Debug* and Info* functions return right away, before formatting their
+// arguments, at the cost of a comparison.
+var minLevel string
+
+// buildFloor is minLevel parsed. An invalid value panics at start-up, so
+// that a broken release build does not go unnoticed.
+var buildFloor = parseBuildFloor()
+
+func parseBuildFloor() Level {
+	if minLevel == "" {
+		return TraceLevel
+	}
+	return MustParseLevel(minLevel)
+}
+
+// BuildFloor returns the lowest level that can be logged, as set when
+// building; TraceLevel unless set.
+func BuildFloor() Level {
+	return buildFloor
+}
+
+// stripped reports whether lvl is below the build floor.
+func stripped(lvl Level) bool {
+	return lvl < buildFloor
+}
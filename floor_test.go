@@ -0,0 +1,30 @@
+package log
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// TestStrippedLevelsDoNoWork checks that calls below the build floor return
+// before doing any work.
+func TestStrippedLevelsDoNoWork(t *testing.T) {
+	initTest(t, true, WithEncoding("json"), WithSink(discard{}))
+	floor := buildFloor
+	buildFloor = WarningLevel
+	defer func() { buildFloor = floor }()
+
+	fields := []Field{zap.String("user", "admin")}
+	timer := Start("reload")
+	tests := map[string]func(){
+		"Infot":      func() { Infot("user {user}", fields...) },
+		"Debugt":     func() { Debugt("user {user}", fields...) },
+		"Infof":      func() { Infof("user %s", "admin") },
+		"Timer.Done": func() { timer.Done(nil) },
+	}
+	for name, fn := range tests {
+		if n := testing.AllocsPerRun(10, fn); n != 0 {
+			t.Errorf("%s allocates %v times below the build floor", name, n)
+		}
+	}
+}
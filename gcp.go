@@ -0,0 +1,84 @@
+package log
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+func init() {
+	registerEncoder("gcp", func(zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		return NewGCPEncoder(), nil
+	})
+}
+
+const (
+	gcpSourceLocationKey = "logging.googleapis.com/sourceLocation"
+	gcpTraceKey          = "logging.googleapis.com/trace"
+	gcpSpanKey           = "logging.googleapis.com/spanId"
+)
+
+type gcpEncoder struct {
+	zapcore.Encoder
+}
+
+// NewGCPEncoder returns a JSON encoder producing the structured payload
+// Google Cloud Logging parses natively from stdout on GKE and Cloud Run.
+func NewGCPEncoder() zapcore.Encoder {
+	cfg := NewEncoderConfig()
+	cfg.TimeKey = "time"
+	cfg.LevelKey = "severity"
+	cfg.CallerKey = zapcore.OmitKey
+	cfg.MessageKey = "message"
+	cfg.StacktraceKey = "stack_trace"
+	cfg.EncodeLevel = gcpLevelEncoder
+	cfg.EncodeTime = zapcore.RFC3339NanoTimeEncoder
+	return &gcpEncoder{Encoder: zapcore.NewJSONEncoder(cfg)}
+}
+
+func (e *gcpEncoder) Clone() zapcore.Encoder {
+	return &gcpEncoder{Encoder: e.Encoder.Clone()}
+}
+
+// EncodeEntry reports the caller as sourceLocation.
+func (e *gcpEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	if ent.Caller.Defined {
+		caller := ent.Caller
+		fields = append(fields[:len(fields):len(fields)], zap.Object(gcpSourceLocationKey,
+			zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+				enc.AddString("file", caller.File)
+				enc.AddInt("line", caller.Line)
+				enc.AddString("function", caller.Function)
+				return nil
+			})))
+	}
+	return e.Encoder.EncodeEntry(ent, fields)
+}
+
+func gcpLevelEncoder(lvl zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	switch lvl {
+	case zapcore.DebugLevel:
+		enc.AppendString("DEBUG")
+	case zapcore.InfoLevel:
+		enc.AppendString("INFO")
+	case zapcore.WarnLevel:
+		enc.AppendString("WARNING")
+	case zapcore.ErrorLevel:
+		enc.AppendString("ERROR")
+	case zapcore.DPanicLevel, zapcore.PanicLevel:
+		enc.AppendString("CRITICAL")
+	case zapcore.FatalLevel:
+		enc.AppendString("ALERT")
+	default:
+		enc.AppendString("DEFAULT")
+	}
+}
+
+// GCPTrace returns the fields linking an entry to a Cloud Trace span.
+func GCPTrace(projectID, traceID, spanID string) []Field {
+	fields := []Field{zap.String(gcpTraceKey, "projects/"+projectID+"/traces/"+traceID)}
+	if spanID != "" {
+		fields = append(fields, zap.String(gcpSpanKey, spanID))
+	}
+	return fields
+}
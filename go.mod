@@ -2,7 +2,11 @@ module github.com/ndmsystems/golog
 
 go 1.17
 
-require go.uber.org/zap v1.23.0
+require (
+	go.uber.org/zap v1.23.0
+	golang.org/x/sys v0.7.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	go.uber.org/atomic v1.7.0 // indirect
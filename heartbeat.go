@@ -0,0 +1,72 @@
+package log
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// processStart approximates the start of the process for the uptime of
+// heartbeats.
+var processStart = time.Now()
+
+// WithHeartbeat logs an INFO "heartbeat" entry every interval, with the
+// uptime of the process ("uptime") and the number of entries logged
+// ("entries") and lost ("dropped", see Stats) since the previous one, so
+// that log-based monitoring can tell a dead daemon from a quiet one. The
+// entries go through the package level, which must let INFO through.
+func WithHeartbeat(interval time.Duration) Option {
+	return func(o *options) {
+		if interval > 0 {
+			o.heartbeat = &heartbeat{interval: interval, stop: make(chan struct{})}
+		}
+	}
+}
+
+type heartbeat struct {
+	interval time.Duration
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// start logs a heartbeat every interval until close is called.
+func (h *heartbeat) start() {
+	entries, dropped := heartbeatCounts()
+	go h.run(entries, dropped)
+}
+
+func (h *heartbeat) run(entries, dropped uint64) {
+	t := time.NewTicker(h.interval)
+	defer t.Stop()
+	for {
+		select {
+		case now := <-t.C:
+			e, d := heartbeatCounts()
+			std().base.WithOptions(zap.WithCaller(false)).Info("heartbeat",
+				zap.Duration("uptime", now.Sub(processStart).Truncate(time.Second)),
+				zap.Uint64("entries", e-entries),
+				zap.Uint64("dropped", d-dropped))
+			// The heartbeat itself is not counted in the next one.
+			entries, dropped = e, d
+			if after, _ := heartbeatCounts(); after > e {
+				entries++
+			}
+		case <-h.stop:
+			return
+		}
+	}
+}
+
+func heartbeatCounts() (entries, dropped uint64) {
+	s := ReadStats()
+	for _, n := range s.Entries {
+		entries += n
+	}
+	return entries, s.Dropped
+}
+
+func (h *heartbeat) close() {
+	h.once.Do(func() { close(h.stop) })
+}
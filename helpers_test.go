@@ -0,0 +1,57 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// syncBuffer is an output collecting the entries written to it.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuffer) Sync() error {
+	return nil
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+// lines returns the entries written to b.
+func (b *syncBuffer) lines() []string {
+	s := strings.TrimRight(b.String(), "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// initTest builds the package logger from opts for the test and restores
+// a default one when it ends.
+func initTest(t testing.TB, debug bool, opts ...Option) {
+	t.Helper()
+	if err := InitE(debug, opts...); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		Init(false, WithEncoding("json"), WithSink(discard{}))
+	})
+}
+
+// discard is an output dropping everything.
+type discard struct{}
+
+func (discard) Write(p []byte) (int, error) { return len(p), nil }
+func (discard) Sync() error                 { return nil }
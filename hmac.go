@@ -0,0 +1,194 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const hmacKey = "hmac"
+
+// HMACWriter is a zapcore.WriteSyncer that chains an HMAC-SHA256 over the
+// lines it writes: each line is signed together with the MAC of the
+// previous one, so removing, reordering or editing any line but the last
+// ones breaks the chain. Removing lines from the end cannot be detected
+// from the file alone: keep the last MAC and the line count reported by
+// VerifyHMACChain elsewhere to check them. JSON lines get the MAC as a
+// trailing "hmac" field, other lines as a trailing " hmac=<hex>".
+type HMACWriter struct {
+	mu   sync.Mutex
+	out  zapcore.WriteSyncer
+	key  []byte
+	prev []byte
+}
+
+// NewHMACWriter returns a writer signing lines written to out. prev is the
+// MAC of the last line already in out, or nil to start a new chain.
+func NewHMACWriter(out zapcore.WriteSyncer, key []byte, prev []byte) *HMACWriter {
+	return &HMACWriter{out: out, key: key, prev: prev}
+}
+
+// OpenHMACFile opens path for appending and returns a writer continuing the
+// chain of the lines already in it.
+func OpenHMACFile(path string, key []byte) (*HMACWriter, error) {
+	prev, err := lastHMAC(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return NewHMACWriter(f, key, prev), nil
+}
+
+// Write signs and writes p as a single line. Newlines within p, such as
+// before the stack traces of the console encoder, are written as \n.
+func (w *HMACWriter) Write(p []byte) (int, error) {
+	line := bytes.TrimRight(p, "\n")
+	if bytes.IndexByte(line, '\n') >= 0 {
+		line = bytes.ReplaceAll(line, []byte("\n"), []byte(`\n`))
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	mac := chainHMAC(w.key, w.prev, line)
+	if _, err := w.out.Write(appendHMAC(line, mac)); err != nil {
+		return 0, err
+	}
+	w.prev = mac
+	return len(p), nil
+}
+
+// Sync flushes the underlying writer.
+func (w *HMACWriter) Sync() error {
+	return w.out.Sync()
+}
+
+// Close closes the underlying writer if it is an io.Closer.
+func (w *HMACWriter) Close() error {
+	if c, ok := w.out.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// VerifyHMACChain checks the chain of lines read from r. It returns the
+// number of lines and the MAC of the last one if the whole chain is
+// intact, to be compared with those expected since lines removed from the
+// end leave the chain intact. Otherwise it returns the 1-based number of
+// the first line that fails verification and the MAC of the line before.
+func VerifyHMACChain(r io.Reader, key []byte) (lines int, last []byte, err error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64<<10), 16<<20)
+	var prev []byte
+	n := 0
+	for sc.Scan() {
+		n++
+		line, mac, ok := splitHMAC(sc.Bytes())
+		if !ok {
+			return n, prev, fmt.Errorf("log: line %d is not signed", n)
+		}
+		if !hmac.Equal(mac, chainHMAC(key, prev, line)) {
+			return n, prev, fmt.Errorf("log: HMAC mismatch at line %d", n)
+		}
+		prev = mac
+	}
+	return n, prev, sc.Err()
+}
+
+func chainHMAC(key, prev, line []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(prev)
+	h.Write(line)
+	return h.Sum(nil)
+}
+
+func appendHMAC(line, mac []byte) []byte {
+	sum := hex.EncodeToString(mac)
+	out := make([]byte, 0, len(line)+len(sum)+16)
+	if len(line) > 1 && line[0] == '{' && line[len(line)-1] == '}' {
+		out = append(out, line[:len(line)-1]...)
+		if len(line) > 2 {
+			out = append(out, ',')
+		}
+		out = append(out, `"`+hmacKey+`":"`+sum+`"}`...)
+	} else {
+		out = append(out, line...)
+		out = append(out, " "+hmacKey+"="+sum...)
+	}
+	return append(out, '\n')
+}
+
+// splitHMAC recovers the original line and its MAC from a signed line.
+func splitHMAC(signed []byte) (line, mac []byte, ok bool) {
+	const hexLen = 2 * sha256.Size
+	jsonPrefix := []byte(`"` + hmacKey + `":"`)
+	textPrefix := []byte(" " + hmacKey + "=")
+
+	var sum []byte
+	switch {
+	case len(signed) >= len(jsonPrefix)+hexLen+2 && signed[len(signed)-1] == '}':
+		start := len(signed) - len(jsonPrefix) - hexLen - 2
+		if !bytes.Equal(signed[start:start+len(jsonPrefix)], jsonPrefix) {
+			return nil, nil, false
+		}
+		sum = signed[start+len(jsonPrefix) : len(signed)-2]
+		line = append([]byte(nil), signed[:start]...)
+		if len(line) > 1 && line[len(line)-1] == ',' {
+			line = line[:len(line)-1]
+		}
+		line = append(line, '}')
+	case len(signed) >= len(textPrefix)+hexLen:
+		start := len(signed) - len(textPrefix) - hexLen
+		if !bytes.Equal(signed[start:start+len(textPrefix)], textPrefix) {
+			return nil, nil, false
+		}
+		sum = signed[start+len(textPrefix):]
+		line = signed[:start]
+	default:
+		return nil, nil, false
+	}
+	mac, err := hex.DecodeString(string(sum))
+	return line, mac, err == nil
+}
+
+// lastHMAC returns the MAC of the last line of path, or nil if the file
+// does not exist or is empty.
+func lastHMAC(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var last []byte
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64<<10), 16<<20)
+	for sc.Scan() {
+		last = append(last[:0], sc.Bytes()...)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(last) == 0 {
+		return nil, nil
+	}
+	_, mac, ok := splitHMAC(last)
+	if !ok {
+		return nil, fmt.Errorf("log: last line of %s is not signed", path)
+	}
+	return mac, nil
+}
@@ -0,0 +1,94 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSplitHMAC(t *testing.T) {
+	mac := chainHMAC([]byte("key"), nil, []byte("x"))
+	for _, line := range []string{"", "text", "a b=c", "{}", `{"a":1}`, `{"msg":"x,"}`} {
+		got, gotMAC, ok := splitHMAC(bytes.TrimSuffix(appendHMAC([]byte(line), mac), []byte("\n")))
+		if !ok || string(got) != line || !bytes.Equal(gotMAC, mac) {
+			t.Errorf("%q: split into %q, %x, %v", line, got, gotMAC, ok)
+		}
+	}
+
+	sum := strings.Repeat("ab", 32)
+	for _, signed := range []string{
+		"",
+		"text",
+		"text hmac=" + sum[:62],
+		"text hmac=" + strings.Repeat("zz", 32),
+		"text mac=" + sum + "0",
+		`{"a":1,"mac":"` + sum + `"}`,
+		`{"a":1,"hmac":"` + sum + `"`,
+	} {
+		if line, _, ok := splitHMAC([]byte(signed)); ok {
+			t.Errorf("%q: split into %q", signed, line)
+		}
+	}
+}
+
+// signedLog returns the lines written through an HMACWriter and the MAC of
+// the last one.
+func signedLog(t *testing.T, key []byte, lines ...string) ([]string, []byte) {
+	t.Helper()
+	var out syncBuffer
+	w := NewHMACWriter(&out, key, nil)
+	for _, l := range lines {
+		if _, err := w.Write([]byte(l + "\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return out.lines(), w.prev
+}
+
+func TestVerifyHMACChain(t *testing.T) {
+	key := []byte("key")
+	signed, last := signedLog(t, key, `{"msg":"a"}`, "b", `{"msg":"c"}`, "d")
+	tests := []struct {
+		name  string
+		lines []string
+		n     int // lines, or the first failing one
+		ok    bool
+	}{
+		{"intact", signed, 4, true},
+		{"truncated", signed[:2], 2, true},
+		{"empty", nil, 0, true},
+		{"removed", []string{signed[0], signed[2], signed[3]}, 2, false},
+		{"reordered", []string{signed[1], signed[0], signed[2], signed[3]}, 1, false},
+		{"edited", []string{signed[0], strings.Replace(signed[1], "b", "B", 1), signed[2], signed[3]}, 2, false},
+		{"unsigned", []string{signed[0], "b", signed[2]}, 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var in string
+			for _, l := range tt.lines {
+				in += l + "\n"
+			}
+			n, mac, err := VerifyHMACChain(strings.NewReader(in), key)
+			if n != tt.n || (err == nil) != tt.ok {
+				t.Errorf("got %d, %v, want %d, ok %v", n, err, tt.n, tt.ok)
+			}
+			if tt.name == "intact" && !bytes.Equal(mac, last) {
+				t.Errorf("last MAC %x, want %x", mac, last)
+			}
+		})
+	}
+	if _, _, err := VerifyHMACChain(strings.NewReader(strings.Join(signed, "\n")), []byte("other")); err == nil {
+		t.Error("chain verified with another key")
+	}
+}
+
+func TestHMACWriterEscapesNewlines(t *testing.T) {
+	key := []byte("key")
+	signed, _ := signedLog(t, key, "panic\ngoroutine 1", "next")
+	if len(signed) != 2 || !strings.HasPrefix(signed[0], `panic\ngoroutine 1 hmac=`) {
+		t.Fatalf("signed lines %q", signed)
+	}
+	if n, _, err := VerifyHMACChain(strings.NewReader(strings.Join(signed, "\n")), key); err != nil || n != 2 {
+		t.Errorf("got %d, %v, want 2 lines", n, err)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build darwin || freebsd
+// +build darwin freebsd
+
+package log
+
+import "golang.org/x/sys/unix"
+
+// isTerminal reports whether fd refers to a terminal.
+func isTerminal(fd uintptr) bool {
+	_, err := unix.IoctlGetTermios(int(fd), unix.TIOCGETA)
+	return err == nil
+}
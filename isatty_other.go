@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !freebsd && !windows
+// +build !linux,!darwin,!freebsd,!windows
+
+package log
+
+// isTerminal always reports false on platforms without a terminal check.
+func isTerminal(fd uintptr) bool {
+	return false
+}
@@ -0,0 +1,133 @@
+package log
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// kmsgMaxRecord is the longest record the kernel accepts from userspace;
+// longer entries are truncated.
+const kmsgMaxRecord = 976
+
+// KmsgConfig describes the /dev/kmsg core.
+type KmsgConfig struct {
+	// Path is the kernel log device. Defaults to /dev/kmsg.
+	Path string
+	// Tag prefixes every record, syslog style. Defaults to the program
+	// name.
+	Tag string
+	// Facility is the syslog facility of the records. Defaults to 1
+	// (user), which tells them apart from the kernel's own messages.
+	Facility int
+	// Level is the minimum level written to the kernel log.
+	Level Level
+}
+
+type kmsgCore struct {
+	zapcore.LevelEnabler
+	enc      zapcore.Encoder
+	prefix   string
+	facility int
+
+	mu *sync.Mutex
+	f  *os.File
+}
+
+// NewKmsgCore returns a core writing entries to the kernel ring buffer
+// through /dev/kmsg, one record per entry with the level as syslog
+// priority. The entries then show up in dmesg, and in the system log once
+// it is up, which is the only way to see them during early boot on
+// embedded devices. The kernel rate-limits userspace writers unless booted
+// with printk.devkmsg=on, so keep Level at warning or above otherwise.
+func NewKmsgCore(cfg KmsgConfig) (zapcore.Core, error) {
+	if cfg.Path == "" {
+		cfg.Path = "/dev/kmsg"
+	}
+	if cfg.Tag == "" {
+		cfg.Tag = filepath.Base(os.Args[0])
+	}
+	if cfg.Facility <= 0 {
+		cfg.Facility = 1
+	}
+	f, err := os.OpenFile(cfg.Path, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	encCfg := NewEncoderConfig()
+	encCfg.TimeKey = zapcore.OmitKey
+	encCfg.LevelKey = zapcore.OmitKey
+	encCfg.LineEnding = ""
+	return &kmsgCore{
+		LevelEnabler: zapcore.Level(cfg.Level),
+		enc:          zapcore.NewConsoleEncoder(encCfg),
+		prefix:       cfg.Tag + "[" + strconv.Itoa(os.Getpid()) + "]: ",
+		facility:     cfg.Facility,
+		mu:           new(sync.Mutex),
+		f:            f,
+	}, nil
+}
+
+func (c *kmsgCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.enc = c.enc.Clone()
+	for i := range fields {
+		fields[i].AddTo(clone.enc)
+	}
+	return &clone
+}
+
+func (c *kmsgCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *kmsgCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+
+	var rec bytes.Buffer
+	rec.WriteByte('<')
+	rec.WriteString(strconv.Itoa(c.facility<<3 | kmsgSeverity(ent.Level)))
+	rec.WriteByte('>')
+	rec.WriteString(c.prefix)
+	rec.Write(bytes.TrimSpace(buf.Bytes()))
+	if rec.Len() > kmsgMaxRecord {
+		rec.Truncate(kmsgMaxRecord)
+	}
+	rec.WriteByte('\n')
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err = c.f.Write(rec.Bytes())
+	return err
+}
+
+func (c *kmsgCore) Sync() error {
+	return nil
+}
+
+// kmsgSeverity maps a level onto the syslog severities.
+func kmsgSeverity(lvl zapcore.Level) int {
+	switch {
+	case lvl >= zapcore.DPanicLevel:
+		return 2 // crit
+	case lvl == zapcore.ErrorLevel:
+		return 3 // err
+	case lvl == zapcore.WarnLevel:
+		return 4 // warning
+	case lvl == zapcore.InfoLevel:
+		return 6 // info
+	default:
+		return 7 // debug
+	}
+}
@@ -0,0 +1,83 @@
+package log
+
+import (
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// serviceAccountNamespace holds the namespace of the pod in every
+// container with a mounted service account.
+const serviceAccountNamespace = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// KubernetesMetadata is a MetadataProvider attributing entries to the pod
+// they come from, with the "k8s.namespace", "k8s.pod", "k8s.node" and
+// "k8s.container" fields, so that logs do not depend on the collector for
+// it. Outside a pod it provides nothing.
+//
+// The values are read from environment variables, which the pod spec sets
+// from the downward API:
+//
+//	env:
+//	- name: POD_NAME
+//	  valueFrom: {fieldRef: {fieldPath: metadata.name}}
+//	- name: POD_NAMESPACE
+//	  valueFrom: {fieldRef: {fieldPath: metadata.namespace}}
+//	- name: NODE_NAME
+//	  valueFrom: {fieldRef: {fieldPath: spec.nodeName}}
+//	- name: CONTAINER_NAME
+//	  value: daemon
+//
+// Without them the pod name falls back to the host name and the namespace
+// to that of the service account.
+type KubernetesMetadata struct {
+	// PodEnv, NamespaceEnv, NodeEnv and ContainerEnv name the variables,
+	// POD_NAME, POD_NAMESPACE, NODE_NAME and CONTAINER_NAME by default.
+	PodEnv       string
+	NamespaceEnv string
+	NodeEnv      string
+	ContainerEnv string
+}
+
+// WithKubernetesMetadata adds the fields of KubernetesMetadata with the
+// default variable names to every entry.
+func WithKubernetesMetadata() Option {
+	return WithMetadata(KubernetesMetadata{}, time.Hour)
+}
+
+// Metadata implements MetadataProvider.
+func (k KubernetesMetadata) Metadata() ([]Field, error) {
+	if os.Getenv("KUBERNETES_SERVICE_HOST") == "" {
+		return nil, nil
+	}
+	pod := envValue(k.PodEnv, "POD_NAME")
+	if pod == "" {
+		pod, _ = os.Hostname()
+	}
+	ns := envValue(k.NamespaceEnv, "POD_NAMESPACE")
+	if ns == "" {
+		ns = readTrimmed(serviceAccountNamespace)
+	}
+
+	var fields []Field
+	add := func(key, val string) {
+		if val != "" {
+			fields = append(fields, zap.String(key, val))
+		}
+	}
+	add("k8s.namespace", ns)
+	add("k8s.pod", pod)
+	add("k8s.node", envValue(k.NodeEnv, "NODE_NAME"))
+	add("k8s.container", envValue(k.ContainerEnv, "CONTAINER_NAME"))
+	return fields, nil
+}
+
+// envValue returns the value of the variable name, or of def if name is
+// empty.
+func envValue(name, def string) string {
+	if name == "" {
+		name = def
+	}
+	return os.Getenv(name)
+}
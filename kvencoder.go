@@ -0,0 +1,131 @@
+package log
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var bufferPool = buffer.NewPool()
+
+// kv is a single encoded field.
+type kv struct {
+	key string
+	val interface{}
+}
+
+// kvEncoder is a zapcore.ObjectEncoder that keeps fields as an ordered list
+// of key/value pairs. Namespaces are flattened into dotted keys. It is the
+// base of the line-oriented encoders that are not JSON.
+type kvEncoder struct {
+	fields []kv
+	prefix string
+}
+
+func (e *kvEncoder) clone() kvEncoder {
+	fields := make([]kv, len(e.fields), len(e.fields)+8)
+	copy(fields, e.fields)
+	return kvEncoder{fields: fields, prefix: e.prefix}
+}
+
+// addFields returns the accumulated fields followed by the given ones,
+// leaving the encoder untouched.
+func (e *kvEncoder) addFields(fields []zapcore.Field) []kv {
+	if len(fields) == 0 {
+		return e.fields
+	}
+	c := e.clone()
+	for i := range fields {
+		fields[i].AddTo(&c)
+	}
+	return c.fields
+}
+
+func (e *kvEncoder) add(k string, v interface{}) {
+	e.fields = append(e.fields, kv{key: e.prefix + k, val: v})
+}
+
+func (e *kvEncoder) AddArray(k string, v zapcore.ArrayMarshaler) error {
+	m := zapcore.NewMapObjectEncoder()
+	err := m.AddArray(k, v)
+	e.add(k, m.Fields[k])
+	return err
+}
+
+func (e *kvEncoder) AddObject(k string, v zapcore.ObjectMarshaler) error {
+	m := zapcore.NewMapObjectEncoder()
+	err := v.MarshalLogObject(m)
+	e.add(k, m.Fields)
+	return err
+}
+
+func (e *kvEncoder) AddBinary(k string, v []byte)          { e.add(k, v) }
+func (e *kvEncoder) AddByteString(k string, v []byte)      { e.add(k, string(v)) }
+func (e *kvEncoder) AddBool(k string, v bool)              { e.add(k, v) }
+func (e *kvEncoder) AddComplex128(k string, v complex128)  { e.add(k, v) }
+func (e *kvEncoder) AddComplex64(k string, v complex64)    { e.add(k, v) }
+func (e *kvEncoder) AddDuration(k string, v time.Duration) { e.add(k, v) }
+func (e *kvEncoder) AddFloat64(k string, v float64)        { e.add(k, v) }
+func (e *kvEncoder) AddFloat32(k string, v float32)        { e.add(k, v) }
+func (e *kvEncoder) AddInt(k string, v int)                { e.add(k, v) }
+func (e *kvEncoder) AddInt64(k string, v int64)            { e.add(k, v) }
+func (e *kvEncoder) AddInt32(k string, v int32)            { e.add(k, v) }
+func (e *kvEncoder) AddInt16(k string, v int16)            { e.add(k, v) }
+func (e *kvEncoder) AddInt8(k string, v int8)              { e.add(k, v) }
+func (e *kvEncoder) AddString(k, v string)                 { e.add(k, v) }
+func (e *kvEncoder) AddTime(k string, v time.Time)         { e.add(k, v) }
+func (e *kvEncoder) AddUint(k string, v uint)              { e.add(k, v) }
+func (e *kvEncoder) AddUint64(k string, v uint64)          { e.add(k, v) }
+func (e *kvEncoder) AddUint32(k string, v uint32)          { e.add(k, v) }
+func (e *kvEncoder) AddUint16(k string, v uint16)          { e.add(k, v) }
+func (e *kvEncoder) AddUint8(k string, v uint8)            { e.add(k, v) }
+func (e *kvEncoder) AddUintptr(k string, v uintptr)        { e.add(k, v) }
+
+func (e *kvEncoder) AddReflected(k string, v interface{}) error {
+	e.add(k, v)
+	return nil
+}
+
+func (e *kvEncoder) OpenNamespace(k string) {
+	e.prefix += k + "."
+}
+
+// formatValue renders a field value as plain text.
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case time.Duration:
+		return v.String()
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32,
+		uint64, uintptr, float32, float64, complex64, complex128:
+		return fmt.Sprint(v)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// lookup returns the value of the last field called key.
+func (e *kvEncoder) lookup(key string) (interface{}, bool) {
+	for i := len(e.fields) - 1; i >= 0; i-- {
+		if e.fields[i].key == key {
+			return e.fields[i].val, true
+		}
+	}
+	return nil, false
+}
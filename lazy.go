@@ -0,0 +1,21 @@
+package log
+
+import "encoding/json"
+
+// LazyValue is a value computed only when the entry carrying it is actually
+// encoded, i.e. after level and sampling checks have passed.
+type LazyValue func() interface{}
+
+// Lazy wraps fn so that it is evaluated only if the entry is written:
+//
+//	log.Debugw("packet received", "dump", log.Lazy(func() interface{} {
+//		return hex.Dump(pkt)
+//	}))
+func Lazy(fn func() interface{}) LazyValue {
+	return LazyValue(fn)
+}
+
+// MarshalJSON evaluates the wrapped function and encodes its result.
+func (v LazyValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v())
+}
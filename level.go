@@ -0,0 +1,96 @@
+package log
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Enabled reports whether entries at the given level would be logged.
+func Enabled(lvl Level) bool {
+	return std().Enabled(lvl)
+}
+
+// IsDebugEnabled reports whether DEBUG entries would be logged. Use it to
+// skip building expensive debug payloads.
+func IsDebugEnabled() bool {
+	return std().Enabled(DebugLevel)
+}
+
+// Enabled reports whether entries at the given level would be logged.
+func (lg *Logger) Enabled(lvl Level) bool {
+	if stripped(lvl) {
+		return false
+	}
+	// The outputs are opened up to the lowest named level and the flight
+	// recorder takes every entry, so the cores cannot tell.
+	if lg.named == nil && lg.atom != (zap.AtomicLevel{}) && lvl < Level(lg.atom.Level()) {
+		return false
+	}
+	return lg.base.Core().Enabled(zapcore.Level(lvl))
+}
+
+// IsDebugEnabled reports whether DEBUG entries would be logged.
+func (lg *Logger) IsDebugEnabled() bool {
+	return lg.Enabled(DebugLevel)
+}
+
+// SetLevel changes the minimum level of the package logger at runtime,
+// without rebuilding it. Loggers derived with With share the level.
+func SetLevel(lvl Level) {
+	std().SetLevel(lvl)
+}
+
+// GetLevel returns the minimum level of the package logger.
+func GetLevel() Level {
+	return std().GetLevel()
+}
+
+// SetLevel changes the minimum level of lg at runtime. On a logger
+// returned by GetLogger it is the same as SetLoggerLevel. It has no effect
+// on a logger that was not built by Init.
+func (lg *Logger) SetLevel(lvl Level) {
+	if lg.named != nil {
+		lg.named.setLevel(int32(lvl))
+		return
+	}
+	if lg.atom != (zap.AtomicLevel{}) {
+		lg.atom.SetLevel(zapcore.Level(lvl))
+		initMu.Lock()
+		updateFloor(lg)
+		initMu.Unlock()
+	}
+}
+
+// GetLevel returns the minimum level of lg.
+func (lg *Logger) GetLevel() Level {
+	if lg.named != nil {
+		return lg.named.effectiveLevel()
+	}
+	if lg.atom == (zap.AtomicLevel{}) {
+		return lg.level
+	}
+	return Level(lg.atom.Level())
+}
+
+// String returns the lower-case name of the level, as accepted by
+// ParseLevel.
+func (lvl Level) String() string {
+	switch lvl {
+	case TraceLevel:
+		return "trace"
+	case OffLevel:
+		return "off"
+	}
+	return zapcore.Level(lvl).String()
+}
+
+// CapitalString returns the upper-case name of the level.
+func (lvl Level) CapitalString() string {
+	switch lvl {
+	case TraceLevel:
+		return "TRACE"
+	case OffLevel:
+		return "OFF"
+	}
+	return zapcore.Level(lvl).CapitalString()
+}
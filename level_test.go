@@ -0,0 +1,37 @@
+package log
+
+import "testing"
+
+func TestEnabledIgnoresNamedLevels(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"plain", nil},
+		{"flight recorder", []Option{WithFlightRecorder(10, discard{})}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			initTest(t, false, append(tt.opts, WithEncoding("json"), WithSink(discard{}))...)
+			SetLoggerLevel("dhcp", DebugLevel)
+			defer ResetLoggerLevel("dhcp")
+
+			if IsDebugEnabled() {
+				t.Error("IsDebugEnabled is true at INFO")
+			}
+			if With("k", 1).IsDebugEnabled() || Tenant("a").IsDebugEnabled() {
+				t.Error("IsDebugEnabled is true on a derived logger at INFO")
+			}
+			if !GetLogger("dhcp").IsDebugEnabled() {
+				t.Error("IsDebugEnabled is false on a named logger at DEBUG")
+			}
+			if !Enabled(InfoLevel) {
+				t.Error("INFO is not enabled")
+			}
+			SetLevel(DebugLevel)
+			if !IsDebugEnabled() {
+				t.Error("IsDebugEnabled is false at DEBUG")
+			}
+		})
+	}
+}
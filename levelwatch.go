@@ -0,0 +1,131 @@
+package log
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// LevelSource delivers level specs, in the format of SetLevels, kept in a
+// remote store.
+type LevelSource interface {
+	// Next returns the current spec on the first call, then blocks until
+	// it changes. A missing key is reported as an empty spec.
+	Next(ctx context.Context) (string, error)
+}
+
+// LevelWatcher applies the level specs of a LevelSource as they change, so
+// that support can raise the verbosity of selected services across a fleet
+// without a deploy:
+//
+//	src, err := log.NewConsulLevelSource(log.ConsulConfig{Key: "ndmd/log-levels"})
+//	...
+//	w := log.WatchLevels(src)
+//	defer w.Close()
+//
+// A spec without a bare level keeps the package level the program had when
+// watching started, so deleting the key restores the initial levels.
+// Invalid specs are ignored and reported by Status.
+type LevelWatcher struct {
+	src    LevelSource
+	base   Level
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu   sync.Mutex
+	spec string
+	err  error
+}
+
+// WatchLevels starts applying the specs of src.
+func WatchLevels(src LevelSource) *LevelWatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &LevelWatcher{
+		src:    src,
+		base:   GetLevel(),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go w.run(ctx)
+	return w
+}
+
+// Status returns the last spec applied and the last error, if any, met
+// reading or applying a spec since.
+func (w *LevelWatcher) Status() (spec string, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.spec, w.err
+}
+
+// Close stops watching. The levels stay as they are.
+func (w *LevelWatcher) Close() {
+	w.cancel()
+	<-w.done
+}
+
+func (w *LevelWatcher) run(ctx context.Context) {
+	defer close(w.done)
+	wait := newBackoff(time.Second, time.Minute)
+	for {
+		spec, err := w.src.Next(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			w.setStatus(w.spec, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait.next()):
+			}
+			continue
+		}
+		wait.reset()
+		w.apply(spec)
+	}
+}
+
+func (w *LevelWatcher) apply(spec string) {
+	root, _, err := parseLevels(spec)
+	if err != nil {
+		w.setStatus(w.spec, err)
+		return
+	}
+	full := spec
+	if root == nil {
+		full = w.base.String() + "," + spec
+	}
+	if err := SetLevels(full); err != nil {
+		w.setStatus(w.spec, err)
+		return
+	}
+	w.setStatus(spec, nil)
+}
+
+func (w *LevelWatcher) setStatus(spec string, err error) {
+	w.mu.Lock()
+	w.spec, w.err = spec, err
+	w.mu.Unlock()
+}
+
+// newWatchClient returns the HTTP client of a level source. It has no
+// timeout, since watches are long-lived; requests are bounded by their
+// context instead.
+func newWatchClient(endpoint string, tlsCfg *TLSConfig) (*http.Client, error) {
+	client := &http.Client{}
+	if tlsCfg != nil {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		c, err := tlsCfg.Build(u.Hostname())
+		if err != nil {
+			return nil, err
+		}
+		client.Transport = &http.Transport{TLSClientConfig: c}
+	}
+	return client, nil
+}
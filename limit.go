@@ -0,0 +1,133 @@
+package log
+
+import (
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// WithSizeLimits caps the length of the message of every entry at
+// maxMessage bytes and the values of its string, byte string, binary,
+// fmt.Stringer and error fields at maxField bytes, so that an accidental
+// multi-megabyte dump cannot overwhelm the outputs. A longer value is cut
+// and ends with "...(truncated, N bytes)", N being its full length, except
+// binary values which are only cut, and the entry gets a truncated=true
+// field. Zero or a negative value leaves the message or the fields
+// unlimited.
+func WithSizeLimits(maxMessage, maxField int) Option {
+	return WrapCore(func(c zapcore.Core) zapcore.Core {
+		return &limitCore{Core: c, maxMessage: maxMessage, maxField: maxField}
+	})
+}
+
+// truncate returns s cut to at most max bytes, on a rune boundary,
+// followed by the truncation marker, and whether it was cut.
+func truncate(s string, max int) (string, bool) {
+	if max <= 0 || len(s) <= max {
+		return s, false
+	}
+	return cutRunes(s, max) + truncationMarker(len(s)), true
+}
+
+// cutRunes returns s cut to at most max bytes, on a rune boundary.
+func cutRunes(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut]
+}
+
+// limitCore truncates the oversized values of the entries written to the
+// core it wraps.
+type limitCore struct {
+	zapcore.Core
+	maxMessage int
+	maxField   int
+	// ctxCut is set if a context field was truncated.
+	ctxCut bool
+}
+
+func (c *limitCore) With(fields []zapcore.Field) zapcore.Core {
+	limited, cut := c.limitFields(fields)
+	return &limitCore{c.Core.With(limited), c.maxMessage, c.maxField, c.ctxCut || cut}
+}
+
+func (c *limitCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *limitCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	var cut bool
+	ent.Message, cut = truncate(ent.Message, c.maxMessage)
+	fields, fieldCut := c.limitFields(fields)
+	if cut || fieldCut || c.ctxCut {
+		fields = append(fields[:len(fields):len(fields)], zap.Bool("truncated", true))
+	}
+	if ce := c.Core.Check(ent, nil); ce != nil {
+		ce.Write(fields...)
+	}
+	return nil
+}
+
+// limitFields returns fields with the oversized values truncated, or
+// fields itself if there are none, and whether any was.
+func (c *limitCore) limitFields(fields []zapcore.Field) ([]zapcore.Field, bool) {
+	if c.maxField <= 0 {
+		return fields, false
+	}
+	var out []zapcore.Field
+	for i, f := range fields {
+		var limited zapcore.Field
+		switch f.Type {
+		case zapcore.StringType:
+			s, cut := truncate(f.String, c.maxField)
+			if !cut {
+				continue
+			}
+			limited = zap.String(f.Key, s)
+		case zapcore.ByteStringType:
+			b, _ := f.Interface.([]byte)
+			s, cut := truncate(string(b), c.maxField)
+			if !cut {
+				continue
+			}
+			limited = zap.ByteString(f.Key, []byte(s))
+		case zapcore.BinaryType:
+			b, _ := f.Interface.([]byte)
+			if len(b) <= c.maxField {
+				continue
+			}
+			limited = zap.Binary(f.Key, b[:c.maxField])
+		case zapcore.StringerType, zapcore.ErrorType:
+			var s string
+			if f.Type == zapcore.StringerType {
+				s = stringerValue(f)
+			} else if err, ok := f.Interface.(error); ok {
+				s = err.Error()
+			}
+			s, cut := truncate(s, c.maxField)
+			if !cut {
+				continue
+			}
+			limited = zap.String(f.Key, s)
+		default:
+			continue
+		}
+		if out == nil {
+			out = append([]zapcore.Field(nil), fields...)
+		}
+		out[i] = limited
+	}
+	if out == nil {
+		return fields, false
+	}
+	return out, true
+}
@@ -0,0 +1,25 @@
+package log
+
+import "testing"
+
+func TestCutRunes(t *testing.T) {
+	tests := []struct {
+		s    string
+		max  int
+		want string
+	}{
+		{"abc", 5, "abc"},
+		{"abc", 3, "abc"},
+		{"abc", 2, "ab"},
+		{"aé", 2, "a"},
+		{"aé", 3, "aé"},
+		{"€€", 4, "€"},
+		{"€", 2, ""},
+		{"abc", 0, ""},
+	}
+	for _, tt := range tests {
+		if got := cutRunes(tt.s, tt.max); got != tt.want {
+			t.Errorf("cutRunes(%q, %d) = %q, want %q", tt.s, tt.max, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package log
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// liveBuffer is the capacity of the channels of Subscribe.
+const liveBuffer = 256
+
+// livePing is the interval of the keep-alive comments sent to idle
+// clients, so that proxies do not close the connection.
+const livePing = 15 * time.Second
+
+// LiveLog keeps the last entries in memory, encoded as JSON by default,
+// and streams them to HTTP clients as Server-Sent Events as they are logged, so that
+// a device web UI can show the log without shell access:
+//
+//	live := log.NewLiveLog(500)
+//	log.Init(false, log.WithCore(live.Core()))
+//	http.Handle("/log/live", live.Handler())
+type LiveLog struct {
+	enc  zapcore.Encoder
+	ring *ringBuffer
+
+	// mu orders the entries handed to the clients, and keeps Subscribe
+	// from missing any.
+	mu      sync.Mutex
+	clients map[chan LiveEntry]struct{}
+}
+
+// LiveEntry is an entry kept by a LiveLog.
+type LiveEntry struct {
+	// Seq numbers the entries of the LiveLog from 1.
+	Seq   uint64
+	Level Level
+	// Name is the name of the logger, see GetLogger.
+	Name string
+	// Data is the encoded entry.
+	Data []byte
+}
+
+// NewLiveLog returns a LiveLog keeping the last size entries.
+func NewLiveLog(size int) *LiveLog {
+	return NewLiveLogEncoder(size, zapcore.NewJSONEncoder(NewEncoderConfig()))
+}
+
+// NewLiveLogEncoder returns a LiveLog keeping the last size entries
+// encoded with enc, e.g. NewProtobufEncoder() for a gRPC service. Handler
+// needs a text encoding.
+func NewLiveLogEncoder(size int, enc zapcore.Encoder) *LiveLog {
+	if size <= 0 {
+		size = 1000
+	}
+	return &LiveLog{
+		enc:     enc,
+		ring:    newRingBuffer(size),
+		clients: make(map[chan LiveEntry]struct{}),
+	}
+}
+
+// Core returns the core feeding l, to be added with WithCore.
+func (l *LiveLog) Core() zapcore.Core {
+	return &liveCore{l: l, enc: l.enc.Clone()}
+}
+
+// add stores an entry and hands it to the clients.
+func (l *LiveLog) add(lvl zapcore.Level, name string, data []byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e := l.ring.push(LiveEntry{Level: Level(lvl), Name: name, Data: data})
+	for c := range l.clients {
+		select {
+		case c <- e:
+		default:
+		}
+	}
+}
+
+// Subscribe returns the stored entries numbered after seq, oldest first,
+// and a channel receiving the entries logged later until cancel is called.
+// Entries logged while the channel is full are not sent to it.
+func (l *LiveLog) Subscribe(after uint64) (recent []LiveEntry, entries <-chan LiveEntry, cancel func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	recent = l.ring.after(after)
+	c := make(chan LiveEntry, liveBuffer)
+	l.clients[c] = struct{}{}
+	return recent, c, func() {
+		l.mu.Lock()
+		delete(l.clients, c)
+		l.mu.Unlock()
+	}
+}
+
+// Handler returns an HTTP handler streaming the stored entries, oldest
+// first, then the new ones as Server-Sent Events, one JSON entry per
+// event. Query parameters select the entries:
+//
+//	level  the minimum level, e.g. "warn"
+//	name   a named logger, including its dotted children, e.g. "net"
+//	n      the number of stored entries to send, all by default
+//
+// Event ids are entry sequence numbers, so that a reconnecting
+// EventSource resumes after the last entry it received.
+func (l *LiveLog) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming not supported", http.StatusInternalServerError)
+			return
+		}
+		q := r.URL.Query()
+		minLevel := TraceLevel
+		if s := q.Get("level"); s != "" {
+			lvl, err := ParseLevel(s)
+			if err != nil {
+				http.Error(w, "invalid level", http.StatusBadRequest)
+				return
+			}
+			minLevel = lvl
+		}
+		name := q.Get("name")
+		n := -1
+		if s := q.Get("n"); s != "" {
+			var err error
+			if n, err = strconv.Atoi(s); err != nil || n < 0 {
+				http.Error(w, "invalid n", http.StatusBadRequest)
+				return
+			}
+		}
+		var after uint64
+		if s := r.Header.Get("Last-Event-ID"); s != "" {
+			after, _ = strconv.ParseUint(s, 10, 64)
+		}
+		match := func(e LiveEntry) bool {
+			return e.Level >= minLevel && inLogger(e.Name, name)
+		}
+
+		recent, c, cancel := l.Subscribe(after)
+		defer cancel()
+		matched := recent[:0]
+		for _, e := range recent {
+			if match(e) {
+				matched = append(matched, e)
+			}
+		}
+		if n >= 0 && n < len(matched) {
+			matched = matched[len(matched)-n:]
+		}
+
+		h := w.Header()
+		h.Set("Content-Type", "text/event-stream")
+		h.Set("Cache-Control", "no-cache")
+		h.Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		for _, e := range matched {
+			if writeEvent(w, e) != nil {
+				return
+			}
+		}
+		f.Flush()
+
+		ping := time.NewTicker(livePing)
+		defer ping.Stop()
+		for {
+			select {
+			case e := <-c:
+				if !match(e) {
+					continue
+				}
+				if writeEvent(w, e) != nil {
+					return
+				}
+			case <-ping.C:
+				if _, err := w.Write([]byte(": ping\n\n")); err != nil {
+					return
+				}
+			case <-r.Context().Done():
+				return
+			}
+			f.Flush()
+		}
+	})
+}
+
+// writeEvent writes e as a Server-Sent Event.
+func writeEvent(w http.ResponseWriter, e LiveEntry) error {
+	var b bytes.Buffer
+	b.WriteString("id: ")
+	b.WriteString(strconv.FormatUint(e.Seq, 10))
+	b.WriteString("\ndata: ")
+	b.Write(bytes.TrimRight(e.Data, "\n"))
+	b.WriteString("\n\n")
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// liveCore encodes entries for a LiveLog.
+type liveCore struct {
+	l   *LiveLog
+	enc zapcore.Encoder
+}
+
+func (c *liveCore) Enabled(zapcore.Level) bool {
+	return true
+}
+
+func (c *liveCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return &liveCore{l: c.l, enc: enc}
+}
+
+func (c *liveCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, c)
+}
+
+func (c *liveCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	data := append([]byte(nil), buf.Bytes()...)
+	buf.Free()
+	c.l.add(ent.Level, ent.LoggerName, data)
+	return nil
+}
+
+func (c *liveCore) Sync() error {
+	return nil
+}
@@ -0,0 +1,34 @@
+package log
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestLiveLogSubscribe(t *testing.T) {
+	live := NewLiveLogEncoder(3, NewMsgpackEncoder())
+	lg := zap.New(live.Core()).Named("net")
+	for i := 0; i < 5; i++ {
+		lg.Info("entry")
+	}
+
+	recent, entries, cancel := live.Subscribe(3)
+	defer cancel()
+	if len(recent) != 2 || recent[0].Seq != 4 || recent[1].Seq != 5 {
+		t.Fatalf("recent entries %+v, want 4 and 5", recent)
+	}
+	if recent[0].Name != "net" || recent[0].Level != InfoLevel {
+		t.Errorf("entry of %q at %v", recent[0].Name, recent[0].Level)
+	}
+	if all, _, cancel := live.Subscribe(0); len(all) != 3 || all[0].Seq != 3 {
+		t.Errorf("stored entries %+v, want 3 to 5", all)
+	} else {
+		cancel()
+	}
+
+	lg.Warn("later")
+	if e := <-entries; e.Seq != 6 || e.Level != WarningLevel {
+		t.Errorf("sent %+v, want entry 6", e)
+	}
+}
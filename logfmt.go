@@ -0,0 +1,83 @@
+package log
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+func init() {
+	registerEncoder("logfmt", func(zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		return NewLogfmtEncoder(), nil
+	})
+}
+
+type logfmtEncoder struct {
+	kvEncoder
+}
+
+// NewLogfmtEncoder returns an encoder producing logfmt lines:
+//
+//	ts=2021-06-01T10:00:00.000Z level=info caller=main.go:12 msg="user connected" user=bob
+func NewLogfmtEncoder() zapcore.Encoder {
+	return &logfmtEncoder{}
+}
+
+func (e *logfmtEncoder) Clone() zapcore.Encoder {
+	return &logfmtEncoder{kvEncoder: e.kvEncoder.clone()}
+}
+
+func (e *logfmtEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	buf := bufferPool.Get()
+	appendLogfmt(buf, "ts", ent.Time.Format("2006-01-02T15:04:05.000Z07:00"))
+	appendLogfmt(buf, "level", Level(ent.Level).String())
+	if ent.LoggerName != "" {
+		appendLogfmt(buf, "logger", ent.LoggerName)
+	}
+	if ent.Caller.Defined {
+		appendLogfmt(buf, "caller", ent.Caller.TrimmedPath())
+	}
+	appendLogfmt(buf, "msg", ent.Message)
+	for _, f := range e.addFields(fields) {
+		appendLogfmt(buf, f.key, logfmtValue(f.val))
+	}
+	if ent.Stack != "" {
+		appendLogfmt(buf, "stacktrace", ent.Stack)
+	}
+	buf.AppendString(zapcore.DefaultLineEnding)
+	return buf, nil
+}
+
+// logfmtValue formats a value; durations keep their unit, which logfmt
+// consumers understand.
+func logfmtValue(v interface{}) string {
+	if d, ok := v.(time.Duration); ok {
+		return d.String()
+	}
+	return formatValue(v)
+}
+
+func appendLogfmt(buf *buffer.Buffer, key, val string) {
+	if buf.Len() > 0 {
+		buf.AppendByte(' ')
+	}
+	buf.AppendString(logfmtKey(key))
+	buf.AppendByte('=')
+	buf.AppendString(quoteIfNeeded(val))
+}
+
+// logfmtKey replaces the characters logfmt does not allow in keys.
+func logfmtKey(key string) string {
+	if key == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || !unicode.IsPrint(r) {
+			return '_'
+		}
+		return r
+	}, key)
+}
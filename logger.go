@@ -6,23 +6,199 @@ import (
 	"go.uber.org/zap/zapcore"
 	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type Logger struct {
 	level Level
-	zap   *zap.SugaredLogger
+	atom  zap.AtomicLevel
+	floor zap.AtomicLevel
+	swap  *coreSwap
+	// secSwap is the handle of the core of the security logger.
+	secSwap *coreSwap
+	zap     *zap.SugaredLogger
+	base    *zap.Logger
+
+	legacyPrint bool
+	strictArgs  bool
+	development bool
+	exitCode    int
+	recorder    *ringBuffer
+	crashPath   string
+	blackBox    *blackBox
+	fatalDump   bool
+	exitTimeout time.Duration
+	clock       zapcore.Clock
+	audit       *zap.Logger
+	security    *Logger
+	streams     map[string]*Logger
+	tenants     *tenantFiles
+	async       []*AsyncWriter
+	stops       []func()
+	encoding    string
+	wrapOutput  func(zapcore.Core) zapcore.Core
+	named       *namedEntry
+	baggage     *baggage
 }
 type Level zapcore.Level
 
+const (
+	// TraceLevel is below DEBUG, for output too verbose even for debugging
+	// sessions.
+	TraceLevel   = Level(zapcore.DebugLevel - 1)
+	DebugLevel   = Level(zapcore.DebugLevel)
+	InfoLevel    = Level(zapcore.InfoLevel)
+	WarningLevel = Level(zapcore.WarnLevel)
+	ErrorLevel   = Level(zapcore.ErrorLevel)
+	PanicLevel   = Level(zapcore.PanicLevel)
+	FatalLevel   = Level(zapcore.FatalLevel)
+	// OffLevel is above every level: nothing is logged at it. Fatal*
+	// functions still exit.
+	OffLevel = Level(zapcore.FatalLevel + 1)
+)
+
 type Zap struct {
 	sugarClient *zap.SugaredLogger
 	client      *zap.Logger
 }
 
-var l = &Logger{}
+// current holds the *Logger used by the package functions. Init and
+// Disable replace it as a whole, so concurrent callers always see either
+// the previous or the new, fully built logger.
+var current atomic.Value
+
+// initMu serializes Init and Disable.
+var initMu sync.Mutex
 
-func Init(debug bool) {
+// std returns the logger used by the package functions, or the default
+// one if Init has not been called yet.
+func std() *Logger {
+	if lg, ok := current.Load().(*Logger); ok {
+		return lg
+	}
+	return defaultLogger()
+}
+
+var (
+	defaultOnce sync.Once
+	defaultLg   *Logger
+)
+
+// defaultLogger returns the logger used before Init: console output to
+// stderr at INFO level, so that libraries logging before the application
+// has configured the package do not crash it. It is built on first use.
+func defaultLogger() *Logger {
+	defaultOnce.Do(func() {
+		atom := LevelToAtomic(InfoLevel)
+		enc := zapcore.NewConsoleEncoder(NewEncoderConfig())
+		level := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
+			return atom.Enabled(lvl) && !stripped(Level(lvl)) && !Silenced()
+		})
+		core := zapcore.NewCore(enc, zapcore.Lock(os.Stderr), level)
+		hook := &exitHook{}
+		lg := zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1), zap.WithFatalHook(hook))
+		defaultLg = &Logger{
+			level: InfoLevel,
+			atom:  atom,
+			zap:   lg.Sugar(),
+			base:  lg,
+
+			exitCode: 1,
+		}
+		hook.lg = defaultLg
+	})
+	return defaultLg
+}
+
+// replace publishes next as the package logger, then flushes the previous
+// one and ends its background work. Entries logged through the previous
+// logger while it is being replaced are still written.
+func replace(next *Logger) {
+	initMu.Lock()
+	defer initMu.Unlock()
+	prev := std()
+	updateFloor(next)
+	current.Store(next)
+	_ = prev.Sync()
+	prev.stop()
+}
+
+// NewNop returns a logger that discards everything written to it. It is
+// meant for tests and benchmarks where output is unwanted.
+func NewNop() *Logger {
+	hook := &exitHook{}
+	lg := zap.NewNop().WithOptions(zap.WithFatalHook(hook))
+	hook.lg = &Logger{
+		level: Level(zap.FatalLevel),
+		zap:   lg.Sugar(),
+		base:  lg,
+
+		exitCode: 1,
+	}
+	return hook.lg
+}
+
+// Disable replaces the package logger with a no-op one.
+func Disable() {
+	replace(NewNop())
+}
+
+// stop ends the background work of a logger that has been replaced.
+func (lg *Logger) stop() {
+	runStops(lg.stops)
+}
+
+// runStops runs stops in reverse order, so that the queues are flushed
+// before the outputs behind them are closed.
+func runStops(stops []func()) {
+	for i := len(stops) - 1; i >= 0; i-- {
+		stops[i]()
+	}
+}
+
+// Init builds the package logger. It may be called again at any time, also
+// while other goroutines are logging, to reconfigure it: the new logger is
+// built completely before it replaces the previous one, which is then
+// flushed and closed. If building fails, the error is printed and the
+// previous logger is kept; use InitE to handle it instead.
+func Init(debug bool, opts ...Option) {
+	if err := InitE(debug, opts...); err != nil {
+		fmt.Println("Logger init error: ", err)
+	}
+}
+
+// MustInit is like Init but panics if the logger cannot be built.
+func MustInit(debug bool, opts ...Option) {
+	if err := InitE(debug, opts...); err != nil {
+		panic(err)
+	}
+}
+
+// InitE is like Init but returns the error if the logger cannot be built,
+// for example because an output cannot be opened. The previous logger is
+// kept in that case.
+func InitE(debug bool, opts ...Option) error {
+	next, err := build(debug, newOptions(opts), nil)
+	if err != nil {
+		return err
+	}
+	replace(next)
+	return nil
+}
+
+// build builds a logger from o. If prev was built by Init, the new logger
+// shares its handle: the cores of prev and of every logger derived from it
+// are switched to the new ones once the build has succeeded.
+func build(debug bool, o *options, prev *Logger) (lg *Logger, err error) {
+	defer func() {
+		// Outputs opened and background work started for a logger that
+		// is never published.
+		if err != nil {
+			runStops(o.stops)
+		}
+	}()
 	lvl := "info"
 	isDev := false
 	disableStack := true
@@ -34,51 +210,297 @@ func Init(debug bool) {
 		disableStack = true
 	}
 
+	// The outputs are opened up to the lowest level set on a named logger;
+	// the package level is applied above them, see levelRouter.
+	o.root = LevelToAtomic(MustParseLevel(lvl))
+	floor := LevelToAtomic(MustParseLevel(lvl))
+	o.swap, o.secSwap = newCoreSwap(), newCoreSwap()
+	o.exit = &exitHook{}
+	if prev != nil && prev.swap != nil {
+		o.root, floor, o.swap, o.secSwap = prev.atom, prev.floor, prev.swap, prev.secSwap
+	}
 	config := &zap.Config{
-		Level:             LevelToAtomic(ParseLevel(lvl)),
+		Level:             floor,
 		Development:       isDev,
 		DisableCaller:     false,
 		DisableStacktrace: disableStack,
-		Sampling: &zap.SamplingConfig{
-			Initial:    100,
-			Thereafter: 100,
-		},
-		Encoding: "json",
-		EncoderConfig: zapcore.EncoderConfig{
-			TimeKey:        "ts",
-			LevelKey:       "level",
-			NameKey:        "logger",
-			CallerKey:      "caller",
-			FunctionKey:    zapcore.OmitKey,
-			MessageKey:     "msg",
-			StacktraceKey:  "stacktrace",
-			LineEnding:     zapcore.DefaultLineEnding,
-			EncodeLevel:    zapcore.CapitalLevelEncoder,
-			EncodeTime:     stampTimeEncoder,
-			EncodeDuration: zapcore.SecondsDurationEncoder,
-			EncodeCaller:   callerEncoder,
-		},
+		Encoding:          o.fieldsEncoding(o.resolveEncoding(debug)),
+		EncoderConfig:     NewEncoderConfig(),
 		//OutputPaths:      []string{"/var/log/syslog"},
 		//ErrorOutputPaths: []string{"/var/log/syslog"},
 		OutputPaths:      []string{"stdout"},
 		ErrorOutputPaths: []string{"stdout"},
 	}
-	lg, err := config.Build(zap.AddCallerSkip(1))
+	for _, ef := range o.encrypted {
+		w, err := OpenEncryptedFile(ef.path, ef.pub)
+		if err != nil {
+			return nil, err
+		}
+		o.stops = append(o.stops, func() { _ = w.Close() })
+		o.sinks = append(o.sinks, sink{ws: w})
+	}
+	if o.split != nil {
+		// The default output is replaced by the two split outputs.
+		config.OutputPaths = nil
+		low, closeLow, err := zap.Open(o.split.low)
+		if err != nil {
+			return nil, err
+		}
+		o.stops = append(o.stops, closeLow)
+		high, closeHigh, err := zap.Open(o.split.high)
+		if err != nil {
+			return nil, err
+		}
+		o.stops = append(o.stops, closeHigh)
+		o.sinks = append([]sink{
+			{ws: low, level: zap.LevelEnablerFunc(func(lvl zapcore.Level) bool { return lvl < zapcore.ErrorLevel })},
+			{ws: high, level: zap.LevelEnablerFunc(func(lvl zapcore.Level) bool { return lvl >= zapcore.ErrorLevel })},
+		}, o.sinks...)
+	}
+	if o.fallback != nil {
+		// The default output becomes one of the sinks, so that it falls
+		// back as well.
+		if config.OutputPaths != nil {
+			config.OutputPaths = nil
+			o.sinks = append([]sink{{ws: zapcore.AddSync(os.Stdout)}}, o.sinks...)
+		}
+		for i, s := range o.sinks {
+			o.sinks[i].ws = NewFallbackWriter(s.ws, o.fallback.secondary, o.fallback.retry)
+		}
+	}
+	if o.async != nil {
+		// The default output becomes one of the queued sinks.
+		if config.OutputPaths != nil {
+			config.OutputPaths = nil
+			o.sinks = append([]sink{{ws: zapcore.AddSync(os.Stdout)}}, o.sinks...)
+		}
+		for i, s := range o.sinks {
+			// The output is shared with the security logger.
+			direct := zapcore.Lock(s.ws)
+			w := NewAsyncWriter(direct, *o.async)
+			o.sinks[i].ws, o.sinks[i].direct = w, direct
+			o.asyncWriters = append(o.asyncWriters, w)
+			o.stops = append(o.stops, func() { w.Close() })
+		}
+	}
+	for _, as := range o.asyncSinks {
+		direct := zapcore.Lock(as.ws)
+		w := NewAsyncWriter(direct, as.cfg)
+		o.sinks = append(o.sinks, sink{ws: w, direct: direct})
+		o.asyncWriters = append(o.asyncWriters, w)
+		o.stops = append(o.stops, func() { w.Close() })
+	}
+	o.syncCores = append(o.syncCores, o.cores...)
+	for _, s := range o.sinks {
+		name := config.Encoding
+		if s.encoding != "" {
+			name = o.fieldsEncoding(s.encoding)
+		}
+		enc, err := newEncoder(name, config.EncoderConfig)
+		if err != nil {
+			return nil, err
+		}
+		var level zapcore.LevelEnabler = config.Level
+		if only := s.level; only != nil {
+			level = zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
+				return config.Level.Enabled(lvl) && only.Enabled(lvl)
+			})
+		}
+		core := zapcore.NewCore(enc, zapcore.Lock(exitSyncer{s.ws}), level)
+		o.cores = append(o.cores, core)
+		if s.direct != nil {
+			core = zapcore.NewCore(enc.Clone(), exitSyncer{s.direct}, level)
+		}
+		o.syncCores = append(o.syncCores, core)
+	}
+	base, err := config.Build(o.zapOptions()...)
 	if err != nil {
-		fmt.Println("Logger init error: ", err)
-		return
+		return nil, err
+	}
+	audit, closeAudit, err := newAuditLogger(o.auditPaths, o.entryClock())
+	if err != nil {
+		return nil, err
+	}
+	o.stops = append(o.stops, closeAudit)
+	streams := make(map[string]*zap.Logger, len(o.streams))
+	for name, cfg := range o.streams {
+		if streams[name], err = newStreamLogger(cfg, config, o); err != nil {
+			return nil, err
+		}
 	}
 
-	l = &Logger{
-		level: ParseLevel(lvl),
-		zap:   lg.Sugar(),
+	var tenants *tenantFiles
+	if cfg := o.tenants; cfg != nil {
+		name := config.Encoding
+		if cfg.Encoding != "" {
+			name = o.fieldsEncoding(cfg.Encoding)
+		}
+		enc, err := newEncoder(name, config.EncoderConfig)
+		if err != nil {
+			return nil, err
+		}
+		if tenants, err = newTenantFiles(*cfg, enc, floor, o.wrapOutput); err != nil {
+			return nil, err
+		}
+		o.stops = append(o.stops, tenants.close)
+	}
+
+	for _, md := range o.metadata {
+		stop, err := md.start()
+		if err != nil {
+			return nil, err
+		}
+		o.stops = append(o.stops, stop)
+	}
+
+	for _, d := range o.anomaly {
+		go d.run()
+		o.stops = append(o.stops, d.close)
+	}
+
+	if o.heartbeat != nil {
+		o.heartbeat.start()
+		o.stops = append(o.stops, o.heartbeat.close)
+	}
+
+	if o.diskGuard != nil {
+		go o.diskGuard.run()
+		o.stops = append(o.stops, o.diskGuard.close)
+	}
+
+	o.swap.store(o.inner)
+	o.secSwap.store(o.secure)
+	o.root.SetLevel(zapcore.Level(MustParseLevel(lvl)))
+
+	lg = &Logger{
+		level:   MustParseLevel(lvl),
+		atom:    o.root,
+		floor:   floor,
+		swap:    o.swap,
+		secSwap: o.secSwap,
+		zap:     base.Sugar(),
+		base:    base,
+
+		legacyPrint: o.legacyPrint,
+		strictArgs:  o.strictArgs,
+		development: isDev,
+		exitCode:    o.exitCode,
+		recorder:    o.recorder,
+		crashPath:   o.crashPath,
+		blackBox:    o.blackBox,
+		fatalDump:   o.fatalDump,
+		exitTimeout: o.exitTimeout,
+		clock:       o.clock,
+		audit:       audit,
+		async:       o.asyncWriters,
+		stops:       o.stops,
+		encoding:    config.Encoding,
+		baggage:     o.baggage,
+		tenants:     tenants,
+		wrapOutput:  o.wrapOutput,
+	}
+	o.exit.lg = lg
+	lg.security = lg.newSecurityLogger()
+	lg.streams = make(map[string]*Logger, len(streams))
+	for name, base := range streams {
+		lg.streams[name] = lg.newStream(base)
 	}
+	return lg, nil
+}
+
+// Sync flushes any buffered entries.
+func Sync() error {
+	return std().Sync()
 }
 
+// Sync flushes any buffered entries.
+func (lg *Logger) Sync() error {
+	// Both are synced even if the first fails, so a console that cannot
+	// be synced does not keep queued outputs from being flushed.
+	err := lg.base.Sync()
+	if lg.audit != nil {
+		if aerr := lg.audit.Sync(); err == nil {
+			err = aerr
+		}
+	}
+	for _, s := range lg.streams {
+		if serr := s.base.Sync(); err == nil {
+			err = serr
+		}
+	}
+	return err
+}
+
+// Sugar returns the underlying sugared logger for advanced use.
+func Sugar() *zap.SugaredLogger {
+	return std().Sugar()
+}
+
+// Desugar returns the underlying zap.Logger for advanced use.
+func Desugar() *zap.Logger {
+	return std().Desugar()
+}
+
+// Sugar returns the underlying sugared logger for advanced use.
+func (lg *Logger) Sugar() *zap.SugaredLogger {
+	return lg.Desugar().Sugar()
+}
+
+// Desugar returns the underlying zap.Logger for advanced use. The caller
+// skip used by the wrapper functions is removed, so entries logged through
+// it report the right caller.
+func (lg *Logger) Desugar() *zap.Logger {
+	return lg.base.WithOptions(zap.AddCallerSkip(-1))
+}
+
+// NewEncoderConfig returns the encoder configuration used by the package,
+// for building additional cores that match its output.
+func NewEncoderConfig() zapcore.EncoderConfig {
+	return zapcore.EncoderConfig{
+		TimeKey:        "ts",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		FunctionKey:    zapcore.OmitKey,
+		MessageKey:     "msg",
+		StacktraceKey:  "stacktrace",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    capitalLevelEncoder,
+		EncodeTime:     stampTimeEncoder,
+		EncodeDuration: zapcore.SecondsDurationEncoder,
+		EncodeCaller:   callerEncoder,
+	}
+}
+
+// capitalLevelEncoder is zapcore.CapitalLevelEncoder knowing TraceLevel.
+func capitalLevelEncoder(lvl zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendString(Level(lvl).CapitalString())
+}
+
+// callerEncoder writes "dir/file.go:line.Func()". It is on the path of
+// every entry, so it is built in a pooled buffer rather than by joining
+// strings.
 func callerEncoder(caller zapcore.EntryCaller, enc zapcore.PrimitiveArrayEncoder) {
-	arr := strings.Split(caller.Function, ".")
-	funName := arr[len(arr)-1]
-	enc.AppendString(caller.TrimmedPath() + "." + funName + "()")
+	file := caller.File
+	if i := strings.LastIndexByte(file, '/'); i >= 0 {
+		if j := strings.LastIndexByte(file[:i], '/'); j >= 0 {
+			file = file[j+1:]
+		}
+	}
+	funName := caller.Function
+	if i := strings.LastIndexByte(funName, '.'); i >= 0 {
+		funName = funName[i+1:]
+	}
+
+	buf := bufferPool.Get()
+	buf.AppendString(file)
+	buf.AppendByte(':')
+	buf.AppendInt(int64(caller.Line))
+	buf.AppendByte('.')
+	buf.AppendString(funName)
+	buf.AppendString("()")
+	enc.AppendByteString(buf.Bytes())
+	buf.Free()
 }
 
 func stampTimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
@@ -99,114 +521,432 @@ func LevelToAtomic(lvl Level) zap.AtomicLevel {
 	return zap.NewAtomicLevelAt(zapcore.Level(lvl))
 }
 
-func ParseLevel(lvl string) (level Level) {
-	switch lvl {
+// ParseLevel parses a level name, ignoring case: "trace", "debug", "info",
+// "warn" or "warning", "err" or "error", "panic", "fatal" and "off".
+func ParseLevel(lvl string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(lvl)) {
+	case "trace":
+		return TraceLevel, nil
 	case "debug":
-		level = Level(zap.DebugLevel)
+		return DebugLevel, nil
 	case "info":
-		level = Level(zap.InfoLevel)
-	case "warning":
-		level = Level(zap.WarnLevel)
-	case "error":
-		level = Level(zap.ErrorLevel)
+		return InfoLevel, nil
+	case "warn", "warning":
+		return WarningLevel, nil
+	case "err", "error":
+		return ErrorLevel, nil
 	case "panic":
-		level = Level(zap.PanicLevel)
+		return PanicLevel, nil
 	case "fatal":
-		level = Level(zap.FatalLevel)
+		return FatalLevel, nil
+	case "off":
+		return OffLevel, nil
 	}
-	return
+	return InfoLevel, fmt.Errorf("log: unknown level %q", lvl)
 }
 
-// Fatal followed by a call to os.Exit(1).
+// MustParseLevel is like ParseLevel but panics if lvl is not a level name.
+func MustParseLevel(lvl string) Level {
+	level, err := ParseLevel(lvl)
+	if err != nil {
+		panic(err)
+	}
+	return level
+}
+
+// Fatal followed by the exit hooks and a call to os.Exit.
 func Fatal(msg ...interface{}) {
-	l.zap.Fatal(msg)
-	os.Exit(1)
+	lg := std()
+	lg.zap.Fatal(lg.printArgs(msg)...)
+	lg.exit()
 }
 
-// Fatalf followed by a call to os.Exit(1).
+// Fatalf followed by the exit hooks and a call to os.Exit.
 func Fatalf(format string, args ...interface{}) {
-	msg := fmt.Sprintf(format, args...)
-	l.zap.Fatal(msg)
-	os.Exit(1)
+	lg := std()
+	lg.zap.Fatalf(format, args...)
+	lg.exit()
 }
 
 // Panic followed by a call to panic().
 func Panic(msg ...interface{}) {
-	l.zap.Panic(msg)
+	lg := std()
+	lg.zap.Panic(lg.printArgs(msg)...)
 }
 
 // Panicf followed by a call to panic().
 func Panicf(format string, args ...interface{}) {
-	msg := fmt.Sprintf(format, args...)
-	l.zap.Panic(msg)
+	std().zap.Panicf(format, args...)
 }
 
 // Error logs a message using ERROR as log level.
 func Error(msg ...interface{}) {
-	l.zap.Error(msg)
+	lg := std()
+	lg.zap.Error(lg.printArgs(msg)...)
 }
 
 // Errorf logs a message using ERROR as log level.
 func Errorf(format string, args ...interface{}) {
-	msg := fmt.Sprintf(format, args...)
-	l.zap.Error(msg)
+	std().zap.Errorf(format, args...)
 }
 
 // Warning logs a message using WARNING as log level.
 func Warning(msg ...interface{}) {
-	l.zap.Warn(msg)
+	lg := std()
+	lg.zap.Warn(lg.printArgs(msg)...)
 }
 
 // Warningf logs a message using WARNING as log level.
 func Warningf(format string, args ...interface{}) {
-	msg := fmt.Sprintf(format, args...)
-	l.zap.Warn(msg)
+	std().zap.Warnf(format, args...)
 }
 
 // Info logs a message using INFO as log level.
 func Info(msg ...interface{}) {
-	l.zap.Info(msg)
+	if stripped(InfoLevel) {
+		return
+	}
+	lg := std()
+	lg.zap.Info(lg.printArgs(msg)...)
 }
 
 // Infof logs a message using INFO as log level.
 func Infof(format string, args ...interface{}) {
-	msg := fmt.Sprintf(format, args...)
-	l.zap.Info(msg)
+	if stripped(InfoLevel) {
+		return
+	}
+	std().zap.Infof(format, args...)
 }
 
 // Debug logs a message using DEBUG as log level.
 func Debug(msg ...interface{}) {
-	l.zap.Debug(msg)
+	if stripped(DebugLevel) {
+		return
+	}
+	lg := std()
+	lg.zap.Debug(lg.printArgs(msg)...)
 }
 
 // Debugf logs a message using DEBUG as log level.
 func Debugf(format string, args ...interface{}) {
-	msg := fmt.Sprintf(format, args...)
-	l.zap.Debug(msg)
+	if stripped(DebugLevel) {
+		return
+	}
+	std().zap.Debugf(format, args...)
+}
+
+// Fatalln followed by the exit hooks and a call to os.Exit.
+func Fatalln(msg ...interface{}) {
+	lg := std()
+	lg.zap.Fatal(sprintln(msg))
+	lg.exit()
+}
+
+// Panicln followed by a call to panic().
+func Panicln(msg ...interface{}) {
+	std().zap.Panic(sprintln(msg))
+}
+
+// Errorln logs a message using ERROR as log level.
+func Errorln(msg ...interface{}) {
+	std().zap.Error(sprintln(msg))
+}
+
+// Warningln logs a message using WARNING as log level.
+func Warningln(msg ...interface{}) {
+	std().zap.Warn(sprintln(msg))
+}
+
+// Infoln logs a message using INFO as log level.
+func Infoln(msg ...interface{}) {
+	if stripped(InfoLevel) {
+		return
+	}
+	std().zap.Info(sprintln(msg))
 }
 
-// Fatalw followed by a call to os.Exit(1).
+// Debugln logs a message using DEBUG as log level.
+func Debugln(msg ...interface{}) {
+	if stripped(DebugLevel) {
+		return
+	}
+	std().zap.Debug(sprintln(msg))
+}
+
+// Fatalw followed by the exit hooks and a call to os.Exit.
 func Fatalw(msg string, args ...interface{}) {
-	l.zap.Fatalw(msg, args...)
-	os.Exit(1)
+	lg := std()
+	lg.zap.Fatalw(msg, lg.kvArgs(args)...)
+	lg.exit()
 }
 
 // Errorw logs a message using ERROR as log level.
 func Errorw(msg string, args ...interface{}) {
-	l.zap.Errorw(msg, args...)
+	lg := std()
+	lg.zap.Errorw(msg, lg.kvArgs(args)...)
 }
 
 // Warningf logs a message using WARNING as log level.
 func Warningw(msg string, args ...interface{}) {
-	l.zap.Warnw(msg, args...)
+	lg := std()
+	lg.zap.Warnw(msg, lg.kvArgs(args)...)
 }
 
 // Infof logs a message using INFO as log level.
 func Infow(msg string, args ...interface{}) {
-	l.zap.Infow(msg, args...)
+	if stripped(InfoLevel) {
+		return
+	}
+	lg := std()
+	lg.zap.Infow(msg, lg.kvArgs(args)...)
 }
 
 // Debugf logs a message using DEBUG as log level.
 func Debugw(msg string, args ...interface{}) {
-	l.zap.Debugw(msg, args...)
+	if stripped(DebugLevel) {
+		return
+	}
+	lg := std()
+	lg.zap.Debugw(msg, lg.kvArgs(args)...)
+}
+
+// Fatalz followed by the exit hooks and a call to os.Exit.
+func Fatalz(msg string, fields ...Field) {
+	lg := std()
+	lg.base.Fatal(msg, fields...)
+	lg.exit()
+}
+
+// Errorz logs a message with typed fields using ERROR as log level.
+func Errorz(msg string, fields ...Field) {
+	std().base.Error(msg, fields...)
+}
+
+// Warningz logs a message with typed fields using WARNING as log level.
+func Warningz(msg string, fields ...Field) {
+	std().base.Warn(msg, fields...)
+}
+
+// Infoz logs a message with typed fields using INFO as log level.
+func Infoz(msg string, fields ...Field) {
+	if stripped(InfoLevel) {
+		return
+	}
+	std().base.Info(msg, fields...)
+}
+
+// Debugz logs a message with typed fields using DEBUG as log level.
+func Debugz(msg string, fields ...Field) {
+	if stripped(DebugLevel) {
+		return
+	}
+	std().base.Debug(msg, fields...)
+}
+
+// Check returns a CheckedEntry if logging a message at the given level is
+// enabled. It is the allocation-free way to log from hot paths:
+//
+//	if ce := log.Check(log.DebugLevel, "packet"); ce != nil {
+//		ce.Write(log.Int("len", n))
+//	}
+func Check(lvl Level, msg string) *zapcore.CheckedEntry {
+	return std().base.Check(zapcore.Level(lvl), msg)
+}
+
+// Fatal followed by the exit hooks and a call to os.Exit.
+func (lg *Logger) Fatal(msg ...interface{}) {
+	lg.zap.Fatal(lg.printArgs(msg)...)
+	lg.exit()
+}
+
+// Fatalf followed by the exit hooks and a call to os.Exit.
+func (lg *Logger) Fatalf(format string, args ...interface{}) {
+	lg.zap.Fatalf(format, args...)
+	lg.exit()
+}
+
+// Panic followed by a call to panic().
+func (lg *Logger) Panic(msg ...interface{}) {
+	lg.zap.Panic(lg.printArgs(msg)...)
+}
+
+// Panicf followed by a call to panic().
+func (lg *Logger) Panicf(format string, args ...interface{}) {
+	lg.zap.Panicf(format, args...)
+}
+
+// Error logs a message using ERROR as log level.
+func (lg *Logger) Error(msg ...interface{}) {
+	lg.zap.Error(lg.printArgs(msg)...)
+}
+
+// Errorf logs a message using ERROR as log level.
+func (lg *Logger) Errorf(format string, args ...interface{}) {
+	lg.zap.Errorf(format, args...)
+}
+
+// Warning logs a message using WARNING as log level.
+func (lg *Logger) Warning(msg ...interface{}) {
+	lg.zap.Warn(lg.printArgs(msg)...)
+}
+
+// Warningf logs a message using WARNING as log level.
+func (lg *Logger) Warningf(format string, args ...interface{}) {
+	lg.zap.Warnf(format, args...)
+}
+
+// Info logs a message using INFO as log level.
+func (lg *Logger) Info(msg ...interface{}) {
+	if stripped(InfoLevel) {
+		return
+	}
+	lg.zap.Info(lg.printArgs(msg)...)
+}
+
+// Infof logs a message using INFO as log level.
+func (lg *Logger) Infof(format string, args ...interface{}) {
+	if stripped(InfoLevel) {
+		return
+	}
+	lg.zap.Infof(format, args...)
+}
+
+// Debug logs a message using DEBUG as log level.
+func (lg *Logger) Debug(msg ...interface{}) {
+	if stripped(DebugLevel) {
+		return
+	}
+	lg.zap.Debug(lg.printArgs(msg)...)
+}
+
+// Debugf logs a message using DEBUG as log level.
+func (lg *Logger) Debugf(format string, args ...interface{}) {
+	if stripped(DebugLevel) {
+		return
+	}
+	lg.zap.Debugf(format, args...)
+}
+
+// Fatalln followed by the exit hooks and a call to os.Exit.
+func (lg *Logger) Fatalln(msg ...interface{}) {
+	lg.zap.Fatal(sprintln(msg))
+	lg.exit()
+}
+
+// Panicln followed by a call to panic().
+func (lg *Logger) Panicln(msg ...interface{}) {
+	lg.zap.Panic(sprintln(msg))
+}
+
+// Errorln logs a message using ERROR as log level.
+func (lg *Logger) Errorln(msg ...interface{}) {
+	lg.zap.Error(sprintln(msg))
+}
+
+// Warningln logs a message using WARNING as log level.
+func (lg *Logger) Warningln(msg ...interface{}) {
+	lg.zap.Warn(sprintln(msg))
+}
+
+// Infoln logs a message using INFO as log level.
+func (lg *Logger) Infoln(msg ...interface{}) {
+	if stripped(InfoLevel) {
+		return
+	}
+	lg.zap.Info(sprintln(msg))
+}
+
+// Debugln logs a message using DEBUG as log level.
+func (lg *Logger) Debugln(msg ...interface{}) {
+	if stripped(DebugLevel) {
+		return
+	}
+	lg.zap.Debug(sprintln(msg))
+}
+
+// Fatalw followed by the exit hooks and a call to os.Exit.
+func (lg *Logger) Fatalw(msg string, args ...interface{}) {
+	lg.zap.Fatalw(msg, lg.kvArgs(args)...)
+	lg.exit()
+}
+
+// Errorw logs a message using ERROR as log level.
+func (lg *Logger) Errorw(msg string, args ...interface{}) {
+	lg.zap.Errorw(msg, lg.kvArgs(args)...)
+}
+
+// Warningw logs a message using WARNING as log level.
+func (lg *Logger) Warningw(msg string, args ...interface{}) {
+	lg.zap.Warnw(msg, lg.kvArgs(args)...)
+}
+
+// Infow logs a message using INFO as log level.
+func (lg *Logger) Infow(msg string, args ...interface{}) {
+	if stripped(InfoLevel) {
+		return
+	}
+	lg.zap.Infow(msg, lg.kvArgs(args)...)
+}
+
+// Debugw logs a message using DEBUG as log level.
+func (lg *Logger) Debugw(msg string, args ...interface{}) {
+	if stripped(DebugLevel) {
+		return
+	}
+	lg.zap.Debugw(msg, lg.kvArgs(args)...)
+}
+
+// Fatalz followed by the exit hooks and a call to os.Exit.
+func (lg *Logger) Fatalz(msg string, fields ...Field) {
+	lg.base.Fatal(msg, fields...)
+	lg.exit()
+}
+
+// Errorz logs a message with typed fields using ERROR as log level.
+func (lg *Logger) Errorz(msg string, fields ...Field) {
+	lg.base.Error(msg, fields...)
+}
+
+// Warningz logs a message with typed fields using WARNING as log level.
+func (lg *Logger) Warningz(msg string, fields ...Field) {
+	lg.base.Warn(msg, fields...)
+}
+
+// Infoz logs a message with typed fields using INFO as log level.
+func (lg *Logger) Infoz(msg string, fields ...Field) {
+	if stripped(InfoLevel) {
+		return
+	}
+	lg.base.Info(msg, fields...)
+}
+
+// Debugz logs a message with typed fields using DEBUG as log level.
+func (lg *Logger) Debugz(msg string, fields ...Field) {
+	if stripped(DebugLevel) {
+		return
+	}
+	lg.base.Debug(msg, fields...)
+}
+
+// Check returns a CheckedEntry if logging a message at the given level is
+// enabled.
+func (lg *Logger) Check(lvl Level, msg string) *zapcore.CheckedEntry {
+	return lg.base.Check(zapcore.Level(lvl), msg)
+}
+
+// printArgs returns the arguments of a print-style call as they should be
+// passed to zap. In legacy mode the whole slice is logged as one value,
+// which renders as "[foo bar]".
+func (lg *Logger) printArgs(args []interface{}) []interface{} {
+	if lg.legacyPrint {
+		return []interface{}{args}
+	}
+	return args
+}
+
+// sprintln formats args with fmt.Sprintln semantics, without the trailing
+// newline.
+func sprintln(args []interface{}) string {
+	msg := fmt.Sprintln(args...)
+	return msg[:len(msg)-1]
 }
@@ -0,0 +1,119 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// openFiles returns the number of file descriptors of the process.
+func openFiles(t *testing.T) int {
+	t.Helper()
+	fds, err := ioutil.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skip("no /proc/self/fd:", err)
+	}
+	return len(fds)
+}
+
+type failingMetadata struct{}
+
+func (failingMetadata) Metadata() ([]Field, error) {
+	return nil, errors.New("no metadata")
+}
+
+func TestInitClosesOutputs(t *testing.T) {
+	dir := t.TempDir()
+	key := testKey(t)
+	opts := func() []Option {
+		return []Option{
+			WithEncoding("json"),
+			WithSplitOutput(filepath.Join(dir, "low.log"), filepath.Join(dir, "high.log")),
+			WithAudit(filepath.Join(dir, "audit.log")),
+			WithStream("access", StreamConfig{Paths: []string{filepath.Join(dir, "access.log")}}),
+			WithEncryptedFile(filepath.Join(dir, "secret.log"), &key.PublicKey),
+			WithNonBlocking(AsyncConfig{}),
+		}
+	}
+	initTest(t, false, opts()...)
+	before := openFiles(t)
+	for i := 0; i < 5; i++ {
+		initTest(t, false, opts()...)
+		if err := Reconfigure(Config{Options: opts()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if after := openFiles(t); after > before {
+		t.Errorf("%d files open after reinitializing, %d before", after, before)
+	}
+
+	// A build failing after the outputs are opened closes them.
+	failing := append(opts(), WithMetadata(failingMetadata{}, 0))
+	before = openFiles(t)
+	if err := InitE(false, failing...); err == nil {
+		t.Fatal("Init succeeded with a failing metadata provider")
+	}
+	if after := openFiles(t); after > before {
+		t.Errorf("%d files open after a failed Init, %d before", after, before)
+	}
+}
+
+// sprintfInfof is the former Infof, formatting the message before the level
+// is checked, kept to compare with.
+func sprintfInfof(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	std().zap.Info(msg)
+}
+
+func sprintfDebugf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	std().zap.Debug(msg)
+}
+
+// initBench builds a package logger writing JSON to nowhere.
+func initBench(b *testing.B) {
+	initTest(b, false, WithEncoding("json"), WithSplitOutput(os.DevNull, os.DevNull))
+}
+
+func BenchmarkInfof(b *testing.B) {
+	initBench(b)
+	b.Run("zap", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			Infof("Lease renewed for %s after %d s", "192.0.2.1", i)
+		}
+	})
+	b.Run("sprintf", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			sprintfInfof("Lease renewed for %s after %d s", "192.0.2.1", i)
+		}
+	})
+}
+
+func BenchmarkDebugfDisabled(b *testing.B) {
+	initBench(b)
+	b.Run("zap", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			Debugf("Lease renewed for %s after %d s", "192.0.2.1", i)
+		}
+	})
+	b.Run("sprintf", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			sprintfDebugf("Lease renewed for %s after %d s", "192.0.2.1", i)
+		}
+	})
+}
+
+func BenchmarkInfow(b *testing.B) {
+	initBench(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Infow("Lease renewed", "ip", "192.0.2.1", "after", i)
+	}
+}
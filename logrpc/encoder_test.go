@@ -0,0 +1,109 @@
+package logrpc
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/protobuf/proto"
+
+	log "github.com/ndmsystems/golog"
+	logpb "github.com/ndmsystems/golog/proto"
+)
+
+type stringer string
+
+func (s stringer) String() string { return string(s) }
+
+func TestProtobufEncoderRoundTrip(t *testing.T) {
+	const bad = "a\xffb"
+	ts := time.Unix(1700000000, 5)
+	ent := zapcore.Entry{
+		Level:      zapcore.WarnLevel,
+		Time:       ts,
+		LoggerName: bad,
+		Message:    bad,
+		Caller:     zapcore.NewEntryCaller(0, "pkg/"+bad+".go", 12, true),
+	}
+	fields := []zapcore.Field{
+		zap.String(bad, bad),
+		zap.String("empty", ""),
+		zap.Error(errors.New(bad)),
+		zap.Stringer("stringer", stringer(bad)),
+		zap.Int("int", -3),
+		zap.Uint64("uint", 7),
+		zap.Float64("float", 1.5),
+		zap.Bool("bool", true),
+		zap.Binary("bytes", []byte{0xff, 0}),
+		zap.Duration("duration", time.Second),
+		zap.Time("time", ts),
+		zap.Any("json", map[string]int{"k": 1}),
+	}
+	buf, err := log.NewProtobufEncoder().EncodeEntry(ent, fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	n, k := binary.Uvarint(b)
+	if k <= 0 || int(n) != len(b)-k {
+		t.Fatalf("length %d of %d bytes", n, len(b)-k)
+	}
+	var got logpb.LogEntry
+	if err := proto.Unmarshal(b[k:], &got); err != nil {
+		t.Fatal(err)
+	}
+
+	const fixed = "a�b"
+	if got.Message != fixed || got.Logger != fixed || got.Caller != "pkg/"+fixed+".go:12" {
+		t.Errorf("entry %q, %q, %q", got.Message, got.Logger, got.Caller)
+	}
+	if got.Level != int32(zapcore.WarnLevel) || got.TimeUnixNano != ts.UnixNano() {
+		t.Errorf("level %d, time %d", got.Level, got.TimeUnixNano)
+	}
+	want := map[string]interface{}{
+		fixed:      fixed,
+		"empty":    "",
+		"error":    fixed,
+		"stringer": fixed,
+		"int":      int64(-3),
+		"uint":     uint64(7),
+		"float":    1.5,
+		"bool":     true,
+		"bytes":    "\xff\x00",
+		"duration": int64(time.Second),
+		"time":     ts.UnixNano(),
+		"json":     `{"k":1}`,
+	}
+	if len(got.Fields) != len(want) {
+		t.Errorf("%d fields, want %d", len(got.Fields), len(want))
+	}
+	for _, f := range got.Fields {
+		var v interface{}
+		switch x := f.Value.(type) {
+		case *logpb.Field_StringValue:
+			v = x.StringValue
+		case *logpb.Field_IntValue:
+			v = x.IntValue
+		case *logpb.Field_UintValue:
+			v = x.UintValue
+		case *logpb.Field_DoubleValue:
+			v = x.DoubleValue
+		case *logpb.Field_BoolValue:
+			v = x.BoolValue
+		case *logpb.Field_BytesValue:
+			v = string(x.BytesValue)
+		case *logpb.Field_JsonValue:
+			v = x.JsonValue
+		case *logpb.Field_DurationNanos:
+			v = x.DurationNanos
+		case *logpb.Field_TimeUnixNano:
+			v = x.TimeUnixNano
+		}
+		if v != want[f.Key] {
+			t.Errorf("%q: %#v, want %#v", f.Key, v, want[f.Key])
+		}
+	}
+}
@@ -0,0 +1,27 @@
+module github.com/ndmsystems/golog/logrpc
+
+go 1.17
+
+require (
+	github.com/ndmsystems/golog v0.0.0
+	github.com/ndmsystems/golog/proto v0.0.0
+	go.uber.org/zap v1.23.0
+	go.uber.org/zap v1.23.0
+	google.golang.org/grpc v1.55.0
+	google.golang.org/protobuf v1.30.0
+)
+
+require (
+	github.com/golang/protobuf v1.5.3 // indirect
+	go.uber.org/atomic v1.7.0 // indirect
+	go.uber.org/multierr v1.6.0 // indirect
+	golang.org/x/net v0.17.0 // indirect
+	golang.org/x/sys v0.13.0 // indirect
+	golang.org/x/text v0.13.0 // indirect
+	google.golang.org/genproto v0.0.0-20230410155749-daa745c078e1 // indirect
+)
+
+replace (
+	github.com/ndmsystems/golog => ../
+	github.com/ndmsystems/golog/proto => ../proto
+)
@@ -0,0 +1,163 @@
+// Package logrpc serves the LogService of proto/logservice.proto, so that
+// a management plane can collect the logs of an agent and change its
+// levels remotely:
+//
+//	live := logrpc.NewLiveLog(1000)
+//	log.Init(false, log.WithCore(live.Core()))
+//
+//	s := grpc.NewServer()
+//	logpb.RegisterLogServiceServer(s, logrpc.NewServer(live))
+//
+// It is a module of its own, like the generated logpb package, so that
+// programs not serving it do not depend on gRPC and protobuf.
+package logrpc
+
+import (
+	"context"
+	"encoding/binary"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	log "github.com/ndmsystems/golog"
+	logpb "github.com/ndmsystems/golog/proto"
+)
+
+// NewLiveLog returns a log.LiveLog keeping the last size entries in the
+// encoding TailLogs sends.
+func NewLiveLog(size int) *log.LiveLog {
+	return log.NewLiveLogEncoder(size, log.NewProtobufEncoder())
+}
+
+// Server implements logpb.LogServiceServer.
+type Server struct {
+	logpb.UnimplementedLogServiceServer
+	live    *log.LiveLog
+	skipped uint64 // atomic
+}
+
+// NewServer returns a server sending the entries of live, which must be
+// built with NewLiveLog. TailLogs fails with Unavailable if live is nil.
+func NewServer(live *log.LiveLog) *Server {
+	return &Server{live: live}
+}
+
+// TailLogs implements logpb.LogServiceServer.
+func (s *Server) TailLogs(req *logpb.TailLogsRequest, stream logpb.LogService_TailLogsServer) error {
+	if s.live == nil {
+		return status.Error(codes.Unavailable, "no entries are kept")
+	}
+	match := func(e log.LiveEntry) bool {
+		return int32(e.Level) >= req.MinLevel &&
+			(req.Logger == "" || e.Name == req.Logger || strings.HasPrefix(e.Name, req.Logger+"."))
+	}
+
+	recent, entries, cancel := s.live.Subscribe(0)
+	defer cancel()
+	matched := recent[:0]
+	for _, e := range recent {
+		if match(e) {
+			matched = append(matched, e)
+		}
+	}
+	if n := int(req.Recent); n > 0 && n < len(matched) {
+		matched = matched[len(matched)-n:]
+	}
+	for _, e := range matched {
+		if err := s.send(stream, e); err != nil {
+			return err
+		}
+	}
+	if !req.Follow {
+		return nil
+	}
+
+	for {
+		select {
+		case e := <-entries:
+			if !match(e) {
+				continue
+			}
+			if err := s.send(stream, e); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// send decodes the entry, a length-delimited LogEntry, and sends it. An
+// entry that cannot be decoded is skipped, so that it does not end every
+// later TailLogs too, and counted, see Skipped.
+func (s *Server) send(stream logpb.LogService_TailLogsServer, e log.LiveEntry) error {
+	size, n := binary.Uvarint(e.Data)
+	var entry logpb.LogEntry
+	if n <= 0 || uint64(len(e.Data)-n) < size || proto.Unmarshal(e.Data[n:n+int(size)], &entry) != nil {
+		atomic.AddUint64(&s.skipped, 1)
+		return nil
+	}
+	return stream.Send(&entry)
+}
+
+// Skipped returns the number of entries TailLogs skipped because they were
+// not in the encoding of NewLiveLog.
+func (s *Server) Skipped() uint64 {
+	return atomic.LoadUint64(&s.skipped)
+}
+
+// SetLevel implements logpb.LogServiceServer. It fails with NotFound for
+// loggers not registered in the process, so that remote names do not
+// register loggers.
+func (s *Server) SetLevel(_ context.Context, req *logpb.SetLevelRequest) (*logpb.SetLevelResponse, error) {
+	if req.Logger != "" && !registered(req.Logger) {
+		return nil, status.Errorf(codes.NotFound, "no logger %q", req.Logger)
+	}
+	if req.Level == "" {
+		if req.Logger == "" {
+			return nil, status.Error(codes.InvalidArgument, "missing level")
+		}
+		log.ResetLoggerLevel(req.Logger)
+		return &logpb.SetLevelResponse{Level: log.GetLogger(req.Logger).GetLevel().String()}, nil
+	}
+	lvl, err := log.ParseLevel(req.Level)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if req.Logger == "" {
+		log.SetLevel(lvl)
+		return &logpb.SetLevelResponse{Level: log.GetLevel().String()}, nil
+	}
+	log.SetLoggerLevel(req.Logger, lvl)
+	return &logpb.SetLevelResponse{Level: log.GetLogger(req.Logger).GetLevel().String()}, nil
+}
+
+// registered reports whether name is in log.LoggerNames.
+func registered(name string) bool {
+	names := log.LoggerNames()
+	i := sort.SearchStrings(names, name)
+	return i < len(names) && names[i] == name
+}
+
+// GetStats implements logpb.LogServiceServer.
+func (s *Server) GetStats(context.Context, *logpb.GetStatsRequest) (*logpb.Stats, error) {
+	st := log.ReadStats()
+	out := &logpb.Stats{
+		Entries:    st.Entries,
+		Dropped:    st.Dropped,
+		Sampled:    st.Sampled,
+		DiskGuard:  st.DiskGuard,
+		SinkErrors: st.SinkErrors,
+		Queued:     int64(st.Queued),
+		Level:      log.GetLevel().String(),
+		Loggers:    make(map[string]string),
+	}
+	for _, name := range log.LoggerNames() {
+		out.Loggers[name] = log.GetLogger(name).GetLevel().String()
+	}
+	return out, nil
+}
@@ -0,0 +1,97 @@
+package logrpc
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	log "github.com/ndmsystems/golog"
+	logpb "github.com/ndmsystems/golog/proto"
+)
+
+// garbling encodes the entries with the message "garbled" as bytes that
+// are no LogEntry.
+type garbling struct {
+	zapcore.Encoder
+}
+
+func (e garbling) Clone() zapcore.Encoder {
+	return garbling{e.Encoder.Clone()}
+}
+
+func (e garbling) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	buf, err := e.Encoder.EncodeEntry(ent, fields)
+	if err == nil && ent.Message == "garbled" {
+		buf.Reset()
+		buf.AppendString("\x05\xff\xff\xff\xff\xff")
+	}
+	return buf, err
+}
+
+// tailStream is a TailLogs stream keeping the entries sent.
+type tailStream struct {
+	grpc.ServerStream
+	sent []*logpb.LogEntry
+}
+
+func (s *tailStream) Context() context.Context {
+	return context.Background()
+}
+
+func (s *tailStream) Send(e *logpb.LogEntry) error {
+	s.sent = append(s.sent, e)
+	return nil
+}
+
+func TestTailLogsSkipsUndecodable(t *testing.T) {
+	live := log.NewLiveLogEncoder(10, garbling{log.NewProtobufEncoder()})
+	lg := zap.New(live.Core())
+	lg.Info("before")
+	lg.Info("garbled")
+	lg.Info("after")
+
+	s := NewServer(live)
+	var stream tailStream
+	if err := s.TailLogs(&logpb.TailLogsRequest{}, &stream); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range stream.sent {
+		got = append(got, e.Message)
+	}
+	if len(got) != 2 || got[0] != "before" || got[1] != "after" {
+		t.Errorf("sent %q, want [before after]", got)
+	}
+	if n := s.Skipped(); n != 1 {
+		t.Errorf("skipped %d entries, want 1", n)
+	}
+}
+
+func TestSetLevelUnknownLogger(t *testing.T) {
+	s := NewServer(nil)
+	_, err := s.SetLevel(context.Background(), &logpb.SetLevelRequest{Logger: "remote.name", Level: "debug"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("SetLevel: %v, want NotFound", err)
+	}
+	for _, name := range log.LoggerNames() {
+		if name == "remote.name" {
+			t.Error("SetLevel registered the logger")
+		}
+	}
+
+	log.GetLogger("rpc.test")
+	resp, err := s.SetLevel(context.Background(), &logpb.SetLevelRequest{Logger: "rpc.test", Level: "debug"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.ResetLoggerLevel("rpc.test")
+	if resp.Level != log.DebugLevel.String() {
+		t.Errorf("level %q, want %q", resp.Level, log.DebugLevel.String())
+	}
+}
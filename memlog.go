@@ -0,0 +1,80 @@
+package log
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// MemoryLog is a zapcore.WriteSyncer keeping the last entries written to
+// it in RAM, for devices where logs should not wear out the flash. The
+// entries are read on demand with DumpTo or over HTTP with Handler.
+//
+//	mem := log.NewMemoryLog(2000)
+//	log.Init(false, log.WithSink(mem))
+//	http.Handle("/debug/log", mem.Handler())
+type MemoryLog struct {
+	ring *ringBuffer
+}
+
+// NewMemoryLog returns a MemoryLog keeping the last size entries.
+func NewMemoryLog(size int) *MemoryLog {
+	if size <= 0 {
+		size = 1000
+	}
+	return &MemoryLog{ring: newRingBuffer(size)}
+}
+
+// Write stores a copy of p, evicting the oldest entry when full.
+func (m *MemoryLog) Write(p []byte) (int, error) {
+	m.ring.add(p)
+	return len(p), nil
+}
+
+// Sync does nothing.
+func (m *MemoryLog) Sync() error {
+	return nil
+}
+
+// DumpTo writes the stored entries to w, oldest first.
+func (m *MemoryLog) DumpTo(w io.Writer) (int64, error) {
+	return dumpEntries(w, m.ring.snapshot())
+}
+
+// Reset drops the stored entries.
+func (m *MemoryLog) Reset() {
+	m.ring.reset()
+}
+
+// Handler returns an HTTP handler serving the stored entries, oldest
+// first. The "n" query parameter limits the response to the last n
+// entries.
+func (m *MemoryLog) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entries := m.ring.snapshot()
+		if s := r.URL.Query().Get("n"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 0 {
+				http.Error(w, "invalid n", http.StatusBadRequest)
+				return
+			}
+			if n < len(entries) {
+				entries = entries[len(entries)-n:]
+			}
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = dumpEntries(w, entries)
+	})
+}
+
+func dumpEntries(w io.Writer, entries [][]byte) (int64, error) {
+	var total int64
+	for _, p := range entries {
+		n, err := w.Write(p)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,148 @@
+package log
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// MetadataProvider supplies fields describing where the program runs, added
+// to every entry by WithMetadata.
+type MetadataProvider interface {
+	Metadata() ([]Field, error)
+}
+
+// WithMetadata adds the fields of p to every entry. They are read once by
+// Init and then every refresh (1 minute if zero) in the background, so
+// that a changed address or an upgraded firmware shows up without a
+// restart. If p fails, the last fields it returned are kept; Init fails if
+// the first call does.
+func WithMetadata(p MetadataProvider, refresh time.Duration) Option {
+	if refresh <= 0 {
+		refresh = time.Minute
+	}
+	var current atomic.Value // []Field
+	return func(o *options) {
+		o.metadata = append(o.metadata, metadataSource{p, refresh, &current})
+		withEnricher(func(_ zapcore.Entry, fields []zapcore.Field) []zapcore.Field {
+			md, _ := current.Load().([]Field)
+			return append(fields, md...)
+		})(o)
+	}
+}
+
+// metadataSource is a provider registered with WithMetadata.
+type metadataSource struct {
+	p       MetadataProvider
+	refresh time.Duration
+	current *atomic.Value
+}
+
+// start reads the fields and refreshes them until the returned function is
+// called.
+func (s metadataSource) start() (func(), error) {
+	md, err := s.p.Metadata()
+	if err != nil {
+		return nil, err
+	}
+	s.current.Store(md)
+	done := make(chan struct{})
+	go func() {
+		t := time.NewTicker(s.refresh)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+				if md, err := s.p.Metadata(); err == nil {
+					s.current.Store(md)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }, nil
+}
+
+// HostMetadata is a MetadataProvider describing the host: its name
+// ("host"), first non-loopback IP address ("host_ip") and kernel release
+// ("kernel"), and on devices that have them the firmware version
+// ("firmware") and model ("model"). Values that cannot be determined are
+// left out.
+type HostMetadata struct {
+	// FirmwareFile and ModelFile are read, trimmed, for the firmware
+	// version and the model of the device.
+	FirmwareFile string
+	ModelFile    string
+}
+
+// Metadata implements MetadataProvider.
+func (h HostMetadata) Metadata() ([]Field, error) {
+	var fields []Field
+	if name, err := os.Hostname(); err == nil {
+		fields = append(fields, zap.String("host", name))
+	}
+	if ip := hostIP(); ip != "" {
+		fields = append(fields, zap.String("host_ip", ip))
+	}
+	if release := readTrimmed("/proc/sys/kernel/osrelease"); release != "" {
+		fields = append(fields, zap.String("kernel", release))
+	}
+	if h.FirmwareFile != "" {
+		if v := readTrimmed(h.FirmwareFile); v != "" {
+			fields = append(fields, zap.String("firmware", v))
+		}
+	}
+	if h.ModelFile != "" {
+		if v := readTrimmed(h.ModelFile); v != "" {
+			fields = append(fields, zap.String("model", v))
+		}
+	}
+	return fields, nil
+}
+
+// hostIP returns the first non-loopback address of an interface that is
+// up, preferring IPv4.
+func hostIP() string {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return ""
+	}
+	var v6 string
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipnet, ok := addr.(*net.IPNet)
+			if !ok || ipnet.IP.IsLinkLocalUnicast() {
+				continue
+			}
+			if ipnet.IP.To4() != nil {
+				return ipnet.IP.String()
+			}
+			if v6 == "" {
+				v6 = ipnet.IP.String()
+			}
+		}
+	}
+	return v6
+}
+
+func readTrimmed(path string) string {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
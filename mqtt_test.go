@@ -0,0 +1,28 @@
+package log
+
+import (
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMQTTCloseTwice(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	c, err := NewMQTTCore(MQTTConfig{Broker: "tcp://" + addr, FlushTimeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != os.ErrClosed {
+		t.Errorf("second Close: %v, want %v", err, os.ErrClosed)
+	}
+}
@@ -0,0 +1,531 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+func init() {
+	registerEncoder("msgpack", func(zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		return NewMsgpackEncoder(), nil
+	})
+}
+
+// msgpackTimestamp is the MessagePack extension type of timestamps, -1.
+const msgpackTimestamp = 0xff
+
+type msgpackEncoder struct {
+	kvEncoder
+}
+
+// NewMsgpackEncoder returns an encoder writing each entry as a MessagePack
+// map with the same keys as the JSON encoder, for links where bandwidth
+// matters. Entries are self-delimiting, so they are simply concatenated.
+// MsgpackToJSON converts a stream of them back to JSON lines.
+func NewMsgpackEncoder() zapcore.Encoder {
+	return &msgpackEncoder{}
+}
+
+func (e *msgpackEncoder) Clone() zapcore.Encoder {
+	return &msgpackEncoder{kvEncoder: e.kvEncoder.clone()}
+}
+
+func (e *msgpackEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	all := e.addFields(fields)
+	n := 3 + len(all)
+	if ent.LoggerName != "" {
+		n++
+	}
+	if ent.Caller.Defined {
+		n++
+	}
+	if ent.Stack != "" {
+		n++
+	}
+
+	buf := bufferPool.Get()
+	appendMsgpackHeader(buf, 0x80, 0xde, n)
+	appendMsgpackString(buf, "ts")
+	appendMsgpack(buf, ent.Time)
+	appendMsgpackString(buf, "level")
+	appendMsgpackString(buf, Level(ent.Level).CapitalString())
+	if ent.LoggerName != "" {
+		appendMsgpackString(buf, "logger")
+		appendMsgpackString(buf, ent.LoggerName)
+	}
+	if ent.Caller.Defined {
+		appendMsgpackString(buf, "caller")
+		appendMsgpackString(buf, ent.Caller.TrimmedPath())
+	}
+	appendMsgpackString(buf, "msg")
+	appendMsgpackString(buf, ent.Message)
+	for _, f := range all {
+		appendMsgpackString(buf, f.key)
+		appendMsgpack(buf, f.val)
+	}
+	if ent.Stack != "" {
+		appendMsgpackString(buf, "stacktrace")
+		appendMsgpackString(buf, ent.Stack)
+	}
+	return buf, nil
+}
+
+// appendMsgpackHeader writes the header of a map (fix 0x80, 16-bit 0xde) or
+// an array (fix 0x90, 16-bit 0xdc) of n items. The 32-bit form follows the
+// 16-bit one.
+func appendMsgpackHeader(buf *buffer.Buffer, fix, long byte, n int) {
+	switch {
+	case n < 16:
+		buf.AppendByte(fix | byte(n))
+	case n <= math.MaxUint16:
+		buf.AppendByte(long)
+		appendUint16(buf, uint16(n))
+	default:
+		buf.AppendByte(long + 1)
+		appendUint32(buf, uint32(n))
+	}
+}
+
+func appendMsgpackString(buf *buffer.Buffer, s string) {
+	switch n := len(s); {
+	case n < 32:
+		buf.AppendByte(0xa0 | byte(n))
+	case n <= math.MaxUint8:
+		buf.AppendByte(0xd9)
+		buf.AppendByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.AppendByte(0xda)
+		appendUint16(buf, uint16(n))
+	default:
+		buf.AppendByte(0xdb)
+		appendUint32(buf, uint32(n))
+	}
+	buf.AppendString(s)
+}
+
+func appendMsgpackInt(buf *buffer.Buffer, v int64) {
+	switch {
+	case v >= 0:
+		appendMsgpackUint(buf, uint64(v))
+	case v >= -32:
+		buf.AppendByte(byte(v))
+	case v >= math.MinInt8:
+		buf.AppendByte(0xd0)
+		buf.AppendByte(byte(v))
+	case v >= math.MinInt16:
+		buf.AppendByte(0xd1)
+		appendUint16(buf, uint16(v))
+	case v >= math.MinInt32:
+		buf.AppendByte(0xd2)
+		appendUint32(buf, uint32(v))
+	default:
+		buf.AppendByte(0xd3)
+		appendUint64(buf, uint64(v))
+	}
+}
+
+func appendMsgpackUint(buf *buffer.Buffer, v uint64) {
+	switch {
+	case v < 128:
+		buf.AppendByte(byte(v))
+	case v <= math.MaxUint8:
+		buf.AppendByte(0xcc)
+		buf.AppendByte(byte(v))
+	case v <= math.MaxUint16:
+		buf.AppendByte(0xcd)
+		appendUint16(buf, uint16(v))
+	case v <= math.MaxUint32:
+		buf.AppendByte(0xce)
+		appendUint32(buf, uint32(v))
+	default:
+		buf.AppendByte(0xcf)
+		appendUint64(buf, v)
+	}
+}
+
+// appendMsgpack writes a field value. Durations are written in
+// nanoseconds; types without a MessagePack counterpart go through their
+// JSON form.
+func appendMsgpack(buf *buffer.Buffer, v interface{}) {
+	switch v := v.(type) {
+	case nil:
+		buf.AppendByte(0xc0)
+	case bool:
+		if v {
+			buf.AppendByte(0xc3)
+		} else {
+			buf.AppendByte(0xc2)
+		}
+	case string:
+		appendMsgpackString(buf, v)
+	case []byte:
+		switch n := len(v); {
+		case n <= math.MaxUint8:
+			buf.AppendByte(0xc4)
+			buf.AppendByte(byte(n))
+		case n <= math.MaxUint16:
+			buf.AppendByte(0xc5)
+			appendUint16(buf, uint16(n))
+		default:
+			buf.AppendByte(0xc6)
+			appendUint32(buf, uint32(n))
+		}
+		buf.Write(v)
+	case int:
+		appendMsgpackInt(buf, int64(v))
+	case int8:
+		appendMsgpackInt(buf, int64(v))
+	case int16:
+		appendMsgpackInt(buf, int64(v))
+	case int32:
+		appendMsgpackInt(buf, int64(v))
+	case int64:
+		appendMsgpackInt(buf, v)
+	case uint:
+		appendMsgpackUint(buf, uint64(v))
+	case uint8:
+		appendMsgpackUint(buf, uint64(v))
+	case uint16:
+		appendMsgpackUint(buf, uint64(v))
+	case uint32:
+		appendMsgpackUint(buf, uint64(v))
+	case uint64:
+		appendMsgpackUint(buf, v)
+	case uintptr:
+		appendMsgpackUint(buf, uint64(v))
+	case float32:
+		buf.AppendByte(0xca)
+		appendUint32(buf, math.Float32bits(v))
+	case float64:
+		buf.AppendByte(0xcb)
+		appendUint64(buf, math.Float64bits(v))
+	case time.Duration:
+		appendMsgpackInt(buf, int64(v))
+	case ByteSize:
+		appendMsgpackInt(buf, int64(v))
+	case time.Time:
+		// Timestamp 96: ext8 with nanoseconds and seconds.
+		buf.AppendByte(0xc7)
+		buf.AppendByte(12)
+		buf.AppendByte(msgpackTimestamp)
+		appendUint32(buf, uint32(v.Nanosecond()))
+		appendUint64(buf, uint64(v.Unix()))
+	case map[string]interface{}:
+		appendMsgpackHeader(buf, 0x80, 0xde, len(v))
+		for k, item := range v {
+			appendMsgpackString(buf, k)
+			appendMsgpack(buf, item)
+		}
+	case []interface{}:
+		appendMsgpackHeader(buf, 0x90, 0xdc, len(v))
+		for _, item := range v {
+			appendMsgpack(buf, item)
+		}
+	case error:
+		appendMsgpackString(buf, v.Error())
+	case fmt.Stringer:
+		appendMsgpackString(buf, v.String())
+	default:
+		var generic interface{}
+		b, err := json.Marshal(v)
+		if err == nil {
+			d := json.NewDecoder(bytes.NewReader(b))
+			d.UseNumber()
+			err = d.Decode(&generic)
+		}
+		if err != nil {
+			appendMsgpackString(buf, fmt.Sprint(v))
+			return
+		}
+		appendMsgpack(buf, jsonNumbers(generic))
+	}
+}
+
+// jsonNumbers turns the json.Number values of a decoded document into
+// integers or floats.
+func jsonNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = jsonNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = jsonNumbers(item)
+		}
+	}
+	return v
+}
+
+func appendUint16(buf *buffer.Buffer, v uint16) {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], v)
+	buf.Write(b[:])
+}
+
+func appendUint32(buf *buffer.Buffer, v uint32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	buf.Write(b[:])
+}
+
+func appendUint64(buf *buffer.Buffer, v uint64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	buf.Write(b[:])
+}
+
+// MsgpackToJSON converts a stream of entries written by the MessagePack
+// encoder into JSON lines, keeping the key order. Timestamps become
+// RFC 3339 strings and binary values base64 strings.
+func MsgpackToJSON(w io.Writer, r io.Reader) error {
+	br := bufio.NewReader(r)
+	out := bufio.NewWriter(w)
+	for {
+		if _, err := br.Peek(1); err == io.EOF {
+			return out.Flush()
+		}
+		buf := bufferPool.Get()
+		err := decodeMsgpack(br, buf)
+		if err == nil {
+			buf.AppendByte('\n')
+			_, err = out.Write(buf.Bytes())
+		}
+		buf.Free()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+	}
+}
+
+var errMsgpackKey = errors.New("log: MessagePack map key is not a string")
+
+// decodeMsgpack reads one value from r and writes it to buf as JSON.
+func decodeMsgpack(r *bufio.Reader, buf *buffer.Buffer) error {
+	b, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	switch {
+	case b <= 0x7f:
+		buf.AppendInt(int64(b))
+		return nil
+	case b >= 0xe0:
+		buf.AppendInt(int64(int8(b)))
+		return nil
+	case b&0xf0 == 0x80:
+		return decodeMsgpackMap(r, buf, int(b&0x0f))
+	case b&0xf0 == 0x90:
+		return decodeMsgpackArray(r, buf, int(b&0x0f))
+	case b&0xe0 == 0xa0:
+		return decodeMsgpackString(r, buf, int(b&0x1f))
+	}
+
+	switch b {
+	case 0xc0:
+		buf.AppendString("null")
+	case 0xc2:
+		buf.AppendBool(false)
+	case 0xc3:
+		buf.AppendBool(true)
+	case 0xc4, 0xc5, 0xc6:
+		n, err := readMsgpackLen(r, b-0xc4)
+		if err != nil {
+			return err
+		}
+		data := make([]byte, n)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return err
+		}
+		buf.AppendString(strconv.Quote(base64.StdEncoding.EncodeToString(data)))
+	case 0xc7, 0xc8, 0xc9:
+		n, err := readMsgpackLen(r, b-0xc7)
+		if err != nil {
+			return err
+		}
+		return decodeMsgpackExt(r, buf, n)
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return decodeMsgpackExt(r, buf, 1<<(b-0xd4))
+	case 0xca:
+		v, err := readUint(r, 4)
+		if err != nil {
+			return err
+		}
+		appendJSONFloat(buf, float64(math.Float32frombits(uint32(v))), 32)
+	case 0xcb:
+		v, err := readUint(r, 8)
+		if err != nil {
+			return err
+		}
+		appendJSONFloat(buf, math.Float64frombits(v), 64)
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		v, err := readUint(r, 1<<(b-0xcc))
+		if err != nil {
+			return err
+		}
+		buf.AppendUint(v)
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (b - 0xd0)
+		v, err := readUint(r, size)
+		if err != nil {
+			return err
+		}
+		// Sign-extend from the encoded width.
+		shift := 64 - 8*uint(size)
+		buf.AppendInt(int64(v<<shift) >> shift)
+	case 0xd9, 0xda, 0xdb:
+		n, err := readMsgpackLen(r, b-0xd9)
+		if err != nil {
+			return err
+		}
+		return decodeMsgpackString(r, buf, n)
+	case 0xdc, 0xdd:
+		n, err := readMsgpackLen(r, b-0xdc+1)
+		if err != nil {
+			return err
+		}
+		return decodeMsgpackArray(r, buf, n)
+	case 0xde, 0xdf:
+		n, err := readMsgpackLen(r, b-0xde+1)
+		if err != nil {
+			return err
+		}
+		return decodeMsgpackMap(r, buf, n)
+	default:
+		return fmt.Errorf("log: invalid MessagePack type byte 0x%02x", b)
+	}
+	return nil
+}
+
+// appendJSONFloat writes f as a JSON number, or as the strings "NaN",
+// "+Inf" and "-Inf" like the JSON encoder of zap, since JSON has no
+// numbers for them.
+func appendJSONFloat(buf *buffer.Buffer, f float64, bitSize int) {
+	switch {
+	case math.IsNaN(f):
+		buf.AppendString(`"NaN"`)
+	case math.IsInf(f, 1):
+		buf.AppendString(`"+Inf"`)
+	case math.IsInf(f, -1):
+		buf.AppendString(`"-Inf"`)
+	default:
+		buf.AppendFloat(f, bitSize)
+	}
+}
+
+// readMsgpackLen reads a length of 1, 2 or 4 bytes for class 0, 1 or 2.
+func readMsgpackLen(r *bufio.Reader, class byte) (int, error) {
+	v, err := readUint(r, 1<<class)
+	return int(v), err
+}
+
+func readUint(r *bufio.Reader, size int) (uint64, error) {
+	var b [8]byte
+	if _, err := io.ReadFull(r, b[8-size:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(b[:]), nil
+}
+
+func decodeMsgpackString(r *bufio.Reader, buf *buffer.Buffer, n int) error {
+	s := make([]byte, n)
+	if _, err := io.ReadFull(r, s); err != nil {
+		return err
+	}
+	q, _ := json.Marshal(string(s))
+	buf.Write(q)
+	return nil
+}
+
+func decodeMsgpackArray(r *bufio.Reader, buf *buffer.Buffer, n int) error {
+	buf.AppendByte('[')
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			buf.AppendByte(',')
+		}
+		if err := decodeMsgpack(r, buf); err != nil {
+			return err
+		}
+	}
+	buf.AppendByte(']')
+	return nil
+}
+
+func decodeMsgpackMap(r *bufio.Reader, buf *buffer.Buffer, n int) error {
+	buf.AppendByte('{')
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			buf.AppendByte(',')
+		}
+		if b, err := r.Peek(1); err == nil && !isMsgpackString(b[0]) {
+			return errMsgpackKey
+		}
+		if err := decodeMsgpack(r, buf); err != nil {
+			return err
+		}
+		buf.AppendByte(':')
+		if err := decodeMsgpack(r, buf); err != nil {
+			return err
+		}
+	}
+	buf.AppendByte('}')
+	return nil
+}
+
+func isMsgpackString(b byte) bool {
+	return b&0xe0 == 0xa0 || b == 0xd9 || b == 0xda || b == 0xdb
+}
+
+// decodeMsgpackExt decodes an extension value of n bytes. Timestamps are
+// written as RFC 3339 strings, other extensions as null.
+func decodeMsgpackExt(r *bufio.Reader, buf *buffer.Buffer, n int) error {
+	typ, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return err
+	}
+	if typ != msgpackTimestamp {
+		buf.AppendString("null")
+		return nil
+	}
+	var t time.Time
+	switch n {
+	case 4:
+		t = time.Unix(int64(binary.BigEndian.Uint32(data)), 0)
+	case 8:
+		v := binary.BigEndian.Uint64(data)
+		t = time.Unix(int64(v&(1<<34-1)), int64(v>>34))
+	case 12:
+		t = time.Unix(int64(binary.BigEndian.Uint64(data[4:])), int64(binary.BigEndian.Uint32(data)))
+	default:
+		return fmt.Errorf("log: invalid MessagePack timestamp length %d", n)
+	}
+	buf.AppendByte('"')
+	buf.AppendTime(t.UTC(), time.RFC3339Nano)
+	buf.AppendByte('"')
+	return nil
+}
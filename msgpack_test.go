@@ -0,0 +1,61 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestMsgpackToJSON(t *testing.T) {
+	ts := time.Date(2023, 5, 1, 12, 0, 0, 123456789, time.UTC)
+	fields := []zapcore.Field{
+		zap.Time("time", ts),
+		zap.Int("fixneg", -5),
+		zap.Int("int8", -100),
+		zap.Int("int16", -1000),
+		zap.Int("int32", -100000),
+		zap.Int64("int64", math.MinInt64),
+		zap.Uint64("uint64", math.MaxUint64),
+		zap.Float64("float", 1.5),
+		zap.Float32("float32", 0.25),
+		zap.Float64("nan", math.NaN()),
+		zap.Float64("inf", math.Inf(1)),
+		zap.Float32("neginf", float32(math.Inf(-1))),
+		zap.Duration("duration", time.Second),
+		zap.Binary("binary", []byte{0, 1, 0xff}),
+		zap.Any("nested", map[string]interface{}{"a": map[string]interface{}{"b": []interface{}{1, "x", nil, true}}}),
+		zap.String("long", strings.Repeat("s", 300)),
+	}
+	ent := zapcore.Entry{Level: zapcore.InfoLevel, Time: ts, Message: "msg"}
+	buf, err := NewMsgpackEncoder().EncodeEntry(ent, fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := MsgpackToJSON(&out, bytes.NewReader(append(buf.Bytes(), buf.Bytes()...))); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 2 || lines[0] != lines[1] {
+		t.Fatalf("converted two entries into:\n%s", out.String())
+	}
+
+	want := `{"ts":"2023-05-01T12:00:00.123456789Z","level":"INFO","msg":"msg",` +
+		`"time":"2023-05-01T12:00:00.123456789Z","fixneg":-5,"int8":-100,"int16":-1000,"int32":-100000,` +
+		`"int64":-9223372036854775808,"uint64":18446744073709551615,"float":1.5,"float32":0.25,` +
+		`"nan":"NaN","inf":"+Inf","neginf":"-Inf","duration":1000000000,"binary":"AAH/",` +
+		`"nested":{"a":{"b":[1,"x",null,true]}},"long":"` + strings.Repeat("s", 300) + `"}`
+	if lines[0] != want {
+		t.Errorf("converted into\n%s\nwant\n%s", lines[0], want)
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &v); err != nil {
+		t.Errorf("invalid JSON: %v", err)
+	}
+}
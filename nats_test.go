@@ -0,0 +1,126 @@
+package log
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeJetStream is a NATS server acknowledging every publish, after
+// sending acks of publishes that never happened to the same inbox.
+type fakeJetStream struct {
+	ln net.Listener
+
+	mu   sync.Mutex
+	pubs []string
+}
+
+func newFakeJetStream(t *testing.T) *fakeJetStream {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeJetStream{ln: ln}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeJetStream) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	fmt.Fprintf(conn, "INFO {}\r\n")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		args := strings.Fields(line)
+		switch {
+		case len(args) == 0:
+		case args[0] == "PING":
+			fmt.Fprintf(conn, "PONG\r\n")
+		case args[0] == "PUB" && len(args) == 4:
+			n, _ := strconv.Atoi(args[3])
+			payload := make([]byte, n+2)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return
+			}
+			f.mu.Lock()
+			f.pubs = append(f.pubs, string(payload[:n]))
+			f.mu.Unlock()
+			reply := args[2]
+			inbox := reply[:strings.LastIndexByte(reply, '.')]
+			ack := `{"stream":"logs","seq":1}`
+			var b strings.Builder
+			for i := 0; i < 8; i++ {
+				fmt.Fprintf(&b, "MSG %s.x%d 1 %d\r\n%s\r\n", inbox, i, len(ack), ack)
+			}
+			fmt.Fprintf(&b, "MSG %s 1 %d\r\n%s\r\n", reply, len(ack), ack)
+			io.WriteString(conn, b.String())
+		}
+	}
+}
+
+func (f *fakeJetStream) published() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.pubs...)
+}
+
+func TestNATSJetStreamIgnoresStaleAcks(t *testing.T) {
+	f := newFakeJetStream(t)
+	s, err := NewNATSSink(NATSConfig{
+		URL:           "nats://" + f.ln.Addr().String(),
+		Subject:       "logs",
+		JetStream:     true,
+		AckTimeout:    200 * time.Millisecond,
+		ReconnectWait: 10 * time.Millisecond,
+		FlushTimeout:  2 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for i := 0; i < 3; i++ {
+		s.Write([]byte("entry " + strconv.Itoa(i) + "\n"))
+	}
+	if err := s.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"entry 0", "entry 1", "entry 2"}
+	if got := f.published(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("published %q, want %q", got, want)
+	}
+}
+
+func TestNATSCloseTwice(t *testing.T) {
+	f := newFakeJetStream(t)
+	s, err := NewNATSSink(NATSConfig{URL: "nats://" + f.ln.Addr().String(), Subject: "logs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != os.ErrClosed {
+		t.Errorf("second Close: %v, want %v", err, os.ErrClosed)
+	}
+}
@@ -0,0 +1,184 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// telegramMaxText is the longest message the Telegram Bot API accepts.
+const telegramMaxText = 4096
+
+// NotifierConfig describes the Fatal and Panic notifier. Either or both of
+// Telegram and Slack can be configured.
+type NotifierConfig struct {
+	// TelegramToken and TelegramChatID select the bot and the chat the
+	// messages are sent to.
+	TelegramToken  string
+	TelegramChatID string
+	// TelegramAPI is the Bot API server. Defaults to
+	// "https://api.telegram.org".
+	TelegramAPI string
+	// SlackWebhook is the URL of a Slack incoming webhook.
+	SlackWebhook string
+	// Timeout bounds sending a notification, which delays the exit of the
+	// process. Defaults to 3 seconds. On Fatal it is further bounded by
+	// the exit timeout, see WithExitTimeout.
+	Timeout    time.Duration
+	HTTPClient *http.Client
+}
+
+type notifierCore struct {
+	cfg     NotifierConfig
+	host    string
+	program string
+	fields  []zapcore.Field
+
+	mu *sync.Mutex
+}
+
+// NewNotifierCore returns a core sending the Fatal and Panic entries,
+// with their fields and the host, to a Telegram chat or a Slack channel
+// before the process exits, so that crashes of unattended daemons are
+// noticed. Attach it with WithCore. Sending is synchronous and bounded by
+// the timeout; a notification that cannot be sent in time is lost.
+func NewNotifierCore(cfg NotifierConfig) (zapcore.Core, error) {
+	if (cfg.TelegramToken == "" || cfg.TelegramChatID == "") && cfg.SlackWebhook == "" {
+		return nil, errors.New("log: a Telegram bot and chat or a Slack webhook is required")
+	}
+	if cfg.TelegramAPI == "" {
+		cfg.TelegramAPI = "https://api.telegram.org"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 3 * time.Second
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	host, _ := os.Hostname()
+	return &notifierCore{
+		cfg:     cfg,
+		host:    host,
+		program: filepath.Base(os.Args[0]),
+		mu:      new(sync.Mutex),
+	}, nil
+}
+
+func (c *notifierCore) Enabled(lvl zapcore.Level) bool {
+	return lvl >= zapcore.PanicLevel
+}
+
+func (c *notifierCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = append(c.fields[:len(c.fields):len(c.fields)], fields...)
+	return &clone
+}
+
+func (c *notifierCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *notifierCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	text := c.text(ent, append(c.fields[:len(c.fields):len(c.fields)], fields...))
+
+	deadline := time.Now().Add(c.cfg.Timeout)
+	if d, ok := exitAt.Load().(time.Time); ok && !d.IsZero() && d.Before(deadline) {
+		deadline = d
+	}
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	// Entries logged concurrently while crashing are sent one at a time.
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var err error
+	if c.cfg.TelegramToken != "" && c.cfg.TelegramChatID != "" {
+		url := strings.TrimRight(c.cfg.TelegramAPI, "/") + "/bot" + c.cfg.TelegramToken + "/sendMessage"
+		if len(text) > telegramMaxText {
+			text = text[:telegramMaxText]
+		}
+		err = c.post(ctx, url, map[string]string{
+			"chat_id": c.cfg.TelegramChatID,
+			"text":    text,
+		})
+	}
+	if c.cfg.SlackWebhook != "" {
+		if serr := c.post(ctx, c.cfg.SlackWebhook, map[string]string{"text": text}); err == nil {
+			err = serr
+		}
+	}
+	return err
+}
+
+func (c *notifierCore) Sync() error {
+	return nil
+}
+
+// text renders an entry as a notification: the level, program, host and
+// message, then a line per field.
+func (c *notifierCore) text(ent zapcore.Entry, fields []zapcore.Field) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s on %s: %s", ent.Level.CapitalString(), c.program, c.host, ent.Message)
+	if ent.LoggerName != "" {
+		fmt.Fprintf(&b, "\nlogger: %s", ent.LoggerName)
+	}
+	if ent.Caller.Defined {
+		fmt.Fprintf(&b, "\ncaller: %s", ent.Caller.TrimmedPath())
+	}
+	enc := zapcore.NewMapObjectEncoder()
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+	keys := make([]string, 0, len(enc.Fields))
+	for k := range enc.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "\n%s: %v", k, enc.Fields[k])
+	}
+	return b.String()
+}
+
+func (c *notifierCore) post(ctx context.Context, url string, msg interface{}) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.cfg.HTTPClient.Do(req)
+	if err != nil {
+		msg := err.Error()
+		if c.cfg.TelegramToken != "" {
+			// The URL in the error holds the Telegram token.
+			msg = strings.Replace(msg, c.cfg.TelegramToken, "<token>", -1)
+		}
+		return errors.New("log: sending notification: " + msg)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("log: notification returned %s: %s", resp.Status, msg)
+	}
+	return nil
+}
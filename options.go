@@ -0,0 +1,401 @@
+package log
+
+import (
+	"crypto/rsa"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Option configures the logger built by Init.
+type Option func(*options)
+
+type options struct {
+	encoding    string
+	cores       []zapcore.Core
+	sinks       []sink
+	encrypted   []encryptedFile
+	recorder    *ringBuffer
+	recorderOut zapcore.WriteSyncer
+	crashPath   string
+	blackBox    *blackBox
+	fatalDump   bool
+	exitTimeout time.Duration
+	clock       zapcore.Clock
+	metadata    []metadataSource
+	anomaly     []*anomalyDetector
+	heartbeat   *heartbeat
+	auditPaths  []string
+	wrapCore    []func(zapcore.Core) zapcore.Core
+	legacyPrint bool
+	strictArgs  bool
+	color       *bool
+	humanUnits  bool
+	exitCode    int
+	exit        *exitHook
+	diskGuard   *diskGuard
+	async       *AsyncConfig
+	fallback    *fallback
+	split       *splitOutput
+	streams     map[string]StreamConfig
+	tenants     *TenantConfig
+	asyncSinks  []asyncSink
+	dupKeys     DuplicateKeys
+	sortKeys    bool
+	baggage     *baggage
+
+	// Filled in by Init.
+	root         zap.AtomicLevel
+	swap         *coreSwap
+	inner        zapcore.Core
+	asyncWriters []*AsyncWriter
+	stops        []func()
+
+	// syncCores are the cores of the security logger besides the default
+	// output: the cores added with WithCore and the outputs written
+	// directly, bypassing their queues.
+	syncCores []zapcore.Core
+	// secure is the core of the security logger, captured while the logger
+	// is built, and secSwap the handle it is swapped in with.
+	secure  zapcore.Core
+	secSwap *coreSwap
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		auditPaths: []string{"stdout"},
+		exitCode:   1,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// resolveEncoding returns the name of the encoder to build, taking the
+// debug mode, WithColor and HumanUnits into account. Without WithEncoding
+// it is "console" if stdout is a terminal and the default output goes
+// there, and "json" otherwise.
+func (o *options) resolveEncoding(debug bool) string {
+	name := o.encoding
+	if name == "" {
+		name = "json"
+		if o.split == nil && isTerminal(os.Stdout.Fd()) {
+			name = "console"
+		}
+	}
+	if debug && name == "console" {
+		name = prettyEncoding
+	}
+	if name == "console" && o.humanUnits {
+		return humanConsoleEncoding
+	}
+	if name == prettyEncoding && o.color != nil {
+		if *o.color {
+			return prettyColorEncoding
+		}
+		return prettyPlainEncoding
+	}
+	return name
+}
+
+// zapOptions translates the package options into zap build options.
+func (o *options) zapOptions() []zap.Option {
+	zopts := []zap.Option{
+		zap.AddCallerSkip(1),
+		zap.WithFatalHook(o.exit),
+		zap.ErrorOutput(newErrorOutput()),
+	}
+	zopts = append(zopts, zap.WithClock(o.entryClock()))
+	root := o.root
+	cores, syncCores := o.cores, o.syncCores
+	zopts = append(zopts, zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		// c is the default output, which is synchronous.
+		secure := zapcore.NewTee(append([]zapcore.Core{c}, syncCores...)...)
+		o.secure = &levelRouter{Core: zapcore.RegisterHooks(secure, countEntry), root: root}
+		if len(cores) == 0 {
+			return c
+		}
+		return zapcore.NewTee(append([]zapcore.Core{c}, cores...)...)
+	}))
+	zopts = append(zopts, zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		return &levelRouter{Core: zapcore.RegisterHooks(c, countEntry), root: root}
+	}))
+	zopts = append(zopts, zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		return zapcore.NewSamplerWithOptions(c, time.Second, 100, 100, zapcore.SamplerHook(countSampled))
+	}))
+	if g := o.diskGuard; g != nil {
+		zopts = append(zopts, zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+			g.core = c
+			return &diskGuardCore{c, g}
+		}))
+	}
+	if o.recorder != nil {
+		ring, out := o.recorder, o.recorderOut
+		zopts = append(zopts, zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+			return newRecorderCore(c, ring, out)
+		}))
+	}
+	zopts = append(zopts, zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		return &exitCore{c, o.exit}
+	}))
+	for _, f := range o.wrapCore {
+		zopts = append(zopts, zap.WrapCore(f))
+	}
+	sw := o.swap
+	zopts = append(zopts, zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		o.inner = c
+		return &swapCore{sw: sw}
+	}))
+	return zopts
+}
+
+// wrapOutput wraps c, an output written apart from the package core, the
+// way zapOptions wraps the package core: with the levels, Silence, the
+// sampler and the wrappers of WrapCore, WithPrivacy and the like.
+func (o *options) wrapOutput(c zapcore.Core) zapcore.Core {
+	c = &levelRouter{Core: zapcore.RegisterHooks(c, countEntry), root: o.root}
+	c = zapcore.NewSamplerWithOptions(c, time.Second, 100, 100, zapcore.SamplerHook(countSampled))
+	for _, f := range o.wrapCore {
+		c = f(c)
+	}
+	return c
+}
+
+// WithEncoding selects the encoder by name: "json", "console", "pretty",
+// "logfmt", "msgpack", "protobuf", "ecs", "gcp", "datadog" or one added
+// with RegisterEncoder. In debug mode "console" is rendered by the pretty
+// encoder, see NewPrettyEncoder. By default "console" is used when stdout
+// is a terminal and "json" otherwise, e.g. under systemd, so that the same
+// binary reads well in both.
+func WithEncoding(name string) Option {
+	return func(o *options) {
+		o.encoding = name
+	}
+}
+
+// WithCore adds a core that receives every entry alongside the default
+// output. It is the way to attach sinks that need the structured entry
+// rather than encoded bytes.
+func WithCore(core zapcore.Core) Option {
+	return func(o *options) {
+		o.cores = append(o.cores, core)
+	}
+}
+
+// WithSink adds an output that receives every entry encoded the same way as
+// the default output.
+func WithSink(ws zapcore.WriteSyncer) Option {
+	return func(o *options) {
+		o.sinks = append(o.sinks, sink{ws: ws})
+	}
+}
+
+// WithSinkEncoding adds an output like WithSink, with its own encoder
+// selected by name as in WithEncoding, e.g. JSON to a file while the
+// console gets "pretty".
+func WithSinkEncoding(ws zapcore.WriteSyncer, encoding string) Option {
+	return func(o *options) {
+		o.sinks = append(o.sinks, sink{ws: ws, encoding: encoding})
+	}
+}
+
+// sink is an output added by the options; an empty encoding means the
+// logger's. level, if set, further restricts the entries it receives.
+// direct, if set, is the output behind the queue of ws, written directly
+// by the security logger.
+type sink struct {
+	ws       zapcore.WriteSyncer
+	encoding string
+	level    zapcore.LevelEnabler
+	direct   zapcore.WriteSyncer
+}
+
+// WithSplitOutput replaces the default stdout output with two: entries
+// below ERROR go to low and ERROR and above to high. Both are file paths
+// or zap sink URLs, typically "stdout" and "stderr", so that container
+// runtimes and supervisors classify the streams correctly.
+func WithSplitOutput(low, high string) Option {
+	return func(o *options) {
+		o.split = &splitOutput{low, high}
+	}
+}
+
+type splitOutput struct {
+	low, high string
+}
+
+// WithEncryptedFile adds a file output encrypted with pub, see
+// EncryptedWriter. Entries are encoded the same way as the default output;
+// the file can be read back with DecryptLog and the matching private key.
+func WithEncryptedFile(path string, pub *rsa.PublicKey) Option {
+	return func(o *options) {
+		o.encrypted = append(o.encrypted, encryptedFile{path, pub})
+	}
+}
+
+type encryptedFile struct {
+	path string
+	pub  *rsa.PublicKey
+}
+
+// WithNonBlocking makes the default output and every WithSink output
+// asynchronous (see AsyncWriter), so logging never waits on a slow output
+// longer than the overflow policy allows. QueueStats reports the counters.
+func WithNonBlocking(cfg AsyncConfig) Option {
+	return func(o *options) {
+		o.async = &cfg
+	}
+}
+
+// WithFallback makes the default output and every WithSink output fall
+// back to secondary (stderr if nil) when a write to them fails, see
+// FallbackWriter. The failed output is retried every retry (30s if zero).
+// Failures are counted in ReadStats.
+func WithFallback(secondary zapcore.WriteSyncer, retry time.Duration) Option {
+	return func(o *options) {
+		o.fallback = &fallback{secondary, retry}
+	}
+}
+
+type fallback struct {
+	secondary zapcore.WriteSyncer
+	retry     time.Duration
+}
+
+// WithAsyncSink adds an output like WithSink, queued with its own
+// capacity and overflow policy, e.g. Block for a local console and
+// DropOldest for a network sink. Outputs added this way are not affected by
+// WithNonBlocking.
+func WithAsyncSink(ws zapcore.WriteSyncer, cfg AsyncConfig) Option {
+	return func(o *options) {
+		o.asyncSinks = append(o.asyncSinks, asyncSink{ws, cfg})
+	}
+}
+
+type asyncSink struct {
+	ws  zapcore.WriteSyncer
+	cfg AsyncConfig
+}
+
+// WithDiskGuard checks the free space of the partition holding path every
+// interval (10s if zero). While it is below minFree bytes, DEBUG and INFO
+// entries are dropped; a single warning is logged when that starts and an
+// info entry when space recovers.
+func WithDiskGuard(path string, minFree uint64, interval time.Duration) Option {
+	return func(o *options) {
+		o.diskGuard = newDiskGuard(path, minFree, interval)
+	}
+}
+
+// WithFlightRecorder keeps the last size entries below ERROR in memory,
+// including DEBUG entries the logger level filters out, and releases them
+// when an ERROR or more severe entry is logged. They are written to out, or
+// attached to the triggering entry under the "recent" key if out is nil.
+// size defaults to 100.
+func WithFlightRecorder(size int, out zapcore.WriteSyncer) Option {
+	return func(o *options) {
+		if size <= 0 {
+			size = 100
+		}
+		o.recorder = newRingBuffer(size)
+		o.recorderOut = out
+	}
+}
+
+// WithCrashReport makes Fatal* functions and HandleCrash write a crash
+// report to path before the process exits. The report holds the reason,
+// build information, the flight recorder contents (see WithFlightRecorder)
+// and a goroutine dump. path may be a regular file or a device node.
+func WithCrashReport(path string) Option {
+	return func(o *options) {
+		o.crashPath = path
+	}
+}
+
+// WithFatalGoroutines makes Fatal* functions log the stacks of all
+// goroutines, as a last ERROR entry with a "goroutines" field, before the
+// process exits, so that deadlocks and other fatal conditions met in the
+// field can be diagnosed from the logs alone. The dump is capped at 256
+// KiB; WithCrashReport keeps it out of the logs instead.
+func WithFatalGoroutines() Option {
+	return func(o *options) {
+		o.fatalDump = true
+	}
+}
+
+// WithStream declares a logical stream with its own outputs and encoding,
+// written through Stream(name). Entries of a stream do not reach the
+// default output.
+func WithStream(name string, cfg StreamConfig) Option {
+	return func(o *options) {
+		if o.streams == nil {
+			o.streams = make(map[string]StreamConfig)
+		}
+		o.streams[name] = cfg
+	}
+}
+
+// WithAudit sets the outputs of the audit channel, as file paths or zap
+// sink URLs. Audit entries go to stdout by default. Init fails without
+// paths.
+func WithAudit(paths ...string) Option {
+	return func(o *options) {
+		o.auditPaths = paths
+	}
+}
+
+// WrapCore wraps or replaces the zapcore.Core built by Init. Wrappers are
+// applied in the order they were given.
+func WrapCore(f func(zapcore.Core) zapcore.Core) Option {
+	return func(o *options) {
+		o.wrapCore = append(o.wrapCore, f)
+	}
+}
+
+// LegacyPrint restores the old behavior of the print-style functions, which
+// logged their arguments as a single slice ("[foo bar]") instead of joining
+// them with fmt.Sprint semantics.
+func LegacyPrint() Option {
+	return func(o *options) {
+		o.legacyPrint = true
+	}
+}
+
+// WithColor forces colors in the pretty encoder on or off. By default they
+// are used when stdout is a terminal and the NO_COLOR environment variable
+// is not set.
+func WithColor(on bool) Option {
+	return func(o *options) {
+		o.color = &on
+	}
+}
+
+// HumanUnits makes the console encoder render durations as "1.2s" and
+// byte counts (see Bytes) as "4.2MiB". JSON output keeps numeric values;
+// the pretty encoder always uses these units.
+func HumanUnits() Option {
+	return func(o *options) {
+		o.humanUnits = true
+	}
+}
+
+// StrictArgs checks the key/value arguments of the *w functions. An odd
+// number of arguments or a key that is not a string panics in debug mode
+// and otherwise logs an error pointing at the offending call; the entry is
+// still logged with the key converted to a string and a missing value
+// shown as "!MISSING".
+func StrictArgs() Option {
+	return func(o *options) {
+		o.strictArgs = true
+	}
+}
+
+// WithExitCode sets the exit code used by the Fatal* functions.
+func WithExitCode(code int) Option {
+	return func(o *options) {
+		o.exitCode = code
+	}
+}
@@ -0,0 +1,77 @@
+package log
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// maxPayload is the number of bytes of a payload logged by Hex and B64;
+// the rest is replaced by a truncation marker.
+const maxPayload = 1024
+
+// Hex constructs a field with a binary payload, e.g. a protocol message,
+// logged as a hex string by JSON and text encoders and as a hex dump, with
+// offsets and ASCII, by the pretty encoder. Only the first 1 KiB is kept,
+// followed by "...(truncated, N bytes)".
+func Hex(key string, b []byte) Field {
+	return zap.Reflect(key, newHexPayload(b))
+}
+
+// B64 constructs a field with a binary payload logged in standard base64.
+// Only the first 1 KiB is kept, followed by "...(truncated, N bytes)".
+func B64(key string, b []byte) Field {
+	s := base64.StdEncoding.EncodeToString(capPayload(b))
+	if len(b) > maxPayload {
+		s += truncationMarker(len(b))
+	}
+	return zap.String(key, s)
+}
+
+// capPayload returns a copy of the bytes of b that are logged, since the
+// caller may reuse b before the entry is encoded.
+func capPayload(b []byte) []byte {
+	if len(b) > maxPayload {
+		b = b[:maxPayload]
+	}
+	return append([]byte(nil), b...)
+}
+
+func truncationMarker(size int) string {
+	return "...(truncated, " + strconv.Itoa(size) + " bytes)"
+}
+
+// hexPayload is the value of a Hex field.
+type hexPayload struct {
+	b    []byte
+	size int
+}
+
+func newHexPayload(b []byte) hexPayload {
+	return hexPayload{b: capPayload(b), size: len(b)}
+}
+
+// String returns the payload in hex, with the truncation marker if needed.
+func (p hexPayload) String() string {
+	s := hex.EncodeToString(p.b)
+	if p.size > len(p.b) {
+		s += truncationMarker(p.size)
+	}
+	return s
+}
+
+func (p hexPayload) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.String())
+}
+
+// dump returns the payload as rendered by hexdump -C.
+func (p hexPayload) dump() string {
+	s := hex.Dump(p.b)
+	if p.size > len(p.b) {
+		s += truncationMarker(p.size)[3:]
+	}
+	return s
+}
@@ -0,0 +1,188 @@
+package log
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// Names under which the pretty encoder is registered. "pretty" colors its
+// output if stdout is a terminal and NO_COLOR is not set; the other two are
+// used by Init when WithColor forces the choice.
+const (
+	prettyEncoding      = "pretty"
+	prettyColorEncoding = "pretty+color"
+	prettyPlainEncoding = "pretty-color"
+)
+
+func init() {
+	registerEncoder(prettyEncoding, func(zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		return NewPrettyEncoder(colorSupported()), nil
+	})
+	registerEncoder(prettyColorEncoding, func(zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		return NewPrettyEncoder(true), nil
+	})
+	registerEncoder(prettyPlainEncoding, func(zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		return NewPrettyEncoder(false), nil
+	})
+}
+
+// colorSupported reports whether stdout is a terminal and the user has not
+// opted out of colors through NO_COLOR (https://no-color.org).
+func colorSupported() bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	return isTerminal(os.Stdout.Fd())
+}
+
+const (
+	ansiReset   = "\x1b[0m"
+	ansiDim     = "\x1b[2m"
+	ansiRed     = "\x1b[31m"
+	ansiYellow  = "\x1b[33m"
+	ansiBlue    = "\x1b[34m"
+	ansiMagenta = "\x1b[35m"
+	ansiCyan    = "\x1b[36m"
+)
+
+// Column widths of the pretty encoder. Longer values push the following
+// columns to the right rather than being cut.
+const (
+	prettyCallerWidth  = 28
+	prettyMessageWidth = 40
+)
+
+// prettyInlineLimit is the longest value printed on the entry line. Longer
+// values, values spanning lines and stack traces go below it, indented.
+const prettyInlineLimit = 60
+
+type prettyEncoder struct {
+	kvEncoder
+	color bool
+}
+
+// NewPrettyEncoder returns an encoder for reading logs in a terminal during
+// development: time, level, caller and message in aligned columns followed
+// by the fields as key=value pairs. With color, levels are colorized and
+// the caller and keys are dimmed.
+func NewPrettyEncoder(color bool) zapcore.Encoder {
+	return &prettyEncoder{color: color}
+}
+
+func (e *prettyEncoder) Clone() zapcore.Encoder {
+	return &prettyEncoder{kvEncoder: e.kvEncoder.clone(), color: e.color}
+}
+
+func (e *prettyEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	all := e.addFields(fields)
+	buf := bufferPool.Get()
+
+	buf.AppendString(ent.Time.Format("Jan 02 15:04:05"))
+	buf.AppendByte(' ')
+	e.paint(buf, levelColor(ent.Level), pad(Level(ent.Level).CapitalString(), 5))
+	buf.AppendByte(' ')
+	if ent.Caller.Defined {
+		e.paint(buf, ansiDim, pad(ent.Caller.TrimmedPath(), prettyCallerWidth))
+		buf.AppendByte(' ')
+	}
+	if ent.LoggerName != "" {
+		buf.AppendString("[" + ent.LoggerName + "] ")
+	}
+	if len(all) > 0 {
+		buf.AppendString(pad(ent.Message, prettyMessageWidth))
+	} else {
+		buf.AppendString(ent.Message)
+	}
+	var blocks []kv
+	for _, f := range all {
+		if p, ok := f.val.(hexPayload); ok {
+			blocks = append(blocks, kv{f.key, p.dump()})
+			continue
+		}
+		val := formatValue(f.val)
+		if len(val) > prettyInlineLimit || strings.Contains(val, "\n") {
+			blocks = append(blocks, kv{f.key, multiline(f.val, val)})
+			continue
+		}
+		buf.AppendByte(' ')
+		e.paint(buf, ansiCyan, f.key+"=")
+		buf.AppendString(quoteIfNeeded(val))
+	}
+	for _, b := range blocks {
+		e.appendBlock(buf, b.key, b.val.(string))
+	}
+	if ent.Stack != "" {
+		e.appendBlock(buf, "stacktrace", ent.Stack)
+	}
+	buf.AppendString(zapcore.DefaultLineEnding)
+	return buf, nil
+}
+
+// appendBlock writes a value on its own lines below the entry, indented
+// under its key.
+func (e *prettyEncoder) appendBlock(buf *buffer.Buffer, key, val string) {
+	buf.AppendString("\n    ")
+	e.paint(buf, ansiCyan, key+":")
+	for _, line := range strings.Split(strings.TrimRight(val, "\n"), "\n") {
+		buf.AppendString("\n        ")
+		buf.AppendString(line)
+	}
+}
+
+// multiline returns the block form of a value: objects and arrays are
+// indented JSON, anything else its text.
+func multiline(v interface{}, text string) string {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		if b, err := json.MarshalIndent(v, "", "  "); err == nil {
+			return string(b)
+		}
+	}
+	return text
+}
+
+func (e *prettyEncoder) paint(buf *buffer.Buffer, color, s string) {
+	if !e.color {
+		buf.AppendString(s)
+		return
+	}
+	buf.AppendString(color)
+	buf.AppendString(s)
+	buf.AppendString(ansiReset)
+}
+
+func levelColor(lvl zapcore.Level) string {
+	switch {
+	case lvl <= zapcore.DebugLevel:
+		return ansiMagenta
+	case lvl == zapcore.InfoLevel:
+		return ansiBlue
+	case lvl == zapcore.WarnLevel:
+		return ansiYellow
+	default:
+		return ansiRed
+	}
+}
+
+// pad right-pads s with spaces to width runes.
+func pad(s string, width int) string {
+	n := utf8.RuneCountInString(s)
+	if n >= width {
+		return s
+	}
+	return s + strings.Repeat(" ", width-n)
+}
+
+// quoteIfNeeded quotes values that would be ambiguous in key=value form.
+func quoteIfNeeded(s string) string {
+	if s == "" || strings.ContainsAny(s, " =\"\t\n\r") {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,164 @@
+package log
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Privacy selects how the addresses logged with IP and MAC are anonymized,
+// see WithPrivacy.
+type Privacy int
+
+const (
+	// PrivacyOff logs addresses in full.
+	PrivacyOff Privacy = iota
+	// PrivacyTruncate zeroes the host part of addresses: the last octet of
+	// IPv4 addresses, all but the first 48 bits of IPv6 addresses and the
+	// device part of MAC addresses, keeping the vendor OUI.
+	PrivacyTruncate
+	// PrivacyHash replaces addresses with a keyed hash, so entries about
+	// the same device can still be correlated.
+	PrivacyHash
+)
+
+// IP constructs a field with an IP address, logged in its shortest form
+// ("192.0.2.1" for IPv4-mapped addresses too) and anonymized as set with
+// WithPrivacy.
+func IP(key string, ip net.IP) Field {
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	return zap.Stringer(key, ipAddr(append(net.IP(nil), ip...)))
+}
+
+// MAC constructs a field with a hardware address, logged as
+// "00:00:5e:00:53:01" and anonymized as set with WithPrivacy.
+func MAC(key string, mac net.HardwareAddr) Field {
+	return zap.Stringer(key, macAddr(append(net.HardwareAddr(nil), mac...)))
+}
+
+// WithPrivacy anonymizes the addresses logged with IP and MAC, for logs
+// that must not hold personal data, e.g. from CPE devices under GDPR.
+// PrivacyHash uses HMAC-SHA256 with key, truncated to 64 bits; with a nil
+// key a random one is generated by Init, so hashes only match within a
+// run of the process. The key must be kept secret: the IPv4 space is
+// small enough to hash in full.
+func WithPrivacy(mode Privacy, key []byte) Option {
+	return func(o *options) {
+		if mode == PrivacyOff {
+			return
+		}
+		if mode == PrivacyHash && key == nil {
+			key = make([]byte, 32)
+			if _, err := rand.Read(key); err != nil {
+				panic("log: cannot generate a privacy key: " + err.Error())
+			}
+		}
+		o.wrapCore = append(o.wrapCore, func(c zapcore.Core) zapcore.Core {
+			return &privacyCore{Core: c, mode: mode, key: key}
+		})
+	}
+}
+
+// address is implemented by the values of IP and MAC fields.
+type address interface {
+	anonymize(mode Privacy, key []byte) string
+}
+
+type ipAddr net.IP
+
+func (a ipAddr) String() string {
+	return net.IP(a).String()
+}
+
+func (a ipAddr) anonymize(mode Privacy, key []byte) string {
+	ip := net.IP(a)
+	switch mode {
+	case PrivacyTruncate:
+		if len(ip) == net.IPv4len {
+			return ip.Mask(net.CIDRMask(24, 32)).String()
+		}
+		return ip.Mask(net.CIDRMask(48, 128)).String()
+	case PrivacyHash:
+		return addrHash(key, ip)
+	}
+	return a.String()
+}
+
+type macAddr net.HardwareAddr
+
+func (a macAddr) String() string {
+	return net.HardwareAddr(a).String()
+}
+
+func (a macAddr) anonymize(mode Privacy, key []byte) string {
+	switch mode {
+	case PrivacyTruncate:
+		mac := append(net.HardwareAddr(nil), a...)
+		for i := 3; i < len(mac); i++ {
+			mac[i] = 0
+		}
+		return mac.String()
+	case PrivacyHash:
+		return addrHash(key, a)
+	}
+	return a.String()
+}
+
+func addrHash(key, b []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(b)
+	return hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// anonymizeFields returns fields with the addresses anonymized, or fields
+// itself if there are none.
+func anonymizeFields(fields []zapcore.Field, mode Privacy, key []byte) []zapcore.Field {
+	var out []zapcore.Field
+	for i, f := range fields {
+		a, ok := f.Interface.(address)
+		if !ok || f.Type != zapcore.StringerType {
+			continue
+		}
+		if out == nil {
+			out = append([]zapcore.Field(nil), fields...)
+		}
+		out[i] = zap.String(f.Key, a.anonymize(mode, key))
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
+
+// privacyCore anonymizes the addresses of the entries written to the core
+// it wraps.
+type privacyCore struct {
+	zapcore.Core
+	mode Privacy
+	key  []byte
+}
+
+func (c *privacyCore) With(fields []zapcore.Field) zapcore.Core {
+	return &privacyCore{c.Core.With(anonymizeFields(fields, c.mode, c.key)), c.mode, c.key}
+}
+
+func (c *privacyCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *privacyCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if ce := c.Core.Check(ent, nil); ce != nil {
+		ce.Write(anonymizeFields(fields, c.mode, c.key)...)
+	}
+	return nil
+}
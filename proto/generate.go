@@ -0,0 +1,17 @@
+// Package logpb holds the Go code generated from the .proto files of this
+// directory: LogEntry, written by the "protobuf" encoder, and the
+// LogService served by package logrpc. It is a module of its own, so that
+// the log package does not depend on protobuf and gRPC.
+//
+// The code is generated with pinned tools, so that regenerating it gives
+// the same output:
+//
+//	protoc             v23.4
+//	protoc-gen-go      v1.30.0
+//	protoc-gen-go-grpc v1.3.0
+package logpb
+
+//go:generate go install google.golang.org/protobuf/cmd/protoc-gen-go@v1.30.0
+//go:generate go install google.golang.org/grpc/cmd/protoc-gen-go-grpc@v1.3.0
+//go:generate sh -c "protoc --version | grep -qx 'libprotoc 23.4' || { echo 'protoc 23.4 is required' >&2; exit 1; }"
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative logentry.proto logservice.proto
@@ -0,0 +1,446 @@
+// Log entries as written by the "protobuf" encoder of
+// github.com/ndmsystems/golog. Each record on the wire is a LogEntry
+// preceded by its length as a varint, as with writeDelimitedTo in the
+// Java and C++ libraries.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.30.0
+// 	protoc        (unknown)
+// source: logentry.proto
+
+package logpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type LogEntry struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Time of the entry in nanoseconds since the Unix epoch.
+	TimeUnixNano int64 `protobuf:"varint,1,opt,name=time_unix_nano,json=timeUnixNano,proto3" json:"time_unix_nano,omitempty"`
+	// zapcore level: -2 trace, -1 debug, 0 info, 1 warning, 2 error,
+	// 3 dpanic, 4 panic, 5 fatal.
+	Level  int32  `protobuf:"zigzag32,2,opt,name=level,proto3" json:"level,omitempty"`
+	Logger string `protobuf:"bytes,3,opt,name=logger,proto3" json:"logger,omitempty"`
+	// File and line, e.g. "pkg/file.go:12".
+	Caller     string   `protobuf:"bytes,4,opt,name=caller,proto3" json:"caller,omitempty"`
+	Message    string   `protobuf:"bytes,5,opt,name=message,proto3" json:"message,omitempty"`
+	Stacktrace string   `protobuf:"bytes,6,opt,name=stacktrace,proto3" json:"stacktrace,omitempty"`
+	Fields     []*Field `protobuf:"bytes,7,rep,name=fields,proto3" json:"fields,omitempty"`
+}
+
+func (x *LogEntry) Reset() {
+	*x = LogEntry{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_logentry_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *LogEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogEntry) ProtoMessage() {}
+
+func (x *LogEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_logentry_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogEntry.ProtoReflect.Descriptor instead.
+func (*LogEntry) Descriptor() ([]byte, []int) {
+	return file_logentry_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *LogEntry) GetTimeUnixNano() int64 {
+	if x != nil {
+		return x.TimeUnixNano
+	}
+	return 0
+}
+
+func (x *LogEntry) GetLevel() int32 {
+	if x != nil {
+		return x.Level
+	}
+	return 0
+}
+
+func (x *LogEntry) GetLogger() string {
+	if x != nil {
+		return x.Logger
+	}
+	return ""
+}
+
+func (x *LogEntry) GetCaller() string {
+	if x != nil {
+		return x.Caller
+	}
+	return ""
+}
+
+func (x *LogEntry) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *LogEntry) GetStacktrace() string {
+	if x != nil {
+		return x.Stacktrace
+	}
+	return ""
+}
+
+func (x *LogEntry) GetFields() []*Field {
+	if x != nil {
+		return x.Fields
+	}
+	return nil
+}
+
+type Field struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// Types that are assignable to Value:
+	//	*Field_StringValue
+	//	*Field_IntValue
+	//	*Field_UintValue
+	//	*Field_DoubleValue
+	//	*Field_BoolValue
+	//	*Field_BytesValue
+	//	*Field_JsonValue
+	//	*Field_DurationNanos
+	//	*Field_TimeUnixNano
+	Value isField_Value `protobuf_oneof:"value"`
+}
+
+func (x *Field) Reset() {
+	*x = Field{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_logentry_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Field) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Field) ProtoMessage() {}
+
+func (x *Field) ProtoReflect() protoreflect.Message {
+	mi := &file_logentry_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Field.ProtoReflect.Descriptor instead.
+func (*Field) Descriptor() ([]byte, []int) {
+	return file_logentry_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Field) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (m *Field) GetValue() isField_Value {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (x *Field) GetStringValue() string {
+	if x, ok := x.GetValue().(*Field_StringValue); ok {
+		return x.StringValue
+	}
+	return ""
+}
+
+func (x *Field) GetIntValue() int64 {
+	if x, ok := x.GetValue().(*Field_IntValue); ok {
+		return x.IntValue
+	}
+	return 0
+}
+
+func (x *Field) GetUintValue() uint64 {
+	if x, ok := x.GetValue().(*Field_UintValue); ok {
+		return x.UintValue
+	}
+	return 0
+}
+
+func (x *Field) GetDoubleValue() float64 {
+	if x, ok := x.GetValue().(*Field_DoubleValue); ok {
+		return x.DoubleValue
+	}
+	return 0
+}
+
+func (x *Field) GetBoolValue() bool {
+	if x, ok := x.GetValue().(*Field_BoolValue); ok {
+		return x.BoolValue
+	}
+	return false
+}
+
+func (x *Field) GetBytesValue() []byte {
+	if x, ok := x.GetValue().(*Field_BytesValue); ok {
+		return x.BytesValue
+	}
+	return nil
+}
+
+func (x *Field) GetJsonValue() string {
+	if x, ok := x.GetValue().(*Field_JsonValue); ok {
+		return x.JsonValue
+	}
+	return ""
+}
+
+func (x *Field) GetDurationNanos() int64 {
+	if x, ok := x.GetValue().(*Field_DurationNanos); ok {
+		return x.DurationNanos
+	}
+	return 0
+}
+
+func (x *Field) GetTimeUnixNano() int64 {
+	if x, ok := x.GetValue().(*Field_TimeUnixNano); ok {
+		return x.TimeUnixNano
+	}
+	return 0
+}
+
+type isField_Value interface {
+	isField_Value()
+}
+
+type Field_StringValue struct {
+	StringValue string `protobuf:"bytes,2,opt,name=string_value,json=stringValue,proto3,oneof"`
+}
+
+type Field_IntValue struct {
+	IntValue int64 `protobuf:"zigzag64,3,opt,name=int_value,json=intValue,proto3,oneof"`
+}
+
+type Field_UintValue struct {
+	UintValue uint64 `protobuf:"varint,4,opt,name=uint_value,json=uintValue,proto3,oneof"`
+}
+
+type Field_DoubleValue struct {
+	DoubleValue float64 `protobuf:"fixed64,5,opt,name=double_value,json=doubleValue,proto3,oneof"`
+}
+
+type Field_BoolValue struct {
+	BoolValue bool `protobuf:"varint,6,opt,name=bool_value,json=boolValue,proto3,oneof"`
+}
+
+type Field_BytesValue struct {
+	BytesValue []byte `protobuf:"bytes,7,opt,name=bytes_value,json=bytesValue,proto3,oneof"`
+}
+
+type Field_JsonValue struct {
+	// Objects, arrays and other values without a native counterpart.
+	JsonValue string `protobuf:"bytes,8,opt,name=json_value,json=jsonValue,proto3,oneof"`
+}
+
+type Field_DurationNanos struct {
+	DurationNanos int64 `protobuf:"varint,9,opt,name=duration_nanos,json=durationNanos,proto3,oneof"`
+}
+
+type Field_TimeUnixNano struct {
+	TimeUnixNano int64 `protobuf:"varint,10,opt,name=time_unix_nano,json=timeUnixNano,proto3,oneof"`
+}
+
+func (*Field_StringValue) isField_Value() {}
+
+func (*Field_IntValue) isField_Value() {}
+
+func (*Field_UintValue) isField_Value() {}
+
+func (*Field_DoubleValue) isField_Value() {}
+
+func (*Field_BoolValue) isField_Value() {}
+
+func (*Field_BytesValue) isField_Value() {}
+
+func (*Field_JsonValue) isField_Value() {}
+
+func (*Field_DurationNanos) isField_Value() {}
+
+func (*Field_TimeUnixNano) isField_Value() {}
+
+var File_logentry_proto protoreflect.FileDescriptor
+
+var file_logentry_proto_rawDesc = []byte{
+	0x0a, 0x0e, 0x6c, 0x6f, 0x67, 0x65, 0x6e, 0x74, 0x72, 0x79, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x12, 0x05, 0x67, 0x6f, 0x6c, 0x6f, 0x67, 0x22, 0xd6, 0x01, 0x0a, 0x08, 0x4c, 0x6f, 0x67, 0x45,
+	0x6e, 0x74, 0x72, 0x79, 0x12, 0x24, 0x0a, 0x0e, 0x74, 0x69, 0x6d, 0x65, 0x5f, 0x75, 0x6e, 0x69,
+	0x78, 0x5f, 0x6e, 0x61, 0x6e, 0x6f, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0c, 0x74, 0x69,
+	0x6d, 0x65, 0x55, 0x6e, 0x69, 0x78, 0x4e, 0x61, 0x6e, 0x6f, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x65,
+	0x76, 0x65, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x11, 0x52, 0x05, 0x6c, 0x65, 0x76, 0x65, 0x6c,
+	0x12, 0x16, 0x0a, 0x06, 0x6c, 0x6f, 0x67, 0x67, 0x65, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x06, 0x6c, 0x6f, 0x67, 0x67, 0x65, 0x72, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x61, 0x6c, 0x6c,
+	0x65, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x63, 0x61, 0x6c, 0x6c, 0x65, 0x72,
+	0x12, 0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x73, 0x74,
+	0x61, 0x63, 0x6b, 0x74, 0x72, 0x61, 0x63, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a,
+	0x73, 0x74, 0x61, 0x63, 0x6b, 0x74, 0x72, 0x61, 0x63, 0x65, 0x12, 0x24, 0x0a, 0x06, 0x66, 0x69,
+	0x65, 0x6c, 0x64, 0x73, 0x18, 0x07, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x67, 0x6f, 0x6c,
+	0x6f, 0x67, 0x2e, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x52, 0x06, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x73,
+	0x22, 0xe2, 0x02, 0x0a, 0x05, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65,
+	0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x23, 0x0a, 0x0c,
+	0x73, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x5f, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x48, 0x00, 0x52, 0x0b, 0x73, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x56, 0x61, 0x6c, 0x75,
+	0x65, 0x12, 0x1d, 0x0a, 0x09, 0x69, 0x6e, 0x74, 0x5f, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x12, 0x48, 0x00, 0x52, 0x08, 0x69, 0x6e, 0x74, 0x56, 0x61, 0x6c, 0x75, 0x65,
+	0x12, 0x1f, 0x0a, 0x0a, 0x75, 0x69, 0x6e, 0x74, 0x5f, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x04, 0x48, 0x00, 0x52, 0x09, 0x75, 0x69, 0x6e, 0x74, 0x56, 0x61, 0x6c, 0x75,
+	0x65, 0x12, 0x23, 0x0a, 0x0c, 0x64, 0x6f, 0x75, 0x62, 0x6c, 0x65, 0x5f, 0x76, 0x61, 0x6c, 0x75,
+	0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x01, 0x48, 0x00, 0x52, 0x0b, 0x64, 0x6f, 0x75, 0x62, 0x6c,
+	0x65, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x12, 0x1f, 0x0a, 0x0a, 0x62, 0x6f, 0x6f, 0x6c, 0x5f, 0x76,
+	0x61, 0x6c, 0x75, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x08, 0x48, 0x00, 0x52, 0x09, 0x62, 0x6f,
+	0x6f, 0x6c, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x12, 0x21, 0x0a, 0x0b, 0x62, 0x79, 0x74, 0x65, 0x73,
+	0x5f, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0c, 0x48, 0x00, 0x52, 0x0a,
+	0x62, 0x79, 0x74, 0x65, 0x73, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x12, 0x1f, 0x0a, 0x0a, 0x6a, 0x73,
+	0x6f, 0x6e, 0x5f, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00,
+	0x52, 0x09, 0x6a, 0x73, 0x6f, 0x6e, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x12, 0x27, 0x0a, 0x0e, 0x64,
+	0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x6e, 0x61, 0x6e, 0x6f, 0x73, 0x18, 0x09, 0x20,
+	0x01, 0x28, 0x03, 0x48, 0x00, 0x52, 0x0d, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x4e,
+	0x61, 0x6e, 0x6f, 0x73, 0x12, 0x26, 0x0a, 0x0e, 0x74, 0x69, 0x6d, 0x65, 0x5f, 0x75, 0x6e, 0x69,
+	0x78, 0x5f, 0x6e, 0x61, 0x6e, 0x6f, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x03, 0x48, 0x00, 0x52, 0x0c,
+	0x74, 0x69, 0x6d, 0x65, 0x55, 0x6e, 0x69, 0x78, 0x4e, 0x61, 0x6e, 0x6f, 0x42, 0x07, 0x0a, 0x05,
+	0x76, 0x61, 0x6c, 0x75, 0x65, 0x42, 0x29, 0x5a, 0x27, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e,
+	0x63, 0x6f, 0x6d, 0x2f, 0x6e, 0x64, 0x6d, 0x73, 0x79, 0x73, 0x74, 0x65, 0x6d, 0x73, 0x2f, 0x67,
+	0x6f, 0x6c, 0x6f, 0x67, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x3b, 0x6c, 0x6f, 0x67, 0x70, 0x62,
+	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_logentry_proto_rawDescOnce sync.Once
+	file_logentry_proto_rawDescData = file_logentry_proto_rawDesc
+)
+
+func file_logentry_proto_rawDescGZIP() []byte {
+	file_logentry_proto_rawDescOnce.Do(func() {
+		file_logentry_proto_rawDescData = protoimpl.X.CompressGZIP(file_logentry_proto_rawDescData)
+	})
+	return file_logentry_proto_rawDescData
+}
+
+var file_logentry_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_logentry_proto_goTypes = []interface{}{
+	(*LogEntry)(nil), // 0: golog.LogEntry
+	(*Field)(nil),    // 1: golog.Field
+}
+var file_logentry_proto_depIdxs = []int32{
+	1, // 0: golog.LogEntry.fields:type_name -> golog.Field
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_logentry_proto_init() }
+func file_logentry_proto_init() {
+	if File_logentry_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_logentry_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*LogEntry); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_logentry_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Field); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_logentry_proto_msgTypes[1].OneofWrappers = []interface{}{
+		(*Field_StringValue)(nil),
+		(*Field_IntValue)(nil),
+		(*Field_UintValue)(nil),
+		(*Field_DoubleValue)(nil),
+		(*Field_BoolValue)(nil),
+		(*Field_BytesValue)(nil),
+		(*Field_JsonValue)(nil),
+		(*Field_DurationNanos)(nil),
+		(*Field_TimeUnixNano)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_logentry_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_logentry_proto_goTypes,
+		DependencyIndexes: file_logentry_proto_depIdxs,
+		MessageInfos:      file_logentry_proto_msgTypes,
+	}.Build()
+	File_logentry_proto = out.File
+	file_logentry_proto_rawDesc = nil
+	file_logentry_proto_goTypes = nil
+	file_logentry_proto_depIdxs = nil
+}
@@ -0,0 +1,224 @@
+// Remote log retrieval and level control of the processes using
+// github.com/ndmsystems/golog, served by its logrpc package.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: logservice.proto
+
+package logpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	LogService_TailLogs_FullMethodName = "/golog.LogService/TailLogs"
+	LogService_SetLevel_FullMethodName = "/golog.LogService/SetLevel"
+	LogService_GetStats_FullMethodName = "/golog.LogService/GetStats"
+)
+
+// LogServiceClient is the client API for LogService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type LogServiceClient interface {
+	// TailLogs sends the entries kept in memory by the process, oldest
+	// first, then, if follow is set, the new ones as they are logged until
+	// the call is cancelled.
+	TailLogs(ctx context.Context, in *TailLogsRequest, opts ...grpc.CallOption) (LogService_TailLogsClient, error)
+	// SetLevel sets the package level or the level of a named logger.
+	SetLevel(ctx context.Context, in *SetLevelRequest, opts ...grpc.CallOption) (*SetLevelResponse, error)
+	// GetStats returns the logger statistics and levels.
+	GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*Stats, error)
+}
+
+type logServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewLogServiceClient(cc grpc.ClientConnInterface) LogServiceClient {
+	return &logServiceClient{cc}
+}
+
+func (c *logServiceClient) TailLogs(ctx context.Context, in *TailLogsRequest, opts ...grpc.CallOption) (LogService_TailLogsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &LogService_ServiceDesc.Streams[0], LogService_TailLogs_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &logServiceTailLogsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type LogService_TailLogsClient interface {
+	Recv() (*LogEntry, error)
+	grpc.ClientStream
+}
+
+type logServiceTailLogsClient struct {
+	grpc.ClientStream
+}
+
+func (x *logServiceTailLogsClient) Recv() (*LogEntry, error) {
+	m := new(LogEntry)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *logServiceClient) SetLevel(ctx context.Context, in *SetLevelRequest, opts ...grpc.CallOption) (*SetLevelResponse, error) {
+	out := new(SetLevelResponse)
+	err := c.cc.Invoke(ctx, LogService_SetLevel_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *logServiceClient) GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*Stats, error) {
+	out := new(Stats)
+	err := c.cc.Invoke(ctx, LogService_GetStats_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LogServiceServer is the server API for LogService service.
+// All implementations must embed UnimplementedLogServiceServer
+// for forward compatibility
+type LogServiceServer interface {
+	// TailLogs sends the entries kept in memory by the process, oldest
+	// first, then, if follow is set, the new ones as they are logged until
+	// the call is cancelled.
+	TailLogs(*TailLogsRequest, LogService_TailLogsServer) error
+	// SetLevel sets the package level or the level of a named logger.
+	SetLevel(context.Context, *SetLevelRequest) (*SetLevelResponse, error)
+	// GetStats returns the logger statistics and levels.
+	GetStats(context.Context, *GetStatsRequest) (*Stats, error)
+	mustEmbedUnimplementedLogServiceServer()
+}
+
+// UnimplementedLogServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedLogServiceServer struct {
+}
+
+func (UnimplementedLogServiceServer) TailLogs(*TailLogsRequest, LogService_TailLogsServer) error {
+	return status.Errorf(codes.Unimplemented, "method TailLogs not implemented")
+}
+func (UnimplementedLogServiceServer) SetLevel(context.Context, *SetLevelRequest) (*SetLevelResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetLevel not implemented")
+}
+func (UnimplementedLogServiceServer) GetStats(context.Context, *GetStatsRequest) (*Stats, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStats not implemented")
+}
+func (UnimplementedLogServiceServer) mustEmbedUnimplementedLogServiceServer() {}
+
+// UnsafeLogServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to LogServiceServer will
+// result in compilation errors.
+type UnsafeLogServiceServer interface {
+	mustEmbedUnimplementedLogServiceServer()
+}
+
+func RegisterLogServiceServer(s grpc.ServiceRegistrar, srv LogServiceServer) {
+	s.RegisterService(&LogService_ServiceDesc, srv)
+}
+
+func _LogService_TailLogs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(TailLogsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(LogServiceServer).TailLogs(m, &logServiceTailLogsServer{stream})
+}
+
+type LogService_TailLogsServer interface {
+	Send(*LogEntry) error
+	grpc.ServerStream
+}
+
+type logServiceTailLogsServer struct {
+	grpc.ServerStream
+}
+
+func (x *logServiceTailLogsServer) Send(m *LogEntry) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _LogService_SetLevel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetLevelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LogServiceServer).SetLevel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LogService_SetLevel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LogServiceServer).SetLevel(ctx, req.(*SetLevelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LogService_GetStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LogServiceServer).GetStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LogService_GetStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LogServiceServer).GetStats(ctx, req.(*GetStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// LogService_ServiceDesc is the grpc.ServiceDesc for LogService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var LogService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "golog.LogService",
+	HandlerType: (*LogServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SetLevel",
+			Handler:    _LogService_SetLevel_Handler,
+		},
+		{
+			MethodName: "GetStats",
+			Handler:    _LogService_GetStats_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "TailLogs",
+			Handler:       _LogService_TailLogs_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "logservice.proto",
+}
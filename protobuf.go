@@ -0,0 +1,183 @@
+package log
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+func init() {
+	registerEncoder("protobuf", func(zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		return NewProtobufEncoder(), nil
+	})
+}
+
+// Protocol buffer wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+type protobufEncoder struct {
+	kvEncoder
+}
+
+// NewProtobufEncoder returns an encoder writing each entry as a LogEntry
+// message (see proto/logentry.proto) preceded by its length as a varint,
+// so collectors can consume typed records without parsing JSON.
+func NewProtobufEncoder() zapcore.Encoder {
+	return &protobufEncoder{}
+}
+
+func (e *protobufEncoder) Clone() zapcore.Encoder {
+	return &protobufEncoder{kvEncoder: e.kvEncoder.clone()}
+}
+
+func (e *protobufEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	msg := bufferPool.Get()
+	defer msg.Free()
+
+	appendProtoVarint(msg, 1, uint64(ent.Time.UnixNano()))
+	appendProtoVarint(msg, 2, zigzag(int64(ent.Level)))
+	appendProtoString(msg, 3, ent.LoggerName)
+	if ent.Caller.Defined {
+		appendProtoString(msg, 4, ent.Caller.TrimmedPath())
+	}
+	appendProtoString(msg, 5, ent.Message)
+	appendProtoString(msg, 6, ent.Stack)
+
+	field := bufferPool.Get()
+	defer field.Free()
+	for _, f := range e.addFields(fields) {
+		field.Reset()
+		appendProtoString(field, 1, f.key)
+		appendProtoValue(field, f.val)
+		appendProtoBytes(msg, 7, field.Bytes())
+	}
+
+	buf := bufferPool.Get()
+	appendUvarint(buf, uint64(msg.Len()))
+	buf.Write(msg.Bytes())
+	return buf, nil
+}
+
+// appendProtoValue writes the oneof value of a Field message.
+func appendProtoValue(buf *buffer.Buffer, v interface{}) {
+	switch v := v.(type) {
+	case nil:
+	case string:
+		appendProtoText(buf, 2, v)
+	case bool:
+		n := uint64(0)
+		if v {
+			n = 1
+		}
+		appendProtoVarint(buf, 6, n)
+	case []byte:
+		appendProtoBytes(buf, 7, v)
+	case int:
+		appendProtoVarint(buf, 3, zigzag(int64(v)))
+	case int8:
+		appendProtoVarint(buf, 3, zigzag(int64(v)))
+	case int16:
+		appendProtoVarint(buf, 3, zigzag(int64(v)))
+	case int32:
+		appendProtoVarint(buf, 3, zigzag(int64(v)))
+	case int64:
+		appendProtoVarint(buf, 3, zigzag(v))
+	case ByteSize:
+		appendProtoVarint(buf, 3, zigzag(int64(v)))
+	case uint:
+		appendProtoVarint(buf, 4, uint64(v))
+	case uint8:
+		appendProtoVarint(buf, 4, uint64(v))
+	case uint16:
+		appendProtoVarint(buf, 4, uint64(v))
+	case uint32:
+		appendProtoVarint(buf, 4, uint64(v))
+	case uint64:
+		appendProtoVarint(buf, 4, v)
+	case uintptr:
+		appendProtoVarint(buf, 4, uint64(v))
+	case float32:
+		appendProtoDouble(buf, 5, float64(v))
+	case float64:
+		appendProtoDouble(buf, 5, v)
+	case time.Duration:
+		appendProtoVarint(buf, 9, uint64(v))
+	case time.Time:
+		appendProtoVarint(buf, 10, uint64(v.UnixNano()))
+	case error:
+		appendProtoText(buf, 2, v.Error())
+	case fmt.Stringer:
+		appendProtoText(buf, 2, v.String())
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			appendProtoText(buf, 2, fmt.Sprint(v))
+			return
+		}
+		appendProtoBytes(buf, 8, b)
+	}
+}
+
+func appendProtoTag(buf *buffer.Buffer, field int, wire int) {
+	appendUvarint(buf, uint64(field<<3|wire))
+}
+
+// appendProtoVarint writes a varint field. Zero values are written too,
+// since they are meaningful as members of a oneof.
+func appendProtoVarint(buf *buffer.Buffer, field int, v uint64) {
+	appendProtoTag(buf, field, wireVarint)
+	appendUvarint(buf, v)
+}
+
+func appendProtoDouble(buf *buffer.Buffer, field int, v float64) {
+	appendProtoTag(buf, field, wireFixed64)
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], math.Float64bits(v))
+	buf.Write(b[:])
+}
+
+func appendProtoBytes(buf *buffer.Buffer, field int, b []byte) {
+	appendProtoTag(buf, field, wireBytes)
+	appendUvarint(buf, uint64(len(b)))
+	buf.Write(b)
+}
+
+// appendProtoString writes a string field unless it is empty, the proto3
+// default.
+func appendProtoString(buf *buffer.Buffer, field int, s string) {
+	if s == "" {
+		return
+	}
+	appendProtoText(buf, field, s)
+}
+
+// appendProtoText writes a string field, even if empty. proto3 strings
+// must be valid UTF-8, so invalid bytes are replaced with U+FFFD.
+func appendProtoText(buf *buffer.Buffer, field int, s string) {
+	if !utf8.ValidString(s) {
+		s = strings.ToValidUTF8(s, "\uFFFD")
+	}
+	appendProtoTag(buf, field, wireBytes)
+	appendUvarint(buf, uint64(len(s)))
+	buf.AppendString(s)
+}
+
+func appendUvarint(buf *buffer.Buffer, v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
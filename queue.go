@@ -0,0 +1,225 @@
+package log
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// OverflowPolicy decides which entry is lost when a bounded queue is full.
+type OverflowPolicy int
+
+const (
+	// DropNewest discards the entry being added.
+	DropNewest OverflowPolicy = iota
+	// DropOldest discards the oldest queued entry to make room.
+	DropOldest
+	// BlockWithTimeout makes the producer wait for room for a bounded
+	// time, then discards the entry being added.
+	BlockWithTimeout
+	// Block makes the producer wait until there is room.
+	Block
+	// Coalesce appends the entry to the newest queued entry with the same
+	// key, so it is delivered in the same write. It suits byte stream
+	// outputs, not message based ones. Entries are discarded when the
+	// merged entry would exceed maxCoalesce bytes.
+	Coalesce
+)
+
+// maxCoalesce bounds the size of an entry grown by the Coalesce policy.
+const maxCoalesce = 1 << 20
+
+// defaultBlockTimeout is how long BlockWithTimeout waits when the queue
+// has no timeout of its own.
+const defaultBlockTimeout = 100 * time.Millisecond
+
+var errQueueTimeout = errors.New("log: timed out waiting for queued entries to be delivered")
+
+// queuedEntry is an encoded entry together with a sink-specific routing key,
+// such as an MQTT topic.
+type queuedEntry struct {
+	key  string
+	data []byte
+}
+
+// entryQueue is a bounded FIFO of encoded entries shared by a producer
+// (the logger) and a single consumer (a sink's sender goroutine).
+type entryQueue struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	items   []queuedEntry
+	max     int
+	policy  OverflowPolicy
+	timeout time.Duration
+	dropped uint64
+	busy    bool
+	closed  bool
+}
+
+func newEntryQueue(max int, policy OverflowPolicy) *entryQueue {
+	q := &entryQueue{max: max, policy: policy}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// push adds a copy of p, applying the overflow policy when the queue is
+// full. It reports whether p was queued.
+func (q *entryQueue) push(key string, p []byte) bool {
+	entry := queuedEntry{key: key, data: make([]byte, len(p))}
+	copy(entry.data, p)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return false
+	}
+	if len(q.items) >= q.max {
+		switch q.policy {
+		case BlockWithTimeout:
+			timeout := q.timeout
+			if timeout <= 0 {
+				timeout = defaultBlockTimeout
+			}
+			q.waitForRoom(timeout)
+		case Block:
+			q.waitForRoom(0)
+		case Coalesce:
+			if q.coalesce(entry) {
+				return true
+			}
+		}
+	}
+	if q.closed {
+		return false
+	}
+	if len(q.items) >= q.max {
+		q.dropped++
+		// The head may be in flight, in which case the next one is the
+		// oldest entry that can still be dropped.
+		i := 0
+		if q.busy {
+			i = 1
+		}
+		if q.policy != DropOldest || i >= len(q.items) {
+			return false
+		}
+		q.items = append(q.items[:i], q.items[i+1:]...)
+	}
+	q.items = append(q.items, entry)
+	q.cond.Broadcast()
+	return true
+}
+
+// waitForRoom waits with q.mu held until the queue has room, is closed or
+// timeout expires. A zero timeout waits indefinitely.
+func (q *entryQueue) waitForRoom(timeout time.Duration) {
+	expired := false
+	if timeout > 0 {
+		t := time.AfterFunc(timeout, func() {
+			q.mu.Lock()
+			expired = true
+			q.cond.Broadcast()
+			q.mu.Unlock()
+		})
+		defer t.Stop()
+	}
+	for len(q.items) >= q.max && !q.closed && !expired {
+		q.cond.Wait()
+	}
+}
+
+// coalesce appends entry to the newest queued entry with q.mu held. It
+// reports false if that entry is in flight, has another key or would grow
+// too large.
+func (q *entryQueue) coalesce(entry queuedEntry) bool {
+	i := len(q.items) - 1
+	if i < 0 || (i == 0 && q.busy) {
+		return false
+	}
+	tail := &q.items[i]
+	if tail.key != entry.key || len(tail.data)+len(entry.data) > maxCoalesce {
+		return false
+	}
+	tail.data = append(tail.data, entry.data...)
+	q.cond.Broadcast()
+	return true
+}
+
+// next blocks until an entry is available and returns it without removing
+// it, so a failed delivery can be retried. It returns false once the queue
+// is closed and drained.
+func (q *entryQueue) next() (queuedEntry, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.items) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if len(q.items) == 0 {
+		return queuedEntry{}, false
+	}
+	q.busy = true
+	return q.items[0], true
+}
+
+// done removes the entry returned by next after it was delivered.
+func (q *entryQueue) done() {
+	q.mu.Lock()
+	if len(q.items) > 0 {
+		q.items = q.items[1:]
+	}
+	q.busy = false
+	q.cond.Broadcast()
+	q.mu.Unlock()
+}
+
+// release marks the entry returned by next as not delivered.
+func (q *entryQueue) release() {
+	q.mu.Lock()
+	q.busy = false
+	q.cond.Broadcast()
+	q.mu.Unlock()
+}
+
+// drain waits until every queued entry has been delivered or the timeout
+// expires.
+func (q *entryQueue) drain(timeout time.Duration) error {
+	expired := false
+	t := time.AfterFunc(timeout, func() {
+		q.mu.Lock()
+		expired = true
+		q.cond.Broadcast()
+		q.mu.Unlock()
+	})
+	defer t.Stop()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.items) > 0 || q.busy {
+		if expired {
+			return errQueueTimeout
+		}
+		q.cond.Wait()
+	}
+	return nil
+}
+
+func (q *entryQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+func (q *entryQueue) droppedCount() uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.dropped
+}
+
+// close wakes up the consumer; entries still queued are returned by next
+// until the queue is empty.
+func (q *entryQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.cond.Broadcast()
+	q.mu.Unlock()
+}
@@ -0,0 +1,120 @@
+package log
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Config is a complete logger configuration for Reconfigure.
+type Config struct {
+	// Debug selects the debug mode, as the argument of Init.
+	Debug bool
+	// Levels, if set, configures the package level and the levels of named
+	// loggers as in SetLevels.
+	Levels string
+	// Options are the options passed to Init.
+	Options []Option
+}
+
+var reconfigureMu sync.Mutex
+
+// Reconfigure rebuilds the package logger from cfg and switches to it
+// without a restart. Unlike Init, the new outputs and encoders are swapped
+// in under the existing logger: loggers already derived with With, and the
+// levels set on them, keep working and write to the new outputs. The
+// previous outputs are flushed and closed after the swap. If building
+// fails, the error is returned and nothing is changed.
+func Reconfigure(cfg Config) error {
+	reconfigureMu.Lock()
+	defer reconfigureMu.Unlock()
+
+	next, err := build(cfg.Debug, newOptions(cfg.Options), std())
+	if err != nil {
+		return err
+	}
+	replace(next)
+	if cfg.Levels != "" {
+		return SetLevels(cfg.Levels)
+	}
+	return nil
+}
+
+// coreSwap holds the current cores of a logger built by Init and of all
+// the loggers derived from it.
+type coreSwap struct {
+	v atomic.Value
+}
+
+// swapTarget boxes the core so that generations can be told apart by
+// pointer, as cores are not necessarily comparable.
+type swapTarget struct {
+	core zapcore.Core
+}
+
+func newCoreSwap() *coreSwap {
+	return &coreSwap{}
+}
+
+func (sw *coreSwap) store(core zapcore.Core) {
+	sw.v.Store(&swapTarget{core})
+}
+
+func (sw *coreSwap) load() *swapTarget {
+	return sw.v.Load().(*swapTarget)
+}
+
+// swapCore is the outermost core of a logger built by Init. It forwards to
+// the current cores of its coreSwap, adding the fields of With.
+type swapCore struct {
+	sw     *coreSwap
+	fields []zapcore.Field
+
+	// cache holds the current core with the fields added, as a
+	// *swapCached.
+	cache atomic.Value
+}
+
+type swapCached struct {
+	target *swapTarget
+	core   zapcore.Core
+}
+
+func (c *swapCore) core() zapcore.Core {
+	t := c.sw.load()
+	if len(c.fields) == 0 {
+		return t.core
+	}
+	if cached, _ := c.cache.Load().(*swapCached); cached != nil && cached.target == t {
+		return cached.core
+	}
+	core := t.core.With(c.fields)
+	c.cache.Store(&swapCached{t, core})
+	return core
+}
+
+func (c *swapCore) Enabled(lvl zapcore.Level) bool {
+	return c.core().Enabled(lvl)
+}
+
+func (c *swapCore) With(fields []zapcore.Field) zapcore.Core {
+	all := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	all = append(all, c.fields...)
+	clone := &swapCore{sw: c.sw, fields: append(all, fields...)}
+	// Fields are encoded once per generation of cores, not per entry.
+	clone.core()
+	return clone
+}
+
+func (c *swapCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return c.core().Check(ent, ce)
+}
+
+func (c *swapCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.core().Write(ent, fields)
+}
+
+func (c *swapCore) Sync() error {
+	return c.sw.load().core.Sync()
+}
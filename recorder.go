@@ -0,0 +1,105 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// recorderCore keeps the entries below ERROR that pass through it in a
+// ring buffer, whatever the logger level, and releases them when an ERROR
+// or more severe entry is logged: either written to out, or attached to
+// that entry under the "recent" key when out is nil.
+type recorderCore struct {
+	inner zapcore.Core
+	enc   zapcore.Encoder
+	ring  *ringBuffer
+	out   zapcore.WriteSyncer
+}
+
+func newRecorderCore(inner zapcore.Core, ring *ringBuffer, out zapcore.WriteSyncer) zapcore.Core {
+	return &recorderCore{
+		inner: inner,
+		enc:   zapcore.NewJSONEncoder(NewEncoderConfig()),
+		ring:  ring,
+		out:   out,
+	}
+}
+
+// Enabled reports true for every level, since entries filtered out by the
+// inner core are still recorded.
+func (c *recorderCore) Enabled(zapcore.Level) bool {
+	return true
+}
+
+func (c *recorderCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.inner = c.inner.With(fields)
+	clone.enc = c.enc.Clone()
+	for i := range fields {
+		fields[i].AddTo(clone.enc)
+	}
+	return &clone
+}
+
+func (c *recorderCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level >= zapcore.ErrorLevel && c.out == nil {
+		// The inner core is written from Write, with the recent entries
+		// attached.
+		if c.inner.Enabled(ent.Level) {
+			return ce.AddCore(ent, c)
+		}
+		return ce
+	}
+	return c.inner.Check(ent, ce).AddCore(ent, c)
+}
+
+func (c *recorderCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if ent.Level < zapcore.ErrorLevel {
+		buf, err := c.enc.EncodeEntry(ent, fields)
+		if err != nil {
+			return err
+		}
+		c.ring.add(buf.Bytes())
+		buf.Free()
+		return nil
+	}
+
+	// Entries are kept on panic and fatal, so the crash report still
+	// has them.
+	recent := c.ring.snapshot()
+	if ent.Level < zapcore.DPanicLevel {
+		c.ring.reset()
+	}
+	if c.out != nil {
+		for _, p := range recent {
+			if _, err := c.out.Write(p); err != nil {
+				return err
+			}
+		}
+		return c.out.Sync()
+	}
+
+	if len(recent) > 0 {
+		raw := make([]json.RawMessage, len(recent))
+		for i, p := range recent {
+			raw[i] = bytes.TrimRight(p, "\n")
+		}
+		fields = append(fields[:len(fields):len(fields)], zap.Any("recent", raw))
+	}
+	if ce := c.inner.Check(ent, nil); ce != nil {
+		ce.Write(fields...)
+	}
+	return nil
+}
+
+func (c *recorderCore) Sync() error {
+	if c.out != nil {
+		if err := c.out.Sync(); err != nil {
+			return err
+		}
+	}
+	return c.inner.Sync()
+}
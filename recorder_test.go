@@ -0,0 +1,23 @@
+package log
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFlightRecorder(t *testing.T) {
+	for _, size := range []int{0, -1, 3} {
+		var out, recent syncBuffer
+		initTest(t, false, WithEncoding("json"), WithSink(&out), WithFlightRecorder(size, &recent))
+
+		Debugw("probe 1")
+		Debugw("probe 2")
+		if strings.Contains(out.String(), "probe") {
+			t.Fatalf("size %d: DEBUG entry written before an error", size)
+		}
+		Errorw("Link down")
+		if got := len(recent.lines()); got != 2 {
+			t.Errorf("size %d: %d recent entries released, want 2:\n%s", size, got, recent.String())
+		}
+	}
+}
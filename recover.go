@@ -0,0 +1,112 @@
+package log
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	"go.uber.org/zap"
+)
+
+// RecoverOption configures how Recover and Go handle a panic.
+type RecoverOption func(*recoverOptions)
+
+type recoverOptions struct {
+	fatal      bool
+	repanic    bool
+	goroutines bool
+}
+
+// RecoverFatal logs recovered panics at FATAL, terminating the process.
+func RecoverFatal() RecoverOption {
+	return func(o *recoverOptions) {
+		o.fatal = true
+	}
+}
+
+// Repanic re-raises the panic after it has been logged.
+func Repanic() RecoverOption {
+	return func(o *recoverOptions) {
+		o.repanic = true
+	}
+}
+
+// WithGoroutines attaches a dump of all goroutines to the entry.
+func WithGoroutines() RecoverOption {
+	return func(o *recoverOptions) {
+		o.goroutines = true
+	}
+}
+
+// Recover logs a panic of the calling goroutine at ERROR together with its
+// stack. It must be deferred directly:
+//
+//	defer log.Recover()
+func Recover(opts ...RecoverOption) {
+	if r := recover(); r != nil {
+		std().logPanic(r, opts)
+	}
+}
+
+// Go runs fn in a new goroutine, logging any panic it raises.
+func Go(fn func(), opts ...RecoverOption) {
+	std().Go(fn, opts...)
+}
+
+// Recover logs a panic of the calling goroutine at ERROR together with its
+// stack. It must be deferred directly.
+func (lg *Logger) Recover(opts ...RecoverOption) {
+	if r := recover(); r != nil {
+		lg.logPanic(r, opts)
+	}
+}
+
+// Go runs fn in a new goroutine, logging any panic it raises.
+func (lg *Logger) Go(fn func(), opts ...RecoverOption) {
+	go func() {
+		defer lg.Recover(opts...)
+		fn()
+	}()
+}
+
+func (lg *Logger) logPanic(r interface{}, opts []RecoverOption) {
+	o := &recoverOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	fields := []Field{
+		zap.Any("panic", r),
+		zap.ByteString("stacktrace", debug.Stack()),
+	}
+	if o.goroutines {
+		fields = append(fields, zap.ByteString("goroutines", goroutineDump()))
+	}
+
+	// The caller of a deferred function is the runtime, so it says nothing
+	// useful; the stack trace points at the panic instead.
+	base := lg.base.WithOptions(zap.WithCaller(false))
+	msg := fmt.Sprint("panic: ", r)
+	if o.repanic {
+		_ = lg.writeCrashReport(msg, debug.Stack())
+	}
+	if o.fatal {
+		base.Fatal(msg, fields...)
+	}
+	base.Error(msg, fields...)
+	if o.repanic {
+		panic(r)
+	}
+}
+
+// goroutineDump returns the stacks of all goroutines.
+func goroutineDump() []byte {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
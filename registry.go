@@ -0,0 +1,398 @@
+package log
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// levelInherit marks a named logger without a level of its own.
+const levelInherit = math.MaxInt32
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]*namedEntry)
+)
+
+// namedEntry is the registry record of a named logger. Its level and sinks
+// outlive Init, so that loggers obtained with GetLogger before the package
+// is configured follow every later reconfiguration.
+type namedEntry struct {
+	name   string
+	level  int32 // atomic; levelInherit if not set
+	sinks  atomic.Value
+	logger *Logger
+
+	mu    sync.Mutex
+	cache atomic.Value
+}
+
+// sinkCores are the cores of the sinks of a named logger, built with the
+// encoding of the package logger they were built for.
+type sinkCores struct {
+	owner *Logger
+	ws    []zapcore.WriteSyncer
+	cores []zapcore.Core
+}
+
+// GetLogger returns the logger registered under name, creating it if
+// needed. Its entries carry the name under the "logger" key and go to the
+// outputs of the package logger, including after a later Init. Its level
+// and additional outputs can be adjusted at any time with SetLoggerLevel
+// and SetLoggerSinks, or with SetLevel on the logger itself.
+//
+//	var dlog = log.GetLogger("dhcp")
+func GetLogger(name string) *Logger {
+	return lookupNamed(name).logger
+}
+
+// LoggerNames returns the names of the registered loggers, sorted.
+func LoggerNames() []string {
+	registryMu.RLock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	registryMu.RUnlock()
+	sort.Strings(names)
+	return names
+}
+
+// SetLoggerLevel sets the level of the logger registered under name,
+// registering it if needed. It may be below the package level, in which
+// case the outputs are opened up for the entries of that logger only.
+func SetLoggerLevel(name string, lvl Level) {
+	lookupNamed(name).setLevel(int32(lvl))
+}
+
+// ResetLoggerLevel makes the logger registered under name inherit its
+// level again, from its dotted parents or the package level.
+func ResetLoggerLevel(name string) {
+	lookupNamed(name).setLevel(levelInherit)
+}
+
+// SetLevels configures the package level and the levels of named loggers
+// from a single comma-separated spec of level or name=level items:
+//
+//	log.SetLevels("info,net=debug,net.dhcp.v6=warn")
+//
+// A bare level sets the package level. Named loggers inherit the level of
+// their closest dotted parent in the spec; loggers not covered by it go
+// back to the package level. Nothing is changed if the spec is invalid.
+func SetLevels(spec string) error {
+	root, named, err := parseLevels(spec)
+	if err != nil {
+		return err
+	}
+
+	registryMu.RLock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	registryMu.RUnlock()
+	for _, name := range names {
+		if _, ok := named[name]; !ok {
+			atomic.StoreInt32(&lookupNamed(name).level, levelInherit)
+		}
+	}
+	for name, lvl := range named {
+		atomic.StoreInt32(&lookupNamed(name).level, int32(lvl))
+	}
+	if root != nil {
+		// Updates the floor as well.
+		SetLevel(*root)
+		return nil
+	}
+	initMu.Lock()
+	updateFloor(std())
+	initMu.Unlock()
+	return nil
+}
+
+// parseLevels parses the spec of SetLevels.
+func parseLevels(spec string) (*Level, map[string]Level, error) {
+	var root *Level
+	named := make(map[string]Level)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		i := strings.IndexByte(item, '=')
+		if i < 0 {
+			lvl, err := ParseLevel(item)
+			if err != nil {
+				return nil, nil, err
+			}
+			root = &lvl
+			continue
+		}
+		name := strings.TrimSpace(item[:i])
+		if name == "" {
+			return nil, nil, fmt.Errorf("log: missing logger name in %q", item)
+		}
+		lvl, err := ParseLevel(item[i+1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		named[name] = lvl
+	}
+	return root, named, nil
+}
+
+// SetLoggerSinks replaces the additional outputs of the logger registered
+// under name. Its entries are written to them, encoded the same way as the
+// default output and through the same options, such as WithPrivacy,
+// WithSanitize and the sampling, as well as to the outputs of the package
+// logger. Calling it without outputs removes them.
+func SetLoggerSinks(name string, ws ...zapcore.WriteSyncer) {
+	e := lookupNamed(name)
+	e.mu.Lock()
+	e.sinks.Store(ws)
+	e.cache.Store((*sinkCores)(nil))
+	e.mu.Unlock()
+}
+
+// lookupNamed returns the registry entry of name, creating it if needed.
+func lookupNamed(name string) *namedEntry {
+	registryMu.RLock()
+	e, ok := registry[name]
+	registryMu.RUnlock()
+	if ok {
+		return e
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if e, ok := registry[name]; ok {
+		return e
+	}
+	e = &namedEntry{name: name, level: levelInherit}
+	base := zap.New(&namedCore{e: e},
+		zap.AddCaller(),
+		zap.AddCallerSkip(1),
+		zap.WithFatalHook(namedExitHook{}),
+		zap.WithClock(namedClock{}),
+	).Named(name)
+	e.logger = &Logger{
+		level: InfoLevel,
+		zap:   base.Sugar(),
+		base:  base,
+
+		exitCode: 1,
+		named:    e,
+	}
+	registry[name] = e
+	return e
+}
+
+// inLogger reports whether name is parent or one of its dotted children.
+// An empty parent covers every logger.
+func inLogger(name, parent string) bool {
+	return parent == "" || name == parent || strings.HasPrefix(name, parent+".")
+}
+
+// namedLevel returns the level that applies to the logger registered
+// under name: its own, or else that of the closest dotted parent with one,
+// so that a level set on "net" applies to "net.dhcp.v6" unless that or
+// "net.dhcp" has its own.
+func namedLevel(name string) (Level, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	for {
+		if e, ok := registry[name]; ok {
+			if lvl, ok := e.ownLevel(); ok {
+				return lvl, true
+			}
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			return 0, false
+		}
+		name = name[:i]
+	}
+}
+
+func (e *namedEntry) ownLevel() (Level, bool) {
+	lvl := atomic.LoadInt32(&e.level)
+	if lvl == levelInherit {
+		return 0, false
+	}
+	return Level(lvl), true
+}
+
+func (e *namedEntry) setLevel(lvl int32) {
+	atomic.StoreInt32(&e.level, lvl)
+	initMu.Lock()
+	updateFloor(std())
+	initMu.Unlock()
+}
+
+// effectiveLevel returns the level of the logger, inherited from its
+// parents or the package level if it has none of its own.
+func (e *namedEntry) effectiveLevel() Level {
+	if lvl, ok := namedLevel(e.name); ok {
+		return lvl
+	}
+	return std().GetLevel()
+}
+
+// sinkCores returns the cores writing to the sinks of the logger, building
+// them for lg if they were built for a previous package logger.
+func (e *namedEntry) sinkCores(lg *Logger) []zapcore.Core {
+	if sc, _ := e.cache.Load().(*sinkCores); sc != nil && sc.owner == lg {
+		return sc.cores
+	}
+	ws, _ := e.sinks.Load().([]zapcore.WriteSyncer)
+	if len(ws) == 0 {
+		return nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if sc, _ := e.cache.Load().(*sinkCores); sc != nil && sc.owner == lg {
+		return sc.cores
+	}
+	ws, _ = e.sinks.Load().([]zapcore.WriteSyncer)
+	name := lg.encoding
+	if name == "" {
+		name = "console"
+	}
+	sc := &sinkCores{owner: lg, ws: ws}
+	// The level of the logger has been applied by namedCore already.
+	all := zap.LevelEnablerFunc(func(zapcore.Level) bool { return true })
+	for _, w := range ws {
+		enc, err := newEncoder(name, NewEncoderConfig())
+		if err != nil {
+			break
+		}
+		core := zapcore.NewCore(enc, zapcore.Lock(w), all)
+		if lg.wrapOutput != nil {
+			core = lg.wrapOutput(core)
+		}
+		sc.cores = append(sc.cores, core)
+	}
+	e.cache.Store(sc)
+	return sc.cores
+}
+
+// namedCore is the core of a named logger. It applies the level of the
+// logger and hands the entries to the current package logger and to the
+// sinks of the logger.
+type namedCore struct {
+	e      *namedEntry
+	fields []zapcore.Field
+}
+
+func (c *namedCore) Enabled(lvl zapcore.Level) bool {
+	return Level(lvl) >= c.e.effectiveLevel()
+}
+
+func (c *namedCore) With(fields []zapcore.Field) zapcore.Core {
+	all := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	all = append(all, c.fields...)
+	return &namedCore{e: c.e, fields: append(all, fields...)}
+}
+
+func (c *namedCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if Silenced() || stripped(Level(ent.Level)) || !c.Enabled(ent.Level) {
+		return ce
+	}
+	lg := std()
+	ce = c.check(lg.base.Core(), ent, ce)
+	for _, core := range c.e.sinkCores(lg) {
+		ce = c.check(core, ent, ce)
+	}
+	return ce
+}
+
+func (c *namedCore) check(core zapcore.Core, ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if len(c.fields) > 0 {
+		core = core.With(c.fields)
+	}
+	return core.Check(ent, ce)
+}
+
+// Write is not used: Check adds the cores of the package logger and of
+// the sinks instead.
+func (c *namedCore) Write(zapcore.Entry, []zapcore.Field) error {
+	return nil
+}
+
+func (c *namedCore) Sync() error {
+	lg := std()
+	err := lg.base.Sync()
+	for _, core := range c.e.sinkCores(lg) {
+		if serr := core.Sync(); err == nil {
+			err = serr
+		}
+	}
+	return err
+}
+
+// namedExitHook runs the exit sequence with the exit code of the current
+// package logger.
+type namedExitHook struct{}
+
+func (namedExitHook) OnWrite(ce *zapcore.CheckedEntry, fields []zapcore.Field) {
+	(&exitHook{std()}).OnWrite(ce, fields)
+}
+
+// levelRouter applies the level of the package logger, or of the named
+// logger an entry comes from, above outputs opened up to the lowest of
+// them (see updateFloor).
+type levelRouter struct {
+	zapcore.Core
+	root zap.AtomicLevel
+}
+
+func (c *levelRouter) With(fields []zapcore.Field) zapcore.Core {
+	return &levelRouter{Core: c.Core.With(fields), root: c.root}
+}
+
+// Enabled reports the floor: the cores above check it before handing
+// entries of named loggers down to Check. Logger.Enabled applies the
+// package level.
+func (c *levelRouter) Enabled(lvl zapcore.Level) bool {
+	return !Silenced() && !stripped(Level(lvl)) && c.Core.Enabled(lvl)
+}
+
+func (c *levelRouter) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if Silenced() || stripped(Level(ent.Level)) {
+		return ce
+	}
+	lvl := Level(c.root.Level())
+	if ent.LoggerName != "" {
+		if own, ok := namedLevel(ent.LoggerName); ok {
+			lvl = own
+		}
+	}
+	if Level(ent.Level) < lvl {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// updateFloor sets the level of the outputs of lg to the lowest of its own
+// level and the levels of the named loggers. The caller holds initMu.
+func updateFloor(lg *Logger) {
+	if lg.floor == (zap.AtomicLevel{}) {
+		return
+	}
+	floor := Level(lg.atom.Level())
+	registryMu.RLock()
+	for _, e := range registry {
+		if lvl, ok := e.ownLevel(); ok && lvl < floor {
+			floor = lvl
+		}
+	}
+	registryMu.RUnlock()
+	lg.floor.SetLevel(zapcore.Level(floor))
+}
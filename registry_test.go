@@ -0,0 +1,45 @@
+package log
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestLoggerSinksWrapped(t *testing.T) {
+	initTest(t, false, WithEncoding("json"), WithSink(discard{}), WithPrivacy(PrivacyTruncate, nil), WithSanitize())
+	var out syncBuffer
+	SetLoggerSinks("dhcp", &out)
+	defer SetLoggerSinks("dhcp")
+
+	GetLogger("dhcp").With(IP("gw", net.ParseIP("192.0.2.1"))).Infow("Lease\nforged", IP("client", net.ParseIP("198.51.100.7")))
+	got := out.String()
+	for _, want := range []string{`"gw":"192.0.2.0"`, `"client":"198.51.100.0"`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("sink entry without %s:\n%s", want, got)
+		}
+	}
+	if !strings.Contains(got, `"msg":"Lease\\nforged"`) {
+		t.Errorf("sink entry not sanitized:\n%s", got)
+	}
+}
+
+func TestLoggerSinksSilenced(t *testing.T) {
+	var out syncBuffer
+	SetLoggerSinks("dhcp", &out)
+	defer SetLoggerSinks("dhcp")
+
+	initTest(t, false, WithEncoding("json"), WithSink(discard{}))
+	for _, def := range []bool{false, true} {
+		if def {
+			// The outputs of the default logger are not wrapped.
+			replace(defaultLogger())
+		}
+		Silence(true)
+		GetLogger("dhcp").Errorw("silenced")
+		Silence(false)
+	}
+	if got := out.String(); got != "" {
+		t.Errorf("silenced entries written to the sink:\n%s", got)
+	}
+}
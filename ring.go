@@ -0,0 +1,78 @@
+package log
+
+import "sync"
+
+// ringBuffer keeps the last encoded entries written to it, numbered from 1
+// in the order they were added.
+type ringBuffer struct {
+	mu      sync.Mutex
+	entries []LiveEntry
+	next    int
+	full    bool
+	seq     uint64
+}
+
+func newRingBuffer(size int) *ringBuffer {
+	return &ringBuffer{entries: make([]LiveEntry, size)}
+}
+
+// add stores a copy of p, evicting the oldest entry when full.
+func (r *ringBuffer) add(p []byte) {
+	entry := make([]byte, len(p))
+	copy(entry, p)
+	r.push(LiveEntry{Data: entry})
+}
+
+// push stores e with the next sequence number, evicting the oldest entry
+// when full, and returns it.
+func (r *ringBuffer) push(e LiveEntry) LiveEntry {
+	r.mu.Lock()
+	r.seq++
+	e.Seq = r.seq
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+	r.mu.Unlock()
+	return e
+}
+
+// snapshot returns the stored entries, oldest first.
+func (r *ringBuffer) snapshot() [][]byte {
+	entries := r.after(0)
+	out := make([][]byte, len(entries))
+	for i, e := range entries {
+		out[i] = e.Data
+	}
+	return out
+}
+
+// after returns the stored entries numbered after seq, oldest first.
+func (r *ringBuffer) after(seq uint64) []LiveEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []LiveEntry
+	add := func(entries []LiveEntry) {
+		for _, e := range entries {
+			if e.Seq > seq {
+				out = append(out, e)
+			}
+		}
+	}
+	if r.full {
+		add(r.entries[r.next:])
+	}
+	add(r.entries[:r.next])
+	return out
+}
+
+// reset drops all stored entries. The numbering goes on.
+func (r *ringBuffer) reset() {
+	r.mu.Lock()
+	for i := range r.entries {
+		r.entries[i] = LiveEntry{}
+	}
+	r.next, r.full = 0, false
+	r.mu.Unlock()
+}
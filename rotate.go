@@ -0,0 +1,387 @@
+package log
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// RotateConfig configures a RotatingFile.
+type RotateConfig struct {
+	// Filename is the file written to. Rotated files are kept next to it
+	// as <name>-<timestamp><ext>.
+	Filename string
+	// MaxSize is the size in bytes at which the file is rotated. Zero
+	// disables size based rotation; Rotate can still be called explicitly.
+	MaxSize int64
+	// Compress gzips rotated files in the background.
+	Compress bool
+
+	// MaxTotalSize bounds the size in bytes of the current file and all
+	// rotated files together. The oldest rotated files are deleted until
+	// the total fits or only MinBackups are left. Zero disables the limit.
+	MaxTotalSize int64
+	// MaxAge deletes rotated files older than this. Zero disables it.
+	MaxAge time.Duration
+	// MinBackups is the number of most recent rotated files kept
+	// regardless of MaxAge and MaxTotalSize, so a device that was switched
+	// off for a long time does not lose the logs leading up to it.
+	MinBackups int
+}
+
+// RotatingFile is a zapcore.WriteSyncer writing to a file that is rotated
+// when it grows past RotateConfig.MaxSize.
+type RotatingFile struct {
+	cfg RotateConfig
+
+	mu     sync.Mutex
+	f      *os.File
+	size   int64
+	err    error
+	closed bool
+
+	// maint serializes compression and retention, which run in the
+	// background after a rotation.
+	maint   sync.Mutex
+	pending sync.WaitGroup
+}
+
+// NewRotatingFile opens cfg.Filename for appending, creating it and its
+// directory if needed.
+func NewRotatingFile(cfg RotateConfig) (*RotatingFile, error) {
+	r := &RotatingFile{cfg: cfg}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	if err := r.prune(); err != nil {
+		r.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(r.cfg.Filename), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(r.cfg.Filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.f, r.size = f, info.Size()
+	return nil
+}
+
+// Write writes p, rotating first if p would take the file past MaxSize. If
+// the rotation fails, p is still appended to the current file, the error
+// is reported by Sync and the next write tries again.
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.reopen(); err != nil {
+		return 0, err
+	}
+	if r.cfg.MaxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.cfg.MaxSize {
+		if err := r.rotate(); err != nil {
+			if r.f == nil {
+				return 0, err
+			}
+			r.err = err
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Rotate closes the current file, moves it aside and opens a new one.
+func (r *RotatingFile) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.reopen(); err != nil {
+		return err
+	}
+	return r.rotate()
+}
+
+// reopen opens the file again if an earlier rotation could not. The caller
+// holds r.mu.
+func (r *RotatingFile) reopen() error {
+	if r.closed {
+		return os.ErrClosed
+	}
+	if r.f == nil {
+		return r.open()
+	}
+	return nil
+}
+
+// rotate moves the current file aside and opens a new one. If the file
+// cannot be moved, it is reopened for appending instead. The caller holds
+// r.mu.
+func (r *RotatingFile) rotate() error {
+	err := r.f.Close()
+	r.f = nil
+	backup := r.backupName(time.Now())
+	if err == nil {
+		err = os.Rename(r.cfg.Filename, backup)
+	}
+	if oerr := r.open(); oerr != nil {
+		return oerr
+	}
+	if err != nil {
+		return err
+	}
+	r.pending.Add(1)
+	go func() {
+		defer r.pending.Done()
+		if err := r.maintain(backup); err != nil {
+			r.mu.Lock()
+			r.err = err
+			r.mu.Unlock()
+		}
+	}()
+	return nil
+}
+
+// maintain compresses a freshly rotated file if configured and applies the
+// retention policy.
+func (r *RotatingFile) maintain(backup string) error {
+	r.maint.Lock()
+	defer r.maint.Unlock()
+	if r.cfg.Compress {
+		if err := compressFile(backup); err != nil {
+			return err
+		}
+	}
+	return r.prune()
+}
+
+// prune deletes rotated files exceeding MaxAge or MaxTotalSize.
+func (r *RotatingFile) prune() error {
+	if r.cfg.MaxAge <= 0 && r.cfg.MaxTotalSize <= 0 {
+		return nil
+	}
+	files, err := r.backups()
+	if err != nil {
+		return err
+	}
+
+	keep := files[:0]
+	for i, b := range files {
+		recent := len(files)-i <= r.cfg.MinBackups
+		if r.cfg.MaxAge > 0 && !recent && time.Since(b.time) > r.cfg.MaxAge {
+			if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		keep = append(keep, b)
+	}
+
+	if r.cfg.MaxTotalSize <= 0 {
+		return nil
+	}
+	r.mu.Lock()
+	total := r.size
+	r.mu.Unlock()
+	for _, b := range keep {
+		total += b.size
+	}
+	for i, b := range keep {
+		if total <= r.cfg.MaxTotalSize || len(keep)-i <= r.cfg.MinBackups {
+			break
+		}
+		if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		total -= b.size
+	}
+	return nil
+}
+
+// backupName returns an unused name for a rotated file, stepping t forward
+// if rotations happen within the same millisecond.
+func (r *RotatingFile) backupName(t time.Time) string {
+	ext := filepath.Ext(r.cfg.Filename)
+	prefix := strings.TrimSuffix(r.cfg.Filename, ext)
+	for {
+		name := prefix + "-" + t.Format(backupTimeFormat) + ext
+		if !exists(name) && !exists(name+".gz") {
+			return name
+		}
+		t = t.Add(time.Millisecond)
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+type backupFile struct {
+	path string
+	time time.Time
+	size int64
+}
+
+// backups returns the rotated files, compressed or not, oldest first.
+func (r *RotatingFile) backups() ([]backupFile, error) {
+	ext := filepath.Ext(r.cfg.Filename)
+	prefix := strings.TrimSuffix(filepath.Base(r.cfg.Filename), ext) + "-"
+	dir := filepath.Dir(r.cfg.Filename)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []backupFile
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ext)
+		t, err := time.ParseInLocation(backupTimeFormat, strings.TrimPrefix(stamp, prefix), time.Local)
+		if err != nil {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			// Deleted or renamed by a concurrent compression.
+			continue
+		}
+		files = append(files, backupFile{filepath.Join(dir, name), t, info.Size()})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].time.Before(files[j].time) })
+	return files, nil
+}
+
+// Sync commits the current file to stable storage and reports the last
+// background compression or retention error.
+func (r *RotatingFile) Sync() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	err := r.err
+	r.err = nil
+	if r.f == nil {
+		return err
+	}
+	if serr := r.f.Sync(); serr != nil {
+		return serr
+	}
+	return err
+}
+
+// Close closes the file and waits for pending compression and retention.
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	var err error
+	if r.f != nil {
+		err = r.f.Close()
+		r.f = nil
+	}
+	r.closed = true
+	r.mu.Unlock()
+	r.pending.Wait()
+	return err
+}
+
+// compressFile replaces path with path.gz.
+func compressFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := path + ".gz.tmp"
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(out)
+	_, err = io.Copy(gz, in)
+	if err == nil {
+		err = gz.Close()
+	}
+	if err == nil {
+		err = out.Sync()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path+".gz")
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Remove(path)
+}
+
+// GzipWriter is a zapcore.WriteSyncer compressing everything written to it
+// into a single gzip stream, for sinks on constrained uplinks. Sync flushes
+// a complete deflate block so the peer can decode everything written so far.
+type GzipWriter struct {
+	mu  sync.Mutex
+	out io.Writer
+	gz  *gzip.Writer
+}
+
+// NewGzipWriter returns a GzipWriter writing to out with the given
+// compression level (see compress/gzip).
+func NewGzipWriter(out io.Writer, level int) (*GzipWriter, error) {
+	gz, err := gzip.NewWriterLevel(out, level)
+	if err != nil {
+		return nil, err
+	}
+	return &GzipWriter{out: out, gz: gz}, nil
+}
+
+// Write compresses p.
+func (w *GzipWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.gz.Write(p)
+}
+
+// Sync flushes pending compressed data and syncs the output if it supports
+// it.
+func (w *GzipWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.gz.Flush(); err != nil {
+		return err
+	}
+	if s, ok := w.out.(interface{ Sync() error }); ok {
+		return ignoreSyncError(s.Sync())
+	}
+	return nil
+}
+
+// Close terminates the gzip stream and closes the output if it is an
+// io.Closer.
+func (w *GzipWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.gz.Close(); err != nil {
+		return err
+	}
+	if c, ok := w.out.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package log
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotateRecoversFromFailedRename(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	name := filepath.Join(dir, "app.log")
+	r, err := NewRotatingFile(RotateConfig{Filename: name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// The file vanishing makes the rename fail.
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Rotate(); err == nil {
+		t.Fatal("Rotate succeeded without a file")
+	}
+	if _, err := r.Write([]byte("after\n")); err != nil {
+		t.Fatalf("Write after a failed rotation: %v", err)
+	}
+	if err := r.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "after\n" {
+		t.Errorf("file holds %q, want %q", data, "after\n")
+	}
+}
+
+func TestRotateWriteAfterClose(t *testing.T) {
+	r, err := NewRotatingFile(RotateConfig{Filename: filepath.Join(t.TempDir(), "app.log")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Write([]byte("x\n")); err != os.ErrClosed {
+		t.Errorf("Write after Close: %v, want %v", err, os.ErrClosed)
+	}
+	if err := r.Rotate(); err != os.ErrClosed {
+		t.Errorf("Rotate after Close: %v, want %v", err, os.ErrClosed)
+	}
+}
+
+// writeBackups creates n rotated files of size bytes for name, one a day
+// old, the oldest first.
+func writeBackups(t *testing.T, name string, n, size int) []string {
+	t.Helper()
+	ext := filepath.Ext(name)
+	prefix := strings.TrimSuffix(name, ext)
+	var paths []string
+	for i := n; i > 0; i-- {
+		ts := time.Now().Add(-time.Duration(i) * 24 * time.Hour)
+		p := prefix + "-" + ts.Format(backupTimeFormat) + ext
+		if err := ioutil.WriteFile(p, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, p)
+	}
+	return paths
+}
+
+func TestRotateMaxTotalSizeKeepsMinBackups(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	backups := writeBackups(t, name, 4, 100)
+	r, err := NewRotatingFile(RotateConfig{Filename: name, MaxTotalSize: 150, MinBackups: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	for i, p := range backups {
+		want := i >= 2
+		if got := exists(p); got != want {
+			t.Errorf("backup %d kept: %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestRotateMaxSize(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		name := "plain"
+		if compress {
+			name = "compress"
+		}
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			file := filepath.Join(dir, "app.log")
+			r, err := NewRotatingFile(RotateConfig{Filename: file, MaxSize: 10, Compress: compress})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range []string{"aaaa\n", "bbbb\n", "cccc\n", "dddd\n", "eeee\n"} {
+				if _, err := r.Write([]byte(e)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// Close waits for the background compression.
+			if err := r.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != "eeee\n" {
+				t.Errorf("current file holds %q, want %q", data, "eeee\n")
+			}
+			files, err := r.backups()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []string{"aaaa\nbbbb\n", "cccc\ndddd\n"}
+			if len(files) != len(want) {
+				t.Fatalf("%d rotated files, want %d", len(files), len(want))
+			}
+			for i, b := range files {
+				if got := strings.HasSuffix(b.path, ".gz"); got != compress {
+					t.Errorf("%s compressed: %v, want %v", b.path, got, compress)
+				}
+				if got := readBackup(t, b.path); got != want[i] {
+					t.Errorf("%s holds %q, want %q", b.path, got, want[i])
+				}
+			}
+		})
+	}
+}
+
+// readBackup returns the contents of a rotated file, compressed or not.
+func readBackup(t *testing.T, path string) string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = gz
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRotateRetention(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  RotateConfig
+		kept []bool // the backups kept, oldest first
+	}{
+		{"none", RotateConfig{}, []bool{true, true, true, true}},
+		{"max age", RotateConfig{MaxAge: 60 * time.Hour}, []bool{false, false, true, true}},
+		{"max age min backups", RotateConfig{MaxAge: 30 * time.Hour, MinBackups: 3}, []bool{false, true, true, true}},
+		{"max total size", RotateConfig{MaxTotalSize: 250}, []bool{false, false, true, true}},
+		{"both", RotateConfig{MaxAge: 60 * time.Hour, MaxTotalSize: 150}, []bool{false, false, false, true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Filename = filepath.Join(t.TempDir(), "app.log")
+			backups := writeBackups(t, tt.cfg.Filename, 4, 100)
+			r, err := NewRotatingFile(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+
+			for i, p := range backups {
+				if got := exists(p); got != tt.kept[i] {
+					t.Errorf("backup %d kept: %v, want %v", i, got, tt.kept[i])
+				}
+			}
+		})
+	}
+}
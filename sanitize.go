@@ -0,0 +1,192 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// WithSanitize cleans the message and the string, fmt.Stringer and error
+// fields of every entry with Sanitize, so that untrusted input such as
+// hostnames or SSIDs cannot forge entries, mangle terminals or break
+// log pipelines. Values nested in objects, arrays and reflected fields are
+// left alone.
+func WithSanitize() Option {
+	return WrapCore(func(c zapcore.Core) zapcore.Core {
+		return &sanitizeCore{c}
+	})
+}
+
+// Sanitize returns s with ANSI escape sequences removed, other control
+// characters escaped as \n, \r, \xHH or, for the C1 controls, \uHHHH,
+// and invalid UTF-8 replaced with U+FFFD. Tabs are kept. s is returned as
+// is if it needs no change.
+func Sanitize(s string) string {
+	if isClean(s) {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == 0x1b:
+			i += ansiLen(s[i:])
+			continue
+		case c == '\n':
+			b.WriteString(`\n`)
+		case c == '\r':
+			b.WriteString(`\r`)
+		case c < 0x20 && c != '\t' || c == 0x7f:
+			fmt.Fprintf(&b, `\x%02x`, c)
+		case c < utf8.RuneSelf:
+			b.WriteByte(c)
+		default:
+			r, size := utf8.DecodeRuneInString(s[i:])
+			switch {
+			case r == utf8.RuneError && size == 1:
+				b.WriteRune(utf8.RuneError)
+			case r >= 0x80 && r < 0xa0:
+				// C1 control characters, including the 8-bit CSI.
+				fmt.Fprintf(&b, `\u%04x`, r)
+			default:
+				b.WriteString(s[i : i+size])
+			}
+			i += size
+			continue
+		}
+		i++
+	}
+	return b.String()
+}
+
+// isClean reports whether s is valid UTF-8 without control characters
+// other than tabs.
+func isClean(s string) bool {
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c < 0x20 && c != '\t' || c == 0x7f {
+				return false
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 || r >= 0x80 && r < 0xa0 {
+			return false
+		}
+		i += size
+	}
+	return true
+}
+
+// ansiLen returns the length of the escape sequence at the start of s,
+// which starts with ESC: a CSI sequence ("\x1b[31m"), an OSC or other
+// string sequence terminated by BEL or ST ("\x1b]0;title\a"), or ESC and
+// a single character.
+func ansiLen(s string) int {
+	if len(s) < 2 {
+		return len(s)
+	}
+	switch s[1] {
+	case '[':
+		// Parameters and intermediates, then a final byte in 0x40-0x7e.
+		for i := 2; i < len(s); i++ {
+			if s[i] >= 0x40 && s[i] <= 0x7e {
+				return i + 1
+			}
+			if s[i] < 0x20 || s[i] > 0x3f {
+				return i
+			}
+		}
+		return len(s)
+	case ']', 'P', 'X', '^', '_':
+		for i := 2; i < len(s); i++ {
+			if s[i] == '\a' {
+				return i + 1
+			}
+			if s[i] == 0x1b && i+1 < len(s) && s[i+1] == '\\' {
+				return i + 2
+			}
+		}
+		return len(s)
+	}
+	return 2
+}
+
+// sanitizeFields returns fields with the values Sanitize changes replaced,
+// or fields itself if there are none.
+func sanitizeFields(fields []zapcore.Field) []zapcore.Field {
+	var out []zapcore.Field
+	for i, f := range fields {
+		var s string
+		switch f.Type {
+		case zapcore.StringType:
+			s = f.String
+		case zapcore.StringerType:
+			s = stringerValue(f)
+		case zapcore.ErrorType:
+			err, _ := f.Interface.(error)
+			if err == nil {
+				continue
+			}
+			s = err.Error()
+		default:
+			continue
+		}
+		clean := Sanitize(s)
+		if clean == s {
+			continue
+		}
+		if out == nil {
+			out = append([]zapcore.Field(nil), fields...)
+		}
+		out[i] = zap.String(f.Key, clean)
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
+
+// stringerValue returns the string of a fmt.Stringer field, the way zap
+// encodes it.
+func stringerValue(f zapcore.Field) (s string) {
+	defer func() {
+		if r := recover(); r != nil {
+			s = fmt.Sprintf("PANIC=%v", r)
+		}
+	}()
+	if v, ok := f.Interface.(fmt.Stringer); ok {
+		return v.String()
+	}
+	return ""
+}
+
+// sanitizeCore cleans the entries written to the core it wraps.
+type sanitizeCore struct {
+	zapcore.Core
+}
+
+func (c *sanitizeCore) With(fields []zapcore.Field) zapcore.Core {
+	return &sanitizeCore{c.Core.With(sanitizeFields(fields))}
+}
+
+func (c *sanitizeCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *sanitizeCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = Sanitize(ent.Message)
+	if ce := c.Core.Check(ent, nil); ce != nil {
+		ce.Write(sanitizeFields(fields)...)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package log
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// FieldKind is the type of a field value required by a Schema.
+type FieldKind int
+
+// Field kinds. Integer kinds cover signed and unsigned values of any size,
+// and StringKind covers fmt.Stringer values.
+const (
+	AnyKind FieldKind = iota
+	StringKind
+	IntKind
+	FloatKind
+	BoolKind
+	DurationKind
+	TimeKind
+	ErrorKind
+)
+
+var kindNames = [...]string{"any", "string", "int", "float", "bool", "duration", "time", "error"}
+
+func (k FieldKind) String() string {
+	if k >= 0 && int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return fmt.Sprintf("FieldKind(%d)", int(k))
+}
+
+// fieldKind returns the kind of the value of f.
+func fieldKind(f zapcore.Field) FieldKind {
+	switch f.Type {
+	case zapcore.StringType, zapcore.ByteStringType, zapcore.StringerType:
+		return StringKind
+	case zapcore.Int64Type, zapcore.Int32Type, zapcore.Int16Type, zapcore.Int8Type,
+		zapcore.Uint64Type, zapcore.Uint32Type, zapcore.Uint16Type, zapcore.Uint8Type,
+		zapcore.UintptrType:
+		return IntKind
+	case zapcore.Float64Type, zapcore.Float32Type:
+		return FloatKind
+	case zapcore.BoolType:
+		return BoolKind
+	case zapcore.DurationType:
+		return DurationKind
+	case zapcore.TimeType, zapcore.TimeFullType:
+		return TimeKind
+	case zapcore.ErrorType:
+		return ErrorKind
+	}
+	return AnyKind
+}
+
+// Schema lists the keys an event requires and the kind of their values.
+type Schema map[string]FieldKind
+
+var (
+	schemasMu sync.RWMutex
+	schemas   = make(map[string]Schema)
+)
+
+// RegisterSchema sets the schema of the entries of event, i.e. those with
+// an "event" field of that value, such as the entries logged with Event
+// for an EventType of that name. The schema is checked when
+// ValidateSchemas is given to Init.
+//
+//	log.RegisterSchema("DHCP_LEASE_EXPIRED", log.Schema{
+//		"mac":   log.StringKind,
+//		"iface": log.StringKind,
+//	})
+func RegisterSchema(event string, s Schema) {
+	copied := make(Schema, len(s))
+	for k, v := range s {
+		copied[k] = v
+	}
+	schemasMu.Lock()
+	schemas[event] = copied
+	schemasMu.Unlock()
+}
+
+func lookupSchema(event string) (Schema, bool) {
+	schemasMu.RLock()
+	defer schemasMu.RUnlock()
+	s, ok := schemas[event]
+	return s, ok
+}
+
+// ValidateSchemas checks the entries of events with a schema registered
+// with RegisterSchema. A missing key or a value of the wrong kind panics
+// in debug mode, which catches it in development and tests, and otherwise
+// adds a schema_error field describing the problem to the entry. Only the
+// fields outside of namespaces are considered.
+func ValidateSchemas() Option {
+	return WrapCore(func(c zapcore.Core) zapcore.Core {
+		return &schemaCore{Core: c}
+	})
+}
+
+// schemaCore validates the entries written to the core it wraps against
+// the schema of their event.
+type schemaCore struct {
+	zapcore.Core
+	ctx []zapcore.Field
+}
+
+func (c *schemaCore) With(fields []zapcore.Field) zapcore.Core {
+	ctx := make([]zapcore.Field, 0, len(c.ctx)+len(fields))
+	ctx = append(append(ctx, c.ctx...), fields...)
+	return &schemaCore{Core: c.Core.With(fields), ctx: ctx}
+}
+
+func (c *schemaCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *schemaCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ce := c.Core.Check(ent, nil)
+	if ce == nil {
+		return nil
+	}
+	if problem := checkSchema(c.ctx, fields); problem != "" {
+		if std().development {
+			panic("log: " + problem)
+		}
+		fields = append(fields[:len(fields):len(fields)], zap.String("schema_error", problem))
+	}
+	ce.Write(fields...)
+	return nil
+}
+
+// checkSchema returns a description of the problems of the entry with the
+// context fields ctx and fields, or an empty string if its event has no
+// schema or the entry matches it.
+func checkSchema(ctx, fields []zapcore.Field) string {
+	// Fields after a namespace are nested in it.
+	top := make([]zapcore.Field, 0, len(ctx)+len(fields))
+	for _, f := range append(ctx[:len(ctx):len(ctx)], fields...) {
+		if f.Type == zapcore.NamespaceType {
+			break
+		}
+		top = append(top, f)
+	}
+	var event string
+	for _, f := range top {
+		if f.Key == "event" && f.Type == zapcore.StringType {
+			event = f.String
+		}
+	}
+	s, ok := lookupSchema(event)
+	if event == "" || !ok {
+		return ""
+	}
+
+	kinds := make(map[string]FieldKind, len(top))
+	for _, f := range top {
+		kinds[f.Key] = fieldKind(f)
+	}
+	var problems []string
+	for key, want := range s {
+		got, ok := kinds[key]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("missing %q", key))
+		case want != AnyKind && got != want:
+			problems = append(problems, fmt.Sprintf("%q is %s, not %s", key, got, want))
+		}
+	}
+	if len(problems) == 0 {
+		return ""
+	}
+	sort.Strings(problems)
+	return "event " + event + ": " + strings.Join(problems, ", ")
+}
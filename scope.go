@@ -0,0 +1,64 @@
+package log
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	scopesMu sync.Mutex
+	scopes   = make(map[string]bool)
+)
+
+// Scope returns the logger of a debug scope, a named logger (see GetLogger)
+// whose DEBUG entries are only logged while the scope is enabled with
+// EnableScopes, whatever the package level:
+//
+//	var wlog = log.Scope("wifi")
+//	wlog.Debugf("scan: %d networks", n)
+func Scope(name string) *Logger {
+	return GetLogger(name)
+}
+
+// EnableScopes enables the debug scopes listed in spec, comma-separated,
+// and disables those enabled by an earlier call, in the spirit of
+// DEBUG=foo,bar in node:
+//
+//	log.EnableScopes(os.Getenv("NDM_DEBUG")) // "wifi,dhcpv6"
+//
+// Enabling a scope also enables its dotted children, so "net" covers
+// "net.dhcp". Scopes are named loggers: enabling one sets its level to
+// DEBUG, disabling it makes it inherit again.
+func EnableScopes(spec string) {
+	next := make(map[string]bool)
+	for _, name := range strings.Split(spec, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			next[name] = true
+		}
+	}
+
+	scopesMu.Lock()
+	defer scopesMu.Unlock()
+	for name := range scopes {
+		if !next[name] {
+			ResetLoggerLevel(name)
+		}
+	}
+	for name := range next {
+		SetLoggerLevel(name, DebugLevel)
+	}
+	scopes = next
+}
+
+// EnabledScopes returns the scopes enabled by EnableScopes, sorted.
+func EnabledScopes() []string {
+	scopesMu.Lock()
+	defer scopesMu.Unlock()
+	names := make([]string, 0, len(scopes))
+	for name := range scopes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
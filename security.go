@@ -0,0 +1,82 @@
+package log
+
+import (
+	"errors"
+	"syscall"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// securityCore writes entries straight to the unsampled and unqueued
+// outputs and syncs them after every entry.
+type securityCore struct {
+	zapcore.Core
+}
+
+func (c *securityCore) With(fields []zapcore.Field) zapcore.Core {
+	return &securityCore{Core: c.Core.With(fields)}
+}
+
+// Check adds the core itself rather than delegating, so that Write, and
+// with it the sync, always goes through securityCore.
+func (c *securityCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *securityCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	// The outputs register themselves when checked; writing to the core
+	// directly would only run its hooks.
+	if ce := c.Core.Check(ent, nil); ce != nil {
+		ce.Write(fields...)
+	}
+	return ignoreSyncError(c.Core.Sync())
+}
+
+// ignoreSyncError drops the errors returned when syncing outputs that do not
+// support it, such as a stdout attached to a pipe or terminal.
+func ignoreSyncError(err error) error {
+	if errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.ENOTSUP) || errors.Is(err, syscall.ENOTTY) {
+		return nil
+	}
+	return err
+}
+
+// newSecurityLogger derives the security logger from lg. It writes to the
+// outputs below the sampler and the output queues, through the swap of lg
+// so that it follows Reconfigure.
+func (lg *Logger) newSecurityLogger() *Logger {
+	if lg.secSwap == nil {
+		return nil
+	}
+	sw := lg.secSwap
+	base := lg.base.WithOptions(zap.WrapCore(func(zapcore.Core) zapcore.Core {
+		return &securityCore{Core: &swapCore{sw: sw}}
+	})).With(zap.String("category", "security"))
+
+	sec := *lg
+	sec.zap = base.Sugar()
+	sec.base = base
+	sec.security = nil
+	return &sec
+}
+
+// Security returns a logger for security events such as authentication
+// failures and configuration changes. Its entries are tagged with
+// category=security, are never sampled nor queued (see WithNonBlocking)
+// and are synced to the outputs as soon as they are written. The logger
+// follows Reconfigure.
+func Security() *Logger {
+	return std().Security()
+}
+
+// Security returns the security event logger derived from lg.
+func (lg *Logger) Security() *Logger {
+	if lg.security == nil {
+		return lg
+	}
+	return lg.security
+}
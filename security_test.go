@@ -0,0 +1,63 @@
+package log
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stuckOutput blocks its first write until release is closed.
+type stuckOutput struct {
+	syncBuffer
+	once    sync.Once
+	release chan struct{}
+}
+
+func (w *stuckOutput) Write(p []byte) (int, error) {
+	w.once.Do(func() { <-w.release })
+	return w.syncBuffer.Write(p)
+}
+
+func TestSecurityBypassesQueues(t *testing.T) {
+	out := &stuckOutput{release: make(chan struct{})}
+	initTest(t, false, WithEncoding("json"), WithAsyncSink(out, AsyncConfig{BufferSize: 1, Overflow: DropNewest}))
+
+	for i := 0; i < 10; i++ {
+		Infow("routine", "i", i)
+	}
+	done := make(chan struct{})
+	go func() {
+		Security().Warningw("Login failed", "user", "admin")
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(out.release)
+	<-done
+	_ = Sync()
+
+	if !strings.Contains(out.String(), "Login failed") {
+		t.Fatalf("security entry dropped by the queue:\n%s", out.String())
+	}
+	if n := len(out.lines()); n >= 11 {
+		t.Fatalf("%d entries written, want some dropped by the queue", n)
+	}
+}
+
+func TestSecurityFollowsReconfigure(t *testing.T) {
+	var first, second syncBuffer
+	initTest(t, false, WithEncoding("json"), WithSink(&first))
+	sec := Security()
+
+	if err := Reconfigure(Config{Options: []Option{WithEncoding("json"), WithSink(&second)}}); err != nil {
+		t.Fatal(err)
+	}
+	sec.Infow("Configuration changed")
+
+	if strings.Contains(first.String(), "Configuration changed") {
+		t.Error("security entry written to the previous output")
+	}
+	if !strings.Contains(second.String(), `"category":"security"`) {
+		t.Errorf("security entry missing from the new output:\n%s", second.String())
+	}
+}
@@ -0,0 +1,25 @@
+package log
+
+import (
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// sequence numbers the entries of the process; it is not reset by Init,
+// so that a reconfiguration does not look like a restart downstream.
+var sequence uint64
+
+// WithSequence stamps every entry with a number incremented by one for each
+// entry written, under key ("seq" if empty), so that entries lost or
+// reordered by a downstream pipeline can be detected. Entries dropped by
+// the level or the sampler do not consume a number.
+func WithSequence(key string) Option {
+	if key == "" {
+		key = "seq"
+	}
+	return withEnricher(func(_ zapcore.Entry, fields []zapcore.Field) []zapcore.Field {
+		return append(fields, zap.Uint64(key, atomic.AddUint64(&sequence, 1)))
+	})
+}
@@ -0,0 +1,123 @@
+package log
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// defaultExitTimeout bounds the flushes on exit unless set with
+// WithExitTimeout.
+const defaultExitTimeout = 2 * time.Second
+
+var errExitTimeout = errors.New("log: flush on exit timed out")
+
+// exitAt holds the deadline of the flushes on exit once a Fatal entry has
+// been logged. Outputs are synced while the entry is written, before the
+// fatal hook runs, so the deadline is set when the entry is checked.
+var exitAt atomic.Value // time.Time
+
+// WithExitTimeout bounds how long Fatal* functions and Close wait for the
+// outputs to be flushed, so that the process exits in time even when a
+// network sink is down; entries not delivered by then are lost. Defaults to
+// 2 seconds. A negative value waits as long as it takes.
+func WithExitTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.exitTimeout = d
+	}
+}
+
+// Close flushes the package logger and ends its background work, waiting
+// at most the exit timeout (see WithExitTimeout), then reverts to the
+// default console logger. Call it before main returns:
+//
+//	defer log.Close()
+func Close() error {
+	initMu.Lock()
+	prev := std()
+	next := defaultLogger()
+	updateFloor(next)
+	current.Store(next)
+	initMu.Unlock()
+
+	return within(prev.exitDeadline(), func() error {
+		err := prev.Sync()
+		prev.stop()
+		return err
+	})
+}
+
+// exitDeadline returns the time by which flushing on exit must end, or the
+// zero time if it is not bounded.
+func (lg *Logger) exitDeadline() time.Time {
+	d := lg.exitTimeout
+	if d < 0 {
+		return time.Time{}
+	}
+	if d == 0 {
+		d = defaultExitTimeout
+	}
+	return time.Now().Add(d)
+}
+
+// startExit sets the deadline of the flushes on exit, unless already set,
+// and returns it.
+func (lg *Logger) startExit() time.Time {
+	if d, ok := exitAt.Load().(time.Time); ok {
+		return d
+	}
+	d := lg.exitDeadline()
+	exitAt.Store(d)
+	return d
+}
+
+// exitCore starts the exit of the process when a Fatal entry is checked,
+// with the exit timeout of the logger of hook.
+type exitCore struct {
+	zapcore.Core
+	hook *exitHook
+}
+
+func (c *exitCore) With(fields []zapcore.Field) zapcore.Core {
+	return &exitCore{c.Core.With(fields), c.hook}
+}
+
+func (c *exitCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level >= zapcore.FatalLevel {
+		c.hook.lg.startExit()
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// exitSyncer bounds the Sync of an output by the exit deadline once the
+// process is exiting.
+type exitSyncer struct {
+	zapcore.WriteSyncer
+}
+
+func (w exitSyncer) Sync() error {
+	if d, ok := exitAt.Load().(time.Time); ok {
+		return within(d, w.WriteSyncer.Sync)
+	}
+	return w.WriteSyncer.Sync()
+}
+
+// within runs fn, giving up waiting for it at deadline unless that is
+// zero. fn then keeps running in the background.
+func within(deadline time.Time, fn func() error) error {
+	if deadline.IsZero() {
+		return fn()
+	}
+	done := make(chan error, 1)
+	go func() { done <- fn() }()
+	t := time.NewTimer(time.Until(deadline))
+	defer t.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-t.C:
+		return errExitTimeout
+	}
+}
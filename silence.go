@@ -0,0 +1,25 @@
+package log
+
+import "sync/atomic"
+
+// silenced is set by Silence.
+var silenced int32
+
+// Silence suppresses all output of the package logger and the named
+// loggers while on, whatever their levels, which are kept for when it is
+// turned off again. It is meant for CLI commands whose stdout must only
+// hold their machine-readable result. Fatal* functions still exit. The
+// audit channel and the streams are not affected; SetLevel(OffLevel)
+// silences a single logger.
+func Silence(on bool) {
+	var v int32
+	if on {
+		v = 1
+	}
+	atomic.StoreInt32(&silenced, v)
+}
+
+// Silenced reports whether output is suppressed by Silence.
+func Silenced() bool {
+	return atomic.LoadInt32(&silenced) != 0
+}
@@ -0,0 +1,194 @@
+package log
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// httpRequest is a request served by fakeHTTP, with the body
+// decompressed.
+type httpRequest struct {
+	path   string
+	query  url.Values
+	header http.Header
+	body   string
+}
+
+// fakeHTTP records the requests it serves and answers them with status.
+type fakeHTTP struct {
+	status int
+
+	mu   sync.Mutex
+	reqs []httpRequest
+}
+
+func (f *fakeHTTP) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body = gz
+	}
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	f.reqs = append(f.reqs, httpRequest{r.URL.Path, r.URL.Query(), r.Header, string(b)})
+	f.mu.Unlock()
+	if f.status != 0 {
+		w.WriteHeader(f.status)
+	}
+}
+
+func (f *fakeHTTP) requests() []httpRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]httpRequest(nil), f.reqs...)
+}
+
+// redirect is a transport sending every request to the server at addr.
+type redirect struct {
+	addr string
+}
+
+func (t redirect) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.URL.Scheme, req.URL.Host = "http", t.addr
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// remoteSink is implemented by the HTTP sinks.
+type remoteSink interface {
+	Write(p []byte) (int, error)
+	Sync() error
+	Close() error
+}
+
+func TestHTTPSinks(t *testing.T) {
+	entries := []string{`{"msg":"a"}` + "\n", "plain b\n"}
+	tests := []struct {
+		name    string
+		gzip    bool
+		new     func(srv *httptest.Server, gzip bool) (remoteSink, error)
+		path    string
+		query   url.Values
+		header  http.Header
+		body    string
+		partial bool // body is only a part of the request body
+	}{
+		{
+			name: "splunk",
+			new: func(srv *httptest.Server, gzip bool) (remoteSink, error) {
+				return NewSplunkSink(SplunkConfig{URL: srv.URL + "/", Token: "secret", Host: "cpe", Index: "net", Gzip: gzip})
+			},
+			path:    "/services/collector/event",
+			header:  http.Header{"Authorization": {"Splunk secret"}},
+			body:    `"host":"cpe","index":"net","event":{"msg":"a"}}` + "\n",
+			partial: true,
+		},
+		{
+			name: "datadog",
+			new: func(srv *httptest.Server, gzip bool) (remoteSink, error) {
+				return NewDatadogSink(DatadogConfig{
+					APIKey: "secret", Service: "ndmd", Tags: "env:test", Gzip: gzip,
+					HTTPClient: &http.Client{Transport: redirect{srv.Listener.Addr().String()}},
+				})
+			},
+			path:   "/api/v2/logs",
+			query:  url.Values{"service": {"ndmd"}, "ddtags": {"env:test"}},
+			header: http.Header{"Dd-Api-Key": {"secret"}, "Content-Type": {"application/json"}},
+			body:   `[{"msg":"a"},plain b]`,
+		},
+	}
+	for _, tt := range tests {
+		for _, gz := range []bool{false, true} {
+			name := tt.name
+			if gz {
+				name += "/gzip"
+			}
+			t.Run(name, func(t *testing.T) {
+				var f fakeHTTP
+				srv := httptest.NewServer(&f)
+				defer srv.Close()
+				s, err := tt.new(srv, gz)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer s.Close()
+				for _, e := range entries {
+					s.Write([]byte(e))
+				}
+				if err := s.Sync(); err != nil {
+					t.Fatal(err)
+				}
+
+				reqs := f.requests()
+				if len(reqs) != 1 {
+					t.Fatalf("%d requests, want 1", len(reqs))
+				}
+				r := reqs[0]
+				if r.path != tt.path {
+					t.Errorf("path %q, want %q", r.path, tt.path)
+				}
+				for k := range tt.query {
+					if got := r.query.Get(k); got != tt.query.Get(k) {
+						t.Errorf("query %s=%q, want %q", k, got, tt.query.Get(k))
+					}
+				}
+				for k := range tt.header {
+					if got := r.header.Get(k); got != tt.header.Get(k) {
+						t.Errorf("header %s: %q, want %q", k, got, tt.header.Get(k))
+					}
+				}
+				if tt.partial && !strings.Contains(r.body, tt.body) || !tt.partial && r.body != tt.body {
+					t.Errorf("body\n%s\nwant\n%s", r.body, tt.body)
+				}
+			})
+		}
+	}
+}
+
+func TestHTTPSinkErrors(t *testing.T) {
+	tests := []struct {
+		status    int
+		permanent bool
+	}{
+		{http.StatusBadRequest, true},
+		{http.StatusRequestEntityTooLarge, true},
+		{http.StatusForbidden, false},
+		{http.StatusTooManyRequests, false},
+		{http.StatusServiceUnavailable, false},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			f := fakeHTTP{status: tt.status}
+			srv := httptest.NewServer(&f)
+			defer srv.Close()
+			s, err := NewSplunkSink(SplunkConfig{URL: srv.URL, Token: "secret"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+			err = s.send([]BatchEntry{{Data: []byte("entry\n")}})
+			if err == nil {
+				t.Fatal("send succeeded")
+			}
+			var perm *permanentError
+			if got := errors.As(err, &perm); got != tt.permanent {
+				t.Errorf("%v: permanent %v, want %v", err, got, tt.permanent)
+			}
+		})
+	}
+}
@@ -0,0 +1,316 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// OIDs of the standard varbinds heading an SNMPv2 trap.
+const (
+	oidSysUpTime   = "1.3.6.1.2.1.1.3.0"
+	oidSnmpTrapOID = "1.3.6.1.6.3.1.1.4.1.0"
+)
+
+// SNMPTrapConfig describes the SNMP trap core.
+type SNMPTrapConfig struct {
+	// Target is the address of the trap receiver. The port defaults to 162.
+	Target string
+	// Community defaults to "public".
+	Community string
+	// TrapOID identifies the traps, e.g. an OID of the enterprise MIB.
+	TrapOID string
+	// MessageOID and LevelOID, if set, are the OIDs of the varbinds
+	// carrying the message and the level of the entry.
+	MessageOID string
+	LevelOID   string
+	// Fields maps field keys to the OIDs of the varbinds carrying their
+	// values. Other fields are not sent.
+	Fields map[string]string
+	// Level is the minimum level sent, WARNING or above. Defaults to
+	// ERROR.
+	Level Level
+}
+
+type snmpCore struct {
+	cfg      SNMPTrapConfig
+	trapOID  []uint32
+	msgOID   []uint32
+	levelOID []uint32
+	fieldOID map[string][]uint32
+	fields   []zapcore.Field
+	start    time.Time
+	reqID    *int32
+
+	mu   *sync.Mutex
+	conn net.Conn
+}
+
+// NewSNMPTrapCore returns a core emitting an SNMPv2c trap for every entry
+// at ERROR and above, for network equipment monitored by an SNMP manager.
+// The trap carries sysUpTime, counted from the creation of the core, the
+// configured trap OID, and varbinds for the message, the level and the
+// fields listed in cfg.Fields: integers that fit are sent as INTEGER and
+// other values as OCTET STRING. Attach it with WithCore.
+func NewSNMPTrapCore(cfg SNMPTrapConfig) (zapcore.Core, error) {
+	if cfg.Target == "" || cfg.TrapOID == "" {
+		return nil, errors.New("log: SNMP trap target and OID are required")
+	}
+	if _, _, err := net.SplitHostPort(cfg.Target); err != nil {
+		cfg.Target = net.JoinHostPort(cfg.Target, "162")
+	}
+	if cfg.Community == "" {
+		cfg.Community = "public"
+	}
+	if cfg.Level <= InfoLevel {
+		cfg.Level = ErrorLevel
+	}
+	c := &snmpCore{
+		cfg:      cfg,
+		fieldOID: make(map[string][]uint32, len(cfg.Fields)),
+		start:    time.Now(),
+		reqID:    new(int32),
+		mu:       new(sync.Mutex),
+	}
+	var err error
+	if c.trapOID, err = parseOID(cfg.TrapOID); err != nil {
+		return nil, err
+	}
+	if cfg.MessageOID != "" {
+		if c.msgOID, err = parseOID(cfg.MessageOID); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.LevelOID != "" {
+		if c.levelOID, err = parseOID(cfg.LevelOID); err != nil {
+			return nil, err
+		}
+	}
+	for key, oid := range cfg.Fields {
+		if c.fieldOID[key], err = parseOID(oid); err != nil {
+			return nil, err
+		}
+	}
+	if c.conn, err = net.Dial("udp", cfg.Target); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *snmpCore) Enabled(lvl zapcore.Level) bool {
+	return Level(lvl) >= c.cfg.Level
+}
+
+func (c *snmpCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = append(c.fields[:len(c.fields):len(c.fields)], fields...)
+	return &clone
+}
+
+func (c *snmpCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *snmpCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	uptime := uint32(ent.Time.Sub(c.start) / (10 * time.Millisecond))
+	binds := [][]byte{
+		berVarbind(mustOID(oidSysUpTime), berTLV(0x43, berUint(uint64(uptime)))),
+		berVarbind(mustOID(oidSnmpTrapOID), berOID(c.trapOID)),
+	}
+	if c.msgOID != nil {
+		binds = append(binds, berVarbind(c.msgOID, berTLV(0x04, []byte(ent.Message))))
+	}
+	if c.levelOID != nil {
+		binds = append(binds, berVarbind(c.levelOID, berTLV(0x04, []byte(ent.Level.CapitalString()))))
+	}
+	if len(c.fieldOID) > 0 {
+		enc := zapcore.NewMapObjectEncoder()
+		for _, list := range [][]zapcore.Field{c.fields, fields} {
+			for i := range list {
+				list[i].AddTo(enc)
+			}
+		}
+		for key, oid := range c.fieldOID {
+			if v, ok := enc.Fields[key]; ok {
+				binds = append(binds, berVarbind(oid, snmpValue(v)))
+			}
+		}
+	}
+
+	pdu := berTLV(0xa7, concat(
+		berTLV(0x02, berInt(int64(atomic.AddInt32(c.reqID, 1)))),
+		berTLV(0x02, berInt(0)), // error-status
+		berTLV(0x02, berInt(0)), // error-index
+		berTLV(0x30, concat(binds...)),
+	))
+	msg := berTLV(0x30, concat(
+		berTLV(0x02, berInt(1)), // SNMPv2c
+		berTLV(0x04, []byte(c.cfg.Community)),
+		pdu,
+	))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_ = c.conn.SetWriteDeadline(time.Now().Add(time.Second))
+	_, err := c.conn.Write(msg)
+	return err
+}
+
+func (c *snmpCore) Sync() error {
+	return nil
+}
+
+// snmpValue encodes a field value as an INTEGER if it is an integer that
+// fits, and as an OCTET STRING otherwise.
+func snmpValue(v interface{}) []byte {
+	var n int64
+	switch v := v.(type) {
+	case int64:
+		n = v
+	case int32:
+		n = int64(v)
+	case int16:
+		n = int64(v)
+	case int8:
+		n = int64(v)
+	case uint64:
+		if v > math.MaxInt32 {
+			return berTLV(0x04, []byte(strconv.FormatUint(v, 10)))
+		}
+		n = int64(v)
+	case uint32:
+		n = int64(v)
+	case uint16:
+		n = int64(v)
+	case uint8:
+		n = int64(v)
+	case bool:
+		if v {
+			n = 1
+		}
+	case string:
+		return berTLV(0x04, []byte(v))
+	default:
+		return berTLV(0x04, []byte(fmt.Sprint(v)))
+	}
+	if n < math.MinInt32 || n > math.MaxInt32 {
+		return berTLV(0x04, []byte(strconv.FormatInt(n, 10)))
+	}
+	return berTLV(0x02, berInt(n))
+}
+
+// parseOID parses a dotted OID such as "1.3.6.1.4.1.2021".
+func parseOID(s string) ([]uint32, error) {
+	parts := strings.Split(strings.TrimPrefix(s, "."), ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("log: invalid OID %q", s)
+	}
+	oid := make([]uint32, len(parts))
+	for i, p := range parts {
+		n, err := strconv.ParseUint(p, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("log: invalid OID %q", s)
+		}
+		oid[i] = uint32(n)
+	}
+	if oid[0] > 2 || oid[0] < 2 && oid[1] >= 40 {
+		return nil, fmt.Errorf("log: invalid OID %q", s)
+	}
+	return oid, nil
+}
+
+// mustOID parses an OID validated beforehand.
+func mustOID(s string) []uint32 {
+	oid, err := parseOID(s)
+	if err != nil {
+		panic(err)
+	}
+	return oid
+}
+
+func berVarbind(oid []uint32, value []byte) []byte {
+	return berTLV(0x30, concat(berOID(oid), value))
+}
+
+// berTLV encodes a BER type-length-value triple.
+func berTLV(tag byte, value []byte) []byte {
+	n := len(value)
+	b := []byte{tag}
+	if n < 0x80 {
+		b = append(b, byte(n))
+	} else {
+		var l []byte
+		for ; n > 0; n >>= 8 {
+			l = append([]byte{byte(n)}, l...)
+		}
+		b = append(append(b, 0x80|byte(len(l))), l...)
+	}
+	return append(b, value...)
+}
+
+// berInt encodes the content of a BER INTEGER, in the fewest bytes of two's
+// complement.
+func berInt(n int64) []byte {
+	b := []byte{byte(n)}
+	for n >= 0x80 || n < -0x80 {
+		n >>= 8
+		b = append([]byte{byte(n)}, b...)
+	}
+	return b
+}
+
+// berUint encodes the content of an unsigned application type such as
+// TimeTicks.
+func berUint(n uint64) []byte {
+	b := []byte{byte(n)}
+	for n >>= 8; n > 0; n >>= 8 {
+		b = append([]byte{byte(n)}, b...)
+	}
+	if b[0] >= 0x80 {
+		b = append([]byte{0}, b...)
+	}
+	return b
+}
+
+// berOID encodes an OBJECT IDENTIFIER.
+func berOID(oid []uint32) []byte {
+	b := berBase128(nil, oid[0]*40+oid[1])
+	for _, arc := range oid[2:] {
+		b = berBase128(b, arc)
+	}
+	return berTLV(0x06, b)
+}
+
+func berBase128(b []byte, n uint32) []byte {
+	var tmp [5]byte
+	i := len(tmp) - 1
+	tmp[i] = byte(n & 0x7f)
+	for n >>= 7; n > 0; n >>= 7 {
+		i--
+		tmp[i] = byte(n&0x7f) | 0x80
+	}
+	return append(b, tmp[i:]...)
+}
+
+func concat(parts ...[]byte) []byte {
+	var n int
+	for _, p := range parts {
+		n += len(p)
+	}
+	b := make([]byte, 0, n)
+	for _, p := range parts {
+		b = append(b, p...)
+	}
+	return b
+}
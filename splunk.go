@@ -0,0 +1,174 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SplunkConfig describes the Splunk HTTP Event Collector sink.
+type SplunkConfig struct {
+	// URL is the HEC base URL, e.g. "https://splunk:8088".
+	URL   string
+	Token string
+	// Host, Source, SourceType and Index are set on every event when not
+	// empty.
+	Host       string
+	Source     string
+	SourceType string
+	Index      string
+	// BatchSize and BatchBytes bound a single request. Default to 100
+	// events and 1 MiB.
+	BatchSize  int
+	BatchBytes int
+	// FlushInterval is the maximum time entries wait before being sent.
+	// Defaults to 5 seconds.
+	FlushInterval time.Duration
+	// Gzip compresses request bodies.
+	Gzip bool
+	// InsecureSkipVerify disables certificate verification, for HEC
+	// endpoints with self-signed certificates.
+	InsecureSkipVerify bool
+	// TLS configures CA pinning and client certificates. It takes
+	// precedence over InsecureSkipVerify.
+	TLS        *TLSConfig
+	HTTPClient *http.Client
+	// Breaker, if set, stops requests for a while after repeated failures.
+	Breaker *BreakerConfig
+	// Spool, if set, keeps entries on disk until they are delivered, so
+	// they survive network outages and restarts, see Spool.
+	Spool *SpoolConfig
+}
+
+// SplunkSink is a zap.Sink posting entries to a Splunk HTTP Event
+// Collector. Attach it with WithSink.
+type SplunkSink struct {
+	remoteQueue
+	cfg SplunkConfig
+	url string
+}
+
+// NewSplunkSink returns a sink sending entries to the configured HEC.
+func NewSplunkSink(cfg SplunkConfig) (*SplunkSink, error) {
+	if cfg.URL == "" || cfg.Token == "" {
+		return nil, errors.New("log: Splunk HEC URL and token are required")
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.BatchBytes <= 0 {
+		cfg.BatchBytes = 1 << 20
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 5 * time.Second
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+		switch {
+		case cfg.TLS != nil:
+			u, err := url.Parse(cfg.URL)
+			if err != nil {
+				return nil, err
+			}
+			tlsCfg, err := cfg.TLS.Build(u.Hostname())
+			if err != nil {
+				return nil, err
+			}
+			cfg.HTTPClient.Transport = &http.Transport{TLSClientConfig: tlsCfg}
+		case cfg.InsecureSkipVerify:
+			cfg.HTTPClient.Transport = &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			}
+		}
+	}
+
+	s := &SplunkSink{cfg: cfg, url: strings.TrimRight(cfg.URL, "/") + "/services/collector/event"}
+	q, err := newRemoteQueue(BatchConfig{
+		MaxEntries: cfg.BatchSize,
+		MaxBytes:   cfg.BatchBytes,
+		MaxLatency: cfg.FlushInterval,
+		Breaker:    cfg.Breaker,
+	}, cfg.Spool, s.send)
+	if err != nil {
+		return nil, err
+	}
+	s.remoteQueue = q
+	return s, nil
+}
+
+type splunkEvent struct {
+	Time       float64     `json:"time"`
+	Host       string      `json:"host,omitempty"`
+	Source     string      `json:"source,omitempty"`
+	SourceType string      `json:"sourcetype,omitempty"`
+	Index      string      `json:"index,omitempty"`
+	Event      interface{} `json:"event"`
+}
+
+func (s *SplunkSink) send(batch []BatchEntry) error {
+	var body bytes.Buffer
+	var w io.Writer = &body
+	var gz *gzip.Writer
+	if s.cfg.Gzip {
+		gz = gzip.NewWriter(&body)
+		w = gz
+	}
+
+	enc := json.NewEncoder(w)
+	for _, e := range batch {
+		entry := bytes.TrimRight(e.Data, "\n")
+		ev := splunkEvent{
+			Time:       float64(e.Time.UnixNano()) / 1e9,
+			Host:       s.cfg.Host,
+			Source:     s.cfg.Source,
+			SourceType: s.cfg.SourceType,
+			Index:      s.cfg.Index,
+			Event:      string(entry),
+		}
+		if json.Valid(entry) {
+			ev.Event = json.RawMessage(entry)
+		}
+		if err := enc.Encode(ev); err != nil {
+			return err
+		}
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Splunk "+s.cfg.Token)
+	req.Header.Set("Content-Type", "application/json")
+	if gz != nil {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	resp, err := s.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		err := fmt.Errorf("log: Splunk HEC returned %s: %s", resp.Status, msg)
+		if rejectedStatus(resp.StatusCode) {
+			return PermanentError(err)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,501 @@
+package log
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	spoolExt        = ".spool"
+	spoolCursor     = "cursor"
+	spoolHeaderSize = 12
+)
+
+// SpoolConfig configures a Spool.
+type SpoolConfig struct {
+	// Dir holds the spool files. It is created if needed and must not be
+	// shared between sinks.
+	Dir string
+	// SegmentSize is the size in bytes at which a new spool file is
+	// started. Defaults to 4 MiB.
+	SegmentSize int64
+	// MaxSize bounds the size in bytes of the spool. When it is exceeded
+	// the oldest spool file is deleted, undelivered or not. Defaults to
+	// 256 MiB.
+	MaxSize int64
+	// RetryWait is the delay before the first retry of a failed delivery.
+	// It doubles up to MaxRetryWait. Default to 1 second and 1 minute.
+	RetryWait    time.Duration
+	MaxRetryWait time.Duration
+}
+
+// Spool is a zapcore.WriteSyncer giving remote sinks at-least-once
+// delivery. Entries are appended to files in a local directory and handed
+// to the send function in batches, as by a Batcher; a batch is removed from
+// the spool only once send has returned without error, or with one made by
+// PermanentError, in which case it is counted in Stats.DroppedBatches.
+// Entries that could not be delivered before the process stopped are sent
+// on the next start.
+//
+// Each spool file is a sequence of records: the entry length (uint32), the
+// write time in Unix nanoseconds (int64), both big endian, and the entry.
+// The delivery position is kept in the "cursor" file.
+type Spool struct {
+	cfg   SpoolConfig
+	batch BatchConfig
+	send  func(batch []BatchEntry) error
+
+	// Written by Write.
+	mu      sync.Mutex
+	f       *os.File
+	seg     uint64 // segment being written
+	segSize int64
+	first   uint64 // oldest segment on disk
+	sizes   map[uint64]int64
+	total   int64
+	err     error
+	closed  bool
+
+	// Owned by the delivery goroutine, and by Close once it has stopped.
+	rf   *os.File
+	rseg uint64
+	roff int64
+
+	notify    chan struct{}
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// PermanentError marks err, returned by the send function of a Spool, as
+// meaning that the batch will never be accepted, e.g. because the server
+// rejected it as malformed, so that it is dropped instead of retried.
+func PermanentError(err error) error {
+	return &permanentError{err}
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// rejectedStatus reports whether an HTTP status rejects the batch sent
+// itself, rather than the request for a reason that may go away.
+func rejectedStatus(code int) bool {
+	return code == http.StatusBadRequest || code == http.StatusRequestEntityTooLarge ||
+		code == http.StatusUnprocessableEntity
+}
+
+// NewSpool opens the spool in cfg.Dir and starts delivering the entries in
+// it, if any, with send. batch bounds the batches handed to send; its
+// MaxLatency is how often the spool is checked for new entries.
+func NewSpool(cfg SpoolConfig, batch BatchConfig, send func(batch []BatchEntry) error) (*Spool, error) {
+	if cfg.Dir == "" {
+		return nil, errors.New("log: spool directory is required")
+	}
+	if cfg.SegmentSize <= 0 {
+		cfg.SegmentSize = 4 << 20
+	}
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = 256 << 20
+	}
+	if cfg.RetryWait <= 0 {
+		cfg.RetryWait = time.Second
+	}
+	if cfg.MaxRetryWait <= 0 {
+		cfg.MaxRetryWait = time.Minute
+	}
+	if batch.MaxEntries <= 0 {
+		batch.MaxEntries = 100
+	}
+	if batch.MaxBytes <= 0 {
+		batch.MaxBytes = 1 << 20
+	}
+	if batch.MaxLatency <= 0 {
+		batch.MaxLatency = 5 * time.Second
+	}
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, err
+	}
+
+	s := &Spool{
+		cfg:    cfg,
+		batch:  batch,
+		send:   send,
+		sizes:  make(map[uint64]int64),
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	segs, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range segs {
+		fi, err := os.Stat(s.path(id))
+		if err != nil {
+			return nil, err
+		}
+		s.sizes[id] = fi.Size()
+		s.total += fi.Size()
+	}
+	// Writing always starts a new segment, so that a record left
+	// incomplete by a crash stays at the end of an old one.
+	if len(segs) > 0 {
+		s.first = segs[0]
+		s.seg = segs[len(segs)-1] + 1
+	} else {
+		s.seg = 1
+		s.first = 1
+	}
+	if err := s.create(); err != nil {
+		return nil, err
+	}
+	s.rseg, s.roff = s.readCursor()
+	if s.rseg < s.first || s.rseg > s.seg {
+		s.rseg, s.roff = s.first, 0
+	}
+
+	s.wg.Add(1)
+	go s.run()
+	return s, nil
+}
+
+func (s *Spool) path(id uint64) string {
+	return filepath.Join(s.cfg.Dir, fmt.Sprintf("%020d%s", id, spoolExt))
+}
+
+// segments returns the ids of the spool files, oldest first.
+func (s *Spool) segments() ([]uint64, error) {
+	infos, err := ioutil.ReadDir(s.cfg.Dir)
+	if err != nil {
+		return nil, err
+	}
+	var ids []uint64
+	for _, fi := range infos {
+		name := fi.Name()
+		if !strings.HasSuffix(name, spoolExt) {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(name, spoolExt), 10, 64)
+		if err == nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+// create starts segment s.seg. The caller holds s.mu or owns s.
+func (s *Spool) create() error {
+	f, err := os.OpenFile(s.path(s.seg), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	s.f, s.segSize = f, 0
+	s.sizes[s.seg] = 0
+	return nil
+}
+
+// Write appends p to the spool. It fails only if the spool file cannot be
+// written.
+func (s *Spool) Write(p []byte) (int, error) {
+	rec := make([]byte, spoolHeaderSize+len(p))
+	binary.BigEndian.PutUint32(rec, uint32(len(p)))
+	binary.BigEndian.PutUint64(rec[4:], uint64(time.Now().UnixNano()))
+	copy(rec[spoolHeaderSize:], p)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return 0, os.ErrClosed
+	}
+	if s.segSize >= s.cfg.SegmentSize {
+		if err := s.f.Close(); err != nil {
+			return 0, err
+		}
+		s.seg++
+		if err := s.create(); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := s.f.Write(rec); err != nil {
+		return 0, err
+	}
+	s.segSize += int64(len(rec))
+	s.sizes[s.seg] = s.segSize
+	s.total += int64(len(rec))
+	for s.total > s.cfg.MaxSize && s.first < s.seg {
+		s.remove(s.first)
+	}
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+	return len(p), nil
+}
+
+// remove deletes segment id, which must be the oldest. The caller holds
+// s.mu.
+func (s *Spool) remove(id uint64) {
+	_ = os.Remove(s.path(id))
+	s.total -= s.sizes[id]
+	delete(s.sizes, id)
+	s.first = id + 1
+}
+
+// Sync flushes the spool file to disk and reports the last delivery error.
+func (s *Spool) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return os.ErrClosed
+	}
+	err := s.f.Sync()
+	if err == nil {
+		err, s.err = s.err, nil
+	}
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+	return err
+}
+
+// Close tries once more to deliver the spooled entries, then stops. What
+// is left is delivered after the next NewSpool on the same directory.
+// Later calls return os.ErrClosed.
+func (s *Spool) Close() error {
+	err := os.ErrClosed
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.wg.Wait()
+		for {
+			n, err := s.deliver()
+			if err != nil || n == 0 {
+				break
+			}
+		}
+		if s.rf != nil {
+			s.rf.Close()
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.closed = true
+		err = s.f.Close()
+		if s.err != nil {
+			err = s.err
+		}
+	})
+	return err
+}
+
+// Pending returns the size in bytes of the spool files.
+func (s *Spool) Pending() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.total
+}
+
+func (s *Spool) run() {
+	defer s.wg.Done()
+	wait := newBackoff(s.cfg.RetryWait, s.cfg.MaxRetryWait)
+	t := time.NewTicker(s.batch.MaxLatency)
+	defer t.Stop()
+	for {
+		n, err := s.deliver()
+		switch {
+		case err != nil:
+			s.mu.Lock()
+			s.err = err
+			s.mu.Unlock()
+			select {
+			case <-s.done:
+				return
+			case <-time.After(wait.next()):
+			}
+		case n > 0:
+			wait.reset()
+		default:
+			select {
+			case <-s.done:
+				return
+			case <-s.notify:
+			case <-t.C:
+			}
+		}
+	}
+}
+
+// deliver sends the next batch and returns the number of entries sent.
+func (s *Spool) deliver() (int, error) {
+	s.mu.Lock()
+	seg, size, first := s.seg, s.segSize, s.first
+	s.mu.Unlock()
+
+	if s.rseg < first {
+		// Deleted by Write to respect MaxSize.
+		s.closeReader()
+		s.rseg, s.roff = first, 0
+		s.writeCursor()
+	}
+	limit := int64(-1)
+	if s.rseg == seg {
+		limit = size
+	}
+	batch, end, err := s.read(limit)
+	if err != nil {
+		return 0, err
+	}
+	if len(batch) > 0 {
+		if err := s.send(batch); err != nil {
+			var perm *permanentError
+			if !errors.As(err, &perm) {
+				return 0, err
+			}
+			atomic.AddUint64(&counters.droppedBatches, 1)
+			atomic.AddUint64(&counters.unsent, uint64(len(batch)))
+			s.mu.Lock()
+			s.err = err
+			s.mu.Unlock()
+		}
+		s.roff = end
+	}
+	moved := len(batch) > 0
+	if len(batch) == 0 && s.rseg < seg {
+		// Fully delivered, and no longer written.
+		s.closeReader()
+		s.mu.Lock()
+		if s.rseg == s.first {
+			s.remove(s.rseg)
+		}
+		s.mu.Unlock()
+		s.rseg, s.roff = s.rseg+1, 0
+		moved = true
+	}
+	if moved {
+		s.writeCursor()
+	}
+	return len(batch), nil
+}
+
+// read reads the next batch from the current read segment, up to limit
+// bytes into it or to its end if limit is negative. An incomplete or
+// damaged record ends the segment.
+func (s *Spool) read(limit int64) ([]BatchEntry, int64, error) {
+	if s.rf == nil {
+		f, err := os.Open(s.path(s.rseg))
+		if os.IsNotExist(err) {
+			return nil, s.roff, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		s.rf = f
+	}
+	if _, err := s.rf.Seek(s.roff, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+
+	var batch []BatchEntry
+	off, size := s.roff, 0
+	hdr := make([]byte, spoolHeaderSize)
+	for len(batch) < s.batch.MaxEntries {
+		if limit >= 0 && off+spoolHeaderSize > limit {
+			break
+		}
+		if _, err := io.ReadFull(s.rf, hdr); err != nil {
+			break
+		}
+		n := int64(binary.BigEndian.Uint32(hdr))
+		if n > s.cfg.MaxSize || (limit >= 0 && off+spoolHeaderSize+n > limit) {
+			break
+		}
+		if len(batch) > 0 && size+int(n) > s.batch.MaxBytes {
+			break
+		}
+		data := make([]byte, n)
+		if _, err := io.ReadFull(s.rf, data); err != nil {
+			break
+		}
+		ts := int64(binary.BigEndian.Uint64(hdr[4:]))
+		batch = append(batch, BatchEntry{Time: time.Unix(0, ts), Data: data})
+		off += spoolHeaderSize + n
+		size += int(n)
+	}
+	return batch, off, nil
+}
+
+func (s *Spool) closeReader() {
+	if s.rf != nil {
+		s.rf.Close()
+		s.rf = nil
+	}
+}
+
+// readCursor returns the delivery position saved by writeCursor.
+func (s *Spool) readCursor() (uint64, int64) {
+	b, err := ioutil.ReadFile(filepath.Join(s.cfg.Dir, spoolCursor))
+	if err != nil {
+		return 0, 0
+	}
+	var seg uint64
+	var off int64
+	if _, err := fmt.Sscanf(string(b), "%d %d", &seg, &off); err != nil {
+		return 0, 0
+	}
+	return seg, off
+}
+
+// writeCursor saves the delivery position. It is replaced atomically, so
+// a crash leaves either the old or the new position and entries are at
+// worst delivered twice.
+func (s *Spool) writeCursor() {
+	path := filepath.Join(s.cfg.Dir, spoolCursor)
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(fmt.Sprintf("%d %d\n", s.rseg, s.roff)), 0644); err != nil {
+		return
+	}
+	_ = os.Rename(tmp, path)
+}
+
+// remoteQueue is how the remote sinks hold entries until they are sent: in
+// memory with a Batcher, or on disk with a Spool.
+type remoteQueue interface {
+	Write(p []byte) (int, error)
+	Sync() error
+	Close() error
+}
+
+// newRemoteQueue returns a Spool if spool is set and a Batcher otherwise.
+// The breaker of batch does not apply to a Spool, which retries failed
+// deliveries with a backoff instead.
+func newRemoteQueue(batch BatchConfig, spool *SpoolConfig, send func([]BatchEntry) error) (remoteQueue, error) {
+	if spool == nil {
+		return NewBatcher(batch, send), nil
+	}
+	s, err := NewSpool(*spool, batch, send)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
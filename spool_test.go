@@ -0,0 +1,56 @@
+package log
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSpoolCloseTwice(t *testing.T) {
+	var r batchRecorder
+	s, err := NewSpool(SpoolConfig{Dir: t.TempDir()}, BatchConfig{}, r.send)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != os.ErrClosed {
+		t.Errorf("second Close: %v, want %v", err, os.ErrClosed)
+	}
+	if _, err := s.Write([]byte("late")); err != os.ErrClosed {
+		t.Errorf("Write after Close: %v, want %v", err, os.ErrClosed)
+	}
+}
+
+func TestSpoolDropsRejectedBatches(t *testing.T) {
+	r := batchRecorder{err: PermanentError(errors.New("malformed"))}
+	s, err := NewSpool(SpoolConfig{Dir: t.TempDir(), RetryWait: time.Hour},
+		BatchConfig{MaxEntries: 2, MaxLatency: 10 * time.Millisecond}, r.send)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	before := ReadStats()
+	for i := 0; i < 3; i++ {
+		s.Write([]byte("entry"))
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(r.sizes()) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("sent %v, want 2 batches", r.sizes())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	// Retried batches would be sent again after a few ticks.
+	time.Sleep(50 * time.Millisecond)
+	after := ReadStats()
+	if n := after.DroppedBatches - before.DroppedBatches; n != 2 {
+		t.Errorf("dropped %d batches, want 2", n)
+	}
+	if got := r.sizes(); len(got) != 2 {
+		t.Errorf("sent %v, want each batch once", got)
+	}
+}
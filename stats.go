@@ -0,0 +1,115 @@
+package log
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// counters are the process-wide statistics behind ReadStats. They are kept
+// across Init and Reconfigure.
+var counters struct {
+	levels     [FatalLevel - TraceLevel + 1]uint64
+	sampled    uint64
+	guarded    uint64
+	sinkErrors uint64
+	// droppedBatches and unsent count the batches and the entries a
+	// Batcher dropped.
+	droppedBatches uint64
+	unsent         uint64
+}
+
+// Stats are the logger statistics returned by ReadStats.
+type Stats struct {
+	// Entries counts the entries written to the outputs, by level name.
+	Entries map[string]uint64 `json:"entries"`
+	// Dropped counts the entries lost on the way: dropped by the sampler,
+	// by the disk guard (see WithDiskGuard), by a full output queue or by a
+	// Batcher that failed to send them.
+	Dropped uint64 `json:"dropped"`
+	// Sampled and DiskGuard are the parts of Dropped that were dropped by
+	// the sampler and the disk guard.
+	Sampled   uint64 `json:"sampled"`
+	DiskGuard uint64 `json:"disk_guard"`
+	// SinkErrors counts the errors returned by the outputs when writing.
+	SinkErrors uint64 `json:"sink_errors"`
+	// DroppedBatches counts the batches a Batcher failed to send and
+	// dropped.
+	DroppedBatches uint64 `json:"dropped_batches"`
+	// Queued is the number of entries waiting in the output queues (see
+	// WithNonBlocking and WithAsyncSink).
+	Queued int `json:"queued"`
+}
+
+// ReadStats returns the statistics of the package logger, so operators can
+// tell whether logging is healthy.
+func ReadStats() Stats {
+	q := std().QueueStats()
+	s := Stats{
+		Entries:        make(map[string]uint64, len(counters.levels)),
+		Sampled:        atomic.LoadUint64(&counters.sampled),
+		DiskGuard:      atomic.LoadUint64(&counters.guarded),
+		SinkErrors:     atomic.LoadUint64(&counters.sinkErrors),
+		Queued:         q.Queued,
+		DroppedBatches: atomic.LoadUint64(&counters.droppedBatches),
+	}
+	for i := range counters.levels {
+		s.Entries[(TraceLevel + Level(i)).String()] = atomic.LoadUint64(&counters.levels[i])
+	}
+	s.Dropped = s.Sampled + s.DiskGuard + q.Dropped + atomic.LoadUint64(&counters.unsent)
+	return s
+}
+
+// StatsVar publishes the statistics of ReadStats through expvar:
+//
+//	expvar.Publish("log", log.StatsVar{})
+type StatsVar struct{}
+
+// String returns the statistics as JSON, implementing expvar.Var.
+func (StatsVar) String() string {
+	b, _ := json.Marshal(ReadStats())
+	return string(b)
+}
+
+// StatsHandler returns an HTTP handler serving the statistics of ReadStats
+// as JSON, for a debug endpoint.
+func StatsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, StatsVar{}.String())
+	})
+}
+
+// countEntry is the hook counting the entries that reach the outputs.
+func countEntry(ent zapcore.Entry) error {
+	if i := Level(ent.Level) - TraceLevel; i >= 0 && int(i) < len(counters.levels) {
+		atomic.AddUint64(&counters.levels[i], 1)
+	}
+	return nil
+}
+
+// countSampled is the sampler hook counting the entries it drops.
+func countSampled(_ zapcore.Entry, dec zapcore.SamplingDecision) {
+	if dec&zapcore.LogDropped != 0 {
+		atomic.AddUint64(&counters.sampled, 1)
+	}
+}
+
+// errorOutput is the zap error output. zap reports there the errors the
+// outputs return, so they are counted on the way.
+type errorOutput struct {
+	zapcore.WriteSyncer
+}
+
+func newErrorOutput() zapcore.WriteSyncer {
+	return &errorOutput{zapcore.Lock(os.Stdout)}
+}
+
+func (w *errorOutput) Write(p []byte) (int, error) {
+	atomic.AddUint64(&counters.sinkErrors, 1)
+	return w.WriteSyncer.Write(p)
+}
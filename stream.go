@@ -0,0 +1,75 @@
+package log
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// StreamConfig describes a logical stream declared with WithStream.
+type StreamConfig struct {
+	// Encoding selects the encoder by name as in WithEncoding. Defaults to
+	// the logger's encoding.
+	Encoding string
+	// Paths are file paths or zap sink URLs the stream is written to.
+	Paths []string
+	// Sinks are additional outputs of the stream.
+	Sinks []zapcore.WriteSyncer
+}
+
+// newStreamLogger builds the zap logger of a stream with the level of the
+// main logger.
+func newStreamLogger(cfg StreamConfig, config *zap.Config, o *options) (*zap.Logger, error) {
+	name := config.Encoding
+	if cfg.Encoding != "" {
+		name = o.fieldsEncoding(cfg.Encoding)
+	}
+	enc, err := newEncoder(name, config.EncoderConfig)
+	if err != nil {
+		return nil, err
+	}
+	outs := make([]zapcore.WriteSyncer, 0, len(cfg.Sinks)+1)
+	if len(cfg.Paths) > 0 {
+		out, closeOut, err := zap.Open(cfg.Paths...)
+		if err != nil {
+			return nil, err
+		}
+		o.stops = append(o.stops, closeOut)
+		outs = append(outs, out)
+	}
+	outs = append(outs, cfg.Sinks...)
+
+	core := zapcore.NewCore(enc, zapcore.Lock(zapcore.NewMultiWriteSyncer(outs...)), o.root)
+	return zap.New(core,
+		zap.AddCaller(),
+		zap.AddCallerSkip(1),
+		zap.WithFatalHook(o.exit),
+		zap.WithClock(o.entryClock()),
+	), nil
+}
+
+// Stream returns the logger of the stream declared under name with
+// WithStream, e.g. Stream("access") for HTTP access logs kept apart from
+// the application log. Undeclared streams fall back to the package logger.
+func Stream(name string) *Logger {
+	return std().Stream(name)
+}
+
+// Stream returns the logger of the stream declared under name, or lg if
+// there is none. Fields added to lg with With are not carried over.
+func (lg *Logger) Stream(name string) *Logger {
+	if s, ok := lg.streams[name]; ok {
+		return s
+	}
+	return lg
+}
+
+// newStream derives the Logger of a stream from lg.
+func (lg *Logger) newStream(base *zap.Logger) *Logger {
+	s := *lg
+	s.zap = base.Sugar()
+	s.base = base
+	s.security = nil
+	s.streams = nil
+	s.stops = nil
+	return &s
+}
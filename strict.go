@@ -0,0 +1,62 @@
+package log
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// missingValue stands in for the value of a trailing key in strict mode.
+const missingValue = "!MISSING"
+
+// kvArgs returns the arguments of a *w call as they should be passed to
+// zap. In strict mode malformed arguments are reported and repaired, see
+// StrictArgs. It must be called directly from the logging function so the
+// reported caller is right.
+func (lg *Logger) kvArgs(args []interface{}) []interface{} {
+	if !lg.strictArgs {
+		return args
+	}
+	problem, fixed := checkArgs(args)
+	if problem == "" {
+		return args
+	}
+	if lg.development {
+		panic("log: " + problem)
+	}
+	// The logging function and kvArgs sit between the caller and zap.
+	lg.base.WithOptions(zap.AddCallerSkip(1)).Error("Malformed key/value arguments", zap.String("problem", problem))
+	return fixed
+}
+
+// checkArgs looks for keys that are not strings and a key without a value.
+// It returns a description of the first problem and a repaired copy of
+// args, or an empty string if there is none.
+func checkArgs(args []interface{}) (string, []interface{}) {
+	var problem string
+	var fixed []interface{}
+	for i := 0; i < len(args); {
+		if _, ok := args[i].(Field); ok {
+			i++
+			continue
+		}
+		key, ok := args[i].(string)
+		if !ok {
+			if problem == "" {
+				problem = fmt.Sprintf("argument %d is a key of type %T, not string", i, args[i])
+				fixed = append([]interface{}(nil), args...)
+			}
+			key = fmt.Sprint(args[i])
+			fixed[i] = key
+		}
+		if i+1 == len(args) {
+			if problem == "" {
+				problem = fmt.Sprintf("key %q has no value", key)
+				fixed = append([]interface{}(nil), args...)
+			}
+			fixed = append(fixed, missingValue)
+		}
+		i += 2
+	}
+	return problem, fixed
+}
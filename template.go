@@ -0,0 +1,175 @@
+package log
+
+import (
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Fatalt followed by the exit hooks and a call to os.Exit.
+func Fatalt(template string, fields ...Field) {
+	lg := std()
+	lg.logt(zapcore.FatalLevel, template, fields)
+	lg.exit()
+}
+
+// Errort logs a templated message using ERROR as log level. Placeholders
+// such as {user} are replaced with the value of the field of that name,
+// and the fields are logged as well.
+func Errort(template string, fields ...Field) {
+	std().logt(zapcore.ErrorLevel, template, fields)
+}
+
+// Warningt logs a templated message using WARNING as log level.
+func Warningt(template string, fields ...Field) {
+	std().logt(zapcore.WarnLevel, template, fields)
+}
+
+// Infot logs a templated message using INFO as log level:
+//
+//	log.Infot("user {user} connected from {ip}", log.Str("user", u), log.Str("ip", ip))
+func Infot(template string, fields ...Field) {
+	if stripped(InfoLevel) {
+		return
+	}
+	std().logt(zapcore.InfoLevel, template, fields)
+}
+
+// Debugt logs a templated message using DEBUG as log level.
+func Debugt(template string, fields ...Field) {
+	if stripped(DebugLevel) {
+		return
+	}
+	std().logt(zapcore.DebugLevel, template, fields)
+}
+
+// Fatalt followed by the exit hooks and a call to os.Exit.
+func (lg *Logger) Fatalt(template string, fields ...Field) {
+	lg.logt(zapcore.FatalLevel, template, fields)
+	lg.exit()
+}
+
+// Errort logs a templated message using ERROR as log level.
+func (lg *Logger) Errort(template string, fields ...Field) {
+	lg.logt(zapcore.ErrorLevel, template, fields)
+}
+
+// Warningt logs a templated message using WARNING as log level.
+func (lg *Logger) Warningt(template string, fields ...Field) {
+	lg.logt(zapcore.WarnLevel, template, fields)
+}
+
+// Infot logs a templated message using INFO as log level.
+func (lg *Logger) Infot(template string, fields ...Field) {
+	if stripped(InfoLevel) {
+		return
+	}
+	lg.logt(zapcore.InfoLevel, template, fields)
+}
+
+// Debugt logs a templated message using DEBUG as log level.
+func (lg *Logger) Debugt(template string, fields ...Field) {
+	if stripped(DebugLevel) {
+		return
+	}
+	lg.logt(zapcore.DebugLevel, template, fields)
+}
+
+// logt renders the template only if the entry is going to be written. It
+// must be called directly from the logging function so the reported caller
+// is right.
+func (lg *Logger) logt(lvl zapcore.Level, template string, fields []Field) {
+	ce := lg.base.WithOptions(zap.AddCallerSkip(1)).Check(lvl, template)
+	if ce == nil {
+		return
+	}
+	ce.Message, fields = renderTemplate(template, fields)
+	ce.Write(fields...)
+}
+
+// renderTemplate replaces each {name} in template with the text of the
+// field called name. Placeholders without a matching field are left as
+// they are. Only the fields named in template are encoded, and it returns
+// the fields to log with those computing their value when encoded
+// (stringers, errors, object and array marshalers) replaced by the value
+// it got, so that it is computed once.
+func renderTemplate(template string, fields []Field) (string, []Field) {
+	if !strings.Contains(template, "{") || len(fields) == 0 {
+		return template, fields
+	}
+	names := make(map[string]bool)
+	scanTemplate(template, func(name string) {
+		names[name] = true
+	}, nil)
+
+	var enc kvEncoder
+	out := fields
+	for i, f := range fields {
+		if f.Type != zapcore.NamespaceType && f.Type != zapcore.InlineMarshalerType && !names[enc.prefix+f.Key] {
+			continue
+		}
+		n := len(enc.fields)
+		f.AddTo(&enc)
+		if v, ok := evaluated(f, enc.fields[n:]); ok {
+			if &out[0] == &fields[0] {
+				out = append([]Field(nil), fields...)
+			}
+			out[i] = v
+		}
+	}
+
+	var b strings.Builder
+	scanTemplate(template, func(name string) {
+		if val, ok := enc.lookup(name); ok {
+			b.WriteString(formatValue(val))
+		} else {
+			b.WriteString("{" + name + "}")
+		}
+	}, func(text string) {
+		b.WriteString(text)
+	})
+	return b.String(), out
+}
+
+// scanTemplate calls placeholder with the name of each {name} of template
+// and, if not nil, text with the text around them.
+func scanTemplate(template string, placeholder func(name string), text func(string)) {
+	for {
+		open := strings.IndexByte(template, '{')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(template[open:], '}')
+		if end < 0 {
+			break
+		}
+		end += open
+		if text != nil {
+			text(template[:open])
+		}
+		placeholder(template[open+1 : end])
+		template = template[end+1:]
+	}
+	if text != nil {
+		text(template)
+	}
+}
+
+// evaluated returns a field logging the value f was encoded to, kvs, if f
+// computes its value when encoded.
+func evaluated(f Field, kvs []kv) (Field, bool) {
+	if len(kvs) != 1 {
+		// Nothing, or an error with details.
+		return f, false
+	}
+	switch f.Type {
+	case zapcore.StringerType, zapcore.ErrorType:
+		if s, ok := kvs[0].val.(string); ok {
+			return zap.String(f.Key, s), true
+		}
+	case zapcore.ObjectMarshalerType, zapcore.ArrayMarshalerType:
+		return zap.Any(f.Key, kvs[0].val), true
+	}
+	return f, false
+}
@@ -0,0 +1,75 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// countingStringer counts the calls of String.
+type countingStringer struct {
+	calls *int
+}
+
+func (s countingStringer) String() string {
+	*s.calls++
+	return "eth0"
+}
+
+type device struct{ serial string }
+
+func (d device) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("serial", d.serial)
+	return nil
+}
+
+func TestRenderTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		fields   []Field
+		want     string
+	}{
+		{"no placeholders", []Field{zap.String("a", "b")}, "no placeholders"},
+		{"user {user} connected", []Field{zap.String("user", "admin")}, "user admin connected"},
+		{"{n} retries in {d}", []Field{zap.Int("n", 3), zap.Duration("d", 1500*time.Millisecond)}, "3 retries in 1.5s"},
+		{"unknown {missing}", []Field{zap.String("user", "admin")}, "unknown {missing}"},
+		{"last {k} wins", []Field{zap.String("k", "first"), zap.String("k", "second")}, "last second wins"},
+		{"{req.id} in namespace", []Field{zap.Namespace("req"), zap.String("id", "42")}, "42 in namespace"},
+		{"failed: {error}", []Field{zap.Error(errors.New("timeout"))}, "failed: timeout"},
+		{"device {dev}", []Field{zap.Object("dev", device{"A1"})}, `device {"serial":"A1"}`},
+		{"unclosed {user", []Field{zap.String("user", "admin")}, "unclosed {user"},
+	}
+	for _, tt := range tests {
+		if got, _ := renderTemplate(tt.template, tt.fields); got != tt.want {
+			t.Errorf("renderTemplate(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestTemplateEvaluatesFieldsOnce(t *testing.T) {
+	var out syncBuffer
+	initTest(t, false, WithEncoding("json"), WithSink(&out))
+	var calls int
+	Infot("link {iface} up", zap.Stringer("iface", countingStringer{&calls}), zap.Object("dev", device{"A1"}))
+	if calls != 1 {
+		t.Errorf("String called %d times, want 1", calls)
+	}
+	lines := out.lines()
+	if len(lines) != 1 {
+		t.Fatalf("got %d entries, want 1", len(lines))
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry["msg"] != "link eth0 up" || entry["iface"] != "eth0" {
+		t.Errorf("logged %s", lines[0])
+	}
+	if dev, _ := entry["dev"].(map[string]interface{}); dev["serial"] != "A1" {
+		t.Errorf("logged %s", lines[0])
+	}
+}
@@ -0,0 +1,327 @@
+package log
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// TenantConfig describes the per-tenant files set with WithTenantFiles.
+type TenantConfig struct {
+	// Dir is the directory of the files, named after the tenants:
+	// "<Dir>/<id>.log", with the bytes of id other than lower-case
+	// letters, digits, '-' and non-leading '.' written as "_XX" in hex, so
+	// that distinct tenants never share a file, also on case-insensitive
+	// file systems. It is created if needed.
+	Dir string
+	// MaxOpen is the number of files kept open; the least recently written
+	// one is closed to open another, and reopened when needed. Defaults to
+	// 64.
+	MaxOpen int
+	// Encoding selects the encoder by name as in WithEncoding. Defaults to
+	// the logger's encoding.
+	Encoding string
+	// Mirror makes tenant entries go to the outputs of the package logger
+	// as well.
+	Mirror bool
+}
+
+// WithTenantFiles writes the entries of the loggers returned by Tenant to
+// a file per tenant instead of the outputs of the package logger.
+func WithTenantFiles(cfg TenantConfig) Option {
+	if cfg.MaxOpen <= 0 {
+		cfg.MaxOpen = 64
+	}
+	return func(o *options) {
+		o.tenants = &cfg
+	}
+}
+
+// Tenant returns a logger for the tenant id of a multi-tenant service. Its
+// entries carry the id under the "tenant" key and go to the file of the
+// tenant if WithTenantFiles is set, or else to the outputs of the package
+// logger, including after a later Init.
+//
+//	tlog := log.Tenant(req.TenantID)
+//	tlog.Infow("Device provisioned", "serial", serial)
+func Tenant(id string) *Logger {
+	base := zap.New(&tenantCore{id: id, fields: []zapcore.Field{zap.String("tenant", id)}},
+		zap.AddCaller(),
+		zap.AddCallerSkip(1),
+		zap.WithFatalHook(namedExitHook{}),
+		zap.WithClock(namedClock{}),
+	)
+	return &Logger{
+		level: InfoLevel,
+		zap:   base.Sugar(),
+		base:  base,
+
+		exitCode: 1,
+	}
+}
+
+// tenantCore is the core of a tenant logger. It hands the entries to the
+// tenant files and, unless they are set without Mirror, to the current
+// package logger. Both apply the levels and Silence the same way.
+type tenantCore struct {
+	id     string
+	fields []zapcore.Field
+	cores  atomic.Value // *tenantCores
+}
+
+// tenantCores are the cores of a tenantCore for the package logger lg,
+// with the fields added.
+type tenantCores struct {
+	lg   *Logger
+	file zapcore.Core // nil without tenant files
+	main zapcore.Core // nil with tenant files set without Mirror
+}
+
+// get returns the cores for the current package logger, building them
+// after Init.
+func (c *tenantCore) get() *tenantCores {
+	lg := std()
+	if tc, ok := c.cores.Load().(*tenantCores); ok && tc.lg == lg {
+		return tc
+	}
+	tc := &tenantCores{lg: lg}
+	t := lg.tenants
+	if t != nil {
+		tc.file = t.core(c.id).With(c.fields)
+	}
+	if t == nil || t.cfg.Mirror {
+		tc.main = lg.base.Core().With(c.fields)
+	}
+	c.cores.Store(tc)
+	return tc
+}
+
+func (c *tenantCore) Enabled(lvl zapcore.Level) bool {
+	lg := std()
+	if Level(lvl) < lg.GetLevel() {
+		return false
+	}
+	if t := lg.tenants; t != nil {
+		if t.level.Enabled(lvl) {
+			return true
+		}
+		if !t.cfg.Mirror {
+			return false
+		}
+	}
+	return lg.base.Core().Enabled(lvl)
+}
+
+func (c *tenantCore) With(fields []zapcore.Field) zapcore.Core {
+	all := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	all = append(all, c.fields...)
+	return &tenantCore{id: c.id, fields: append(all, fields...)}
+}
+
+func (c *tenantCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	tc := c.get()
+	if tc.file != nil {
+		ce = tc.file.Check(ent, ce)
+	}
+	if tc.main != nil {
+		ce = tc.main.Check(ent, ce)
+	}
+	return ce
+}
+
+// Write is not used: Check adds the cores of the tenant file and of the
+// package logger instead.
+func (c *tenantCore) Write(zapcore.Entry, []zapcore.Field) error {
+	return nil
+}
+
+func (c *tenantCore) Sync() error {
+	lg := std()
+	if t := lg.tenants; t != nil {
+		if err := t.Sync(); err != nil || !t.cfg.Mirror {
+			return err
+		}
+	}
+	return lg.base.Sync()
+}
+
+// tenantFiles are the files of the tenants, of which the most recently
+// written are kept open.
+type tenantFiles struct {
+	cfg TenantConfig
+	enc zapcore.Encoder
+	// level is the floor of the outputs, see levelRouter.
+	level zapcore.LevelEnabler
+	// wrap wraps the cores like the package core, see options.wrapOutput.
+	wrap func(zapcore.Core) zapcore.Core
+
+	mu     sync.Mutex
+	open   map[string]*list.Element
+	lru    list.List // of *tenantFile, most recent first
+	closed bool
+}
+
+type tenantFile struct {
+	id string
+	f  *os.File
+}
+
+var errTenantsClosed = errors.New("log: tenant files closed")
+
+func newTenantFiles(cfg TenantConfig, enc zapcore.Encoder, level zapcore.LevelEnabler, wrap func(zapcore.Core) zapcore.Core) (*tenantFiles, error) {
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, err
+	}
+	return &tenantFiles{
+		cfg:   cfg,
+		enc:   enc,
+		level: level,
+		wrap:  wrap,
+		open:  make(map[string]*list.Element),
+	}, nil
+}
+
+// core returns a core writing to the file of the tenant id, wrapped like
+// the core of the package logger so that WithPrivacy, WithSanitize and the
+// like apply to the tenant files too.
+func (t *tenantFiles) core(id string) zapcore.Core {
+	return t.wrap(&tenantFileCore{t: t, id: id, enc: t.enc.Clone()})
+}
+
+// path returns the path of the file of the tenant id, see TenantConfig.Dir.
+func (t *tenantFiles) path(id string) string {
+	return filepath.Join(t.cfg.Dir, tenantFileName(id)+".log")
+}
+
+// tenantFileName escapes id into a file name that cannot escape the
+// directory, distinct for every id, also ignoring case. The empty id is
+// "_", which no other id escapes to.
+func tenantFileName(id string) string {
+	if id == "" {
+		return "_"
+	}
+	var b strings.Builder
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-', c == '.' && i > 0:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "_%02x", c)
+		}
+	}
+	return b.String()
+}
+
+// write writes p to the file of the tenant id, opening it if needed.
+func (t *tenantFiles) write(id string, p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return 0, errTenantsClosed
+	}
+	e, ok := t.open[id]
+	if ok {
+		t.lru.MoveToFront(e)
+	} else {
+		f, err := os.OpenFile(t.path(id), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return 0, err
+		}
+		e = t.lru.PushFront(&tenantFile{id, f})
+		t.open[id] = e
+		for t.lru.Len() > t.cfg.MaxOpen {
+			t.evict(t.lru.Back())
+		}
+	}
+	return e.Value.(*tenantFile).f.Write(p)
+}
+
+// evict closes the file of e. The caller holds t.mu.
+func (t *tenantFiles) evict(e *list.Element) {
+	tf := t.lru.Remove(e).(*tenantFile)
+	delete(t.open, tf.id)
+	_ = tf.f.Close()
+}
+
+// Sync syncs the open files.
+func (t *tenantFiles) Sync() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var err error
+	for e := t.lru.Front(); e != nil; e = e.Next() {
+		if serr := e.Value.(*tenantFile).f.Sync(); err == nil {
+			err = serr
+		}
+	}
+	return err
+}
+
+// close closes the open files. Later writes fail.
+func (t *tenantFiles) close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for t.lru.Len() > 0 {
+		t.evict(t.lru.Back())
+	}
+	t.closed = true
+}
+
+// tenantFileCore encodes entries for the file of a tenant.
+type tenantFileCore struct {
+	t   *tenantFiles
+	id  string
+	enc zapcore.Encoder
+}
+
+func (c *tenantFileCore) Enabled(lvl zapcore.Level) bool {
+	return c.t.level.Enabled(lvl)
+}
+
+func (c *tenantFileCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return &tenantFileCore{t: c.t, id: c.id, enc: enc}
+}
+
+func (c *tenantFileCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *tenantFileCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	_, err = c.t.write(c.id, buf.Bytes())
+	buf.Free()
+	if err != nil {
+		return err
+	}
+	if ent.Level > zapcore.ErrorLevel {
+		// Like zap, sync before a panic or an exit.
+		return c.Sync()
+	}
+	return nil
+}
+
+func (c *tenantFileCore) Sync() error {
+	if d, ok := exitAt.Load().(time.Time); ok {
+		return within(d, c.t.Sync)
+	}
+	return c.t.Sync()
+}
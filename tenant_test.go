@@ -0,0 +1,103 @@
+package log
+
+import (
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTenantFileName(t *testing.T) {
+	ids := []string{"", "_", "a", "a_b", "a/b", "a:b", "a_2fb", "Acme", "acme", ".", "..", "../etc", "a.b", "ключ"}
+	seen := make(map[string]string)
+	for _, id := range ids {
+		name := tenantFileName(id)
+		if strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+			t.Errorf("%q: unsafe file name %q", id, name)
+		}
+		if prev, ok := seen[strings.ToLower(name)]; ok {
+			t.Errorf("%q and %q share the file name %q", prev, id, name)
+		}
+		seen[strings.ToLower(name)] = id
+	}
+}
+
+func TestTenantFiles(t *testing.T) {
+	dir := t.TempDir()
+	var out syncBuffer
+	initTest(t, false, WithEncoding("json"), WithSink(&out), WithTenantFiles(TenantConfig{Dir: dir, MaxOpen: 2}))
+
+	ids := []string{"a", "b", "c", "a", "A"}
+	for _, id := range ids {
+		Tenant(id).Infow("Provisioned", "id", id)
+		Tenant(id).Debugw("hidden")
+	}
+	Silence(true)
+	Tenant("a").Errorw("silenced")
+	Silence(false)
+	_ = Sync()
+
+	if out.String() != "" {
+		t.Errorf("tenant entries written to the package outputs:\n%s", out.String())
+	}
+	want := map[string]int{"a": 2, "b": 1, "c": 1, "A": 1}
+	for id, n := range want {
+		b, err := ioutil.ReadFile(filepath.Join(dir, tenantFileName(id)+".log"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+		if len(lines) != n {
+			t.Errorf("%q: %d entries, want %d:\n%s", id, len(lines), n, b)
+		}
+		for _, l := range lines {
+			if !strings.Contains(l, `"tenant":"`+id+`"`) {
+				t.Errorf("%q: entry of another tenant: %s", id, l)
+			}
+		}
+	}
+	if n := len(std().tenants.open); n > 2 {
+		t.Errorf("%d files open, want at most 2", n)
+	}
+}
+
+func TestTenantFilesPrivacy(t *testing.T) {
+	dir := t.TempDir()
+	initTest(t, false, WithEncoding("json"), WithSink(discard{}), WithPrivacy(PrivacyTruncate, nil),
+		WithTenantFiles(TenantConfig{Dir: dir}))
+	tlog := Tenant("a").With(IP("gw", net.ParseIP("192.0.2.1")))
+	tlog.Infow("Connected", IP("client", net.ParseIP("198.51.100.7")))
+	_ = Sync()
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "a.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"gw":"192.0.2.0"`, `"client":"198.51.100.0"`} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("tenant file without %s:\n%s", want, b)
+		}
+	}
+}
+
+func TestTenantMirror(t *testing.T) {
+	var out syncBuffer
+	initTest(t, false, WithEncoding("json"), WithSink(&out), WithTenantFiles(TenantConfig{Dir: t.TempDir(), Mirror: true}))
+	Tenant("a").Infow("Provisioned")
+	Silence(true)
+	Tenant("a").Infow("silenced")
+	Silence(false)
+	if lines := out.lines(); len(lines) != 1 || !strings.Contains(lines[0], `"tenant":"a"`) {
+		t.Errorf("mirrored entries:\n%s", out.String())
+	}
+}
+
+func TestTenantField(t *testing.T) {
+	var out syncBuffer
+	initTest(t, false, WithEncoding("json"), WithSink(&out))
+	Tenant("acme").With("k", 1).Infow("Provisioned")
+	if !strings.Contains(out.String(), `"tenant":"acme","k":1`) {
+		t.Errorf("entry without the tenant field:\n%s", out.String())
+	}
+}
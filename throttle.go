@@ -0,0 +1,87 @@
+package log
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// callSites keeps per-call-site state for Once, Every and EveryDuration,
+// keyed by the program counter of the caller.
+var callSites sync.Map
+
+var nop = NewNop()
+
+func callSite(skip int) uintptr {
+	pc, _, _, _ := runtime.Caller(skip + 1)
+	return pc
+}
+
+// Once returns the package logger the first time it is called from a given
+// call site and a no-op logger afterwards.
+func Once() *Logger {
+	return std().once(callSite(1))
+}
+
+// Every returns the package logger on the first and then every n-th call
+// from a given call site, and a no-op logger otherwise.
+func Every(n int) *Logger {
+	return std().every(callSite(1), n)
+}
+
+// EveryDuration returns the package logger at most once per d for a given
+// call site, and a no-op logger otherwise.
+func EveryDuration(d time.Duration) *Logger {
+	return std().everyDuration(callSite(1), d)
+}
+
+// Once returns lg the first time it is called from a given call site and a
+// no-op logger afterwards.
+func (lg *Logger) Once() *Logger {
+	return lg.once(callSite(1))
+}
+
+// Every returns lg on the first and then every n-th call from a given call
+// site, and a no-op logger otherwise.
+func (lg *Logger) Every(n int) *Logger {
+	return lg.every(callSite(1), n)
+}
+
+// EveryDuration returns lg at most once per d for a given call site, and a
+// no-op logger otherwise.
+func (lg *Logger) EveryDuration(d time.Duration) *Logger {
+	return lg.everyDuration(callSite(1), d)
+}
+
+func (lg *Logger) once(pc uintptr) *Logger {
+	if _, loaded := callSites.LoadOrStore(pc, struct{}{}); loaded {
+		return nop
+	}
+	return lg
+}
+
+func (lg *Logger) every(pc uintptr, n int) *Logger {
+	if n <= 1 {
+		return lg
+	}
+	v, _ := callSites.LoadOrStore(pc, new(uint64))
+	if (atomic.AddUint64(v.(*uint64), 1)-1)%uint64(n) != 0 {
+		return nop
+	}
+	return lg
+}
+
+func (lg *Logger) everyDuration(pc uintptr, d time.Duration) *Logger {
+	v, _ := callSites.LoadOrStore(pc, new(int64))
+	last := v.(*int64)
+	now := time.Now().UnixNano()
+	prev := atomic.LoadInt64(last)
+	if prev != 0 && now-prev < int64(d) {
+		return nop
+	}
+	if !atomic.CompareAndSwapInt64(last, prev, now) {
+		return nop
+	}
+	return lg
+}
@@ -0,0 +1,101 @@
+package log
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Timer measures an operation started with Start.
+type Timer struct {
+	lg     *Logger
+	msg    string
+	fields []Field
+	start  time.Time
+	slow   time.Duration
+}
+
+// Start begins timing an operation described by msg. Call Done on the
+// returned Timer when the operation ends:
+//
+//	t := log.Start("reload config", log.Str("path", path))
+//	err := reload(path)
+//	t.Done(err)
+func Start(msg string, fields ...Field) *Timer {
+	return std().Start(msg, fields...)
+}
+
+// Start begins timing an operation logged through lg.
+func (lg *Logger) Start(msg string, fields ...Field) *Timer {
+	return &Timer{lg: lg, msg: msg, fields: fields, start: time.Now()}
+}
+
+// WarnAfter makes Done log at WARNING when the operation took longer than
+// d and did not fail.
+func (t *Timer) WarnAfter(d time.Duration) *Timer {
+	t.slow = d
+	return t
+}
+
+// Done logs the operation with its elapsed time: at ERROR with the error
+// if err is not nil, at WARNING if it exceeded the WarnAfter threshold and
+// at INFO otherwise. It returns the elapsed time.
+func (t *Timer) Done(err error) time.Duration {
+	elapsed := time.Since(t.start)
+	lvl := InfoLevel
+	switch {
+	case err != nil:
+		lvl = ErrorLevel
+	case t.slow > 0 && elapsed > t.slow:
+		lvl = WarningLevel
+	}
+	if stripped(lvl) {
+		return elapsed
+	}
+
+	fields := append(t.fields[:len(t.fields):len(t.fields)], zap.Duration("elapsed", elapsed))
+	switch lvl {
+	case ErrorLevel:
+		t.lg.base.Error(t.msg, append(fields, zap.Error(err))...)
+	case WarningLevel:
+		t.lg.base.Warn(t.msg, append(fields, zap.Duration("threshold", t.slow))...)
+	default:
+		t.lg.base.Info(t.msg, fields...)
+	}
+	return elapsed
+}
+
+// TraceFn logs entering the function name at TRACE level and returns a
+// function logging the exit with the elapsed time, for use as
+//
+//	defer log.TraceFn("reload")()
+func TraceFn(name string) func() {
+	return std().traceFn(name)
+}
+
+// TraceFn logs entering and, through the returned function, leaving name
+// at TRACE level.
+func (lg *Logger) TraceFn(name string) func() {
+	return lg.traceFn(name)
+}
+
+// traceFn must be called directly from TraceFn so the reported caller is
+// right.
+func (lg *Logger) traceFn(name string) func() {
+	if stripped(TraceLevel) {
+		return func() {}
+	}
+	enter := lg.base.WithOptions(zap.AddCallerSkip(1))
+	if ce := enter.Check(zapcore.Level(TraceLevel), "enter "+name); ce != nil {
+		ce.Write()
+	} else {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		if ce := lg.base.Check(zapcore.Level(TraceLevel), "exit "+name); ce != nil {
+			ce.Write(zap.Duration("elapsed", time.Since(start)))
+		}
+	}
+}
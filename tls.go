@@ -0,0 +1,108 @@
+package log
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"time"
+)
+
+// TLSConfig configures TLS for network sinks.
+type TLSConfig struct {
+	// CAFile is a PEM bundle of the CAs trusted to sign the server
+	// certificate. When set, the system roots are not used.
+	CAFile string
+	// CertFile and KeyFile hold the client certificate for mutual TLS.
+	CertFile string
+	KeyFile  string
+	// ServerName overrides the name verified against the certificate.
+	ServerName string
+	// PinnedSPKI lists base64-encoded SHA-256 hashes of accepted server
+	// public keys (SubjectPublicKeyInfo). When set, the server leaf key
+	// must match one of them in addition to normal verification.
+	PinnedSPKI []string
+	// InsecureSkipVerify disables certificate verification. Pins are
+	// still checked.
+	InsecureSkipVerify bool
+}
+
+// Build returns the crypto/tls configuration for connecting to serverName,
+// which is used unless ServerName is set.
+func (c *TLSConfig) Build(serverName string) (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         serverName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+	if c.ServerName != "" {
+		cfg.ServerName = c.ServerName
+	}
+	if c.CAFile != "" {
+		pem, err := ioutil.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("log: no certificates found in %s", c.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	if len(c.PinnedSPKI) > 0 {
+		pins := make(map[string]bool, len(c.PinnedSPKI))
+		for _, pin := range c.PinnedSPKI {
+			pins[pin] = true
+		}
+		cfg.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return errors.New("log: server presented no certificate")
+			}
+			leaf, err := x509.ParseCertificate(rawCerts[0])
+			if err != nil {
+				return err
+			}
+			sum := sha256.Sum256(leaf.RawSubjectPublicKeyInfo)
+			if !pins[base64.StdEncoding.EncodeToString(sum[:])] {
+				return errors.New("log: server public key does not match any pin")
+			}
+			return nil
+		}
+	}
+	return cfg, nil
+}
+
+// buildTLS returns the TLS configuration for addr, or nil when TLS is off.
+func buildTLS(c *TLSConfig, enabled bool, addr string) (*tls.Config, error) {
+	if c == nil {
+		if !enabled {
+			return nil, nil
+		}
+		c = &TLSConfig{}
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	return c.Build(host)
+}
+
+// dialSink connects to addr, over TLS when cfg is not nil.
+func dialSink(addr string, cfg *tls.Config) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if cfg == nil {
+		return dialer.Dial("tcp", addr)
+	}
+	return tls.DialWithDialer(dialer, "tcp", addr, cfg)
+}
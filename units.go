@@ -0,0 +1,91 @@
+package log
+
+import (
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// humanConsoleEncoding is the console encoder with human-friendly units,
+// selected by Init when HumanUnits is given.
+const humanConsoleEncoding = "console+human"
+
+func init() {
+	registerEncoder(humanConsoleEncoding, func(cfg zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		cfg.EncodeDuration = zapcore.StringDurationEncoder
+		return &humanEncoder{zapcore.NewConsoleEncoder(cfg)}, nil
+	})
+}
+
+// ByteSize is a number of bytes. It is logged as a number by JSON encoders
+// and as "4.2MiB" by the text ones.
+type ByteSize int64
+
+// String formats b with binary prefixes and one decimal.
+func (b ByteSize) String() string {
+	const unit = 1024
+	n := int64(b)
+	sign := ""
+	if n < 0 {
+		sign, n = "-", -n
+	}
+	if n < unit {
+		return sign + strconv.FormatInt(n, 10) + "B"
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit && exp < 5; m /= unit {
+		div *= unit
+		exp++
+	}
+	return sign + strconv.FormatFloat(float64(n)/float64(div), 'f', 1, 64) + string("KMGTPE"[exp]) + "iB"
+}
+
+// Bytes constructs a field with a byte count, see ByteSize.
+func Bytes(key string, n int64) Field {
+	return zap.Reflect(key, ByteSize(n))
+}
+
+// Duration constructs a field with a time.Duration value. JSON encoders
+// log it in seconds; the pretty encoder, and the console encoder with
+// HumanUnits, as "1.2s" or "350ms".
+func Duration(key string, d time.Duration) Field {
+	return zap.Duration(key, d)
+}
+
+// humanEncoder wraps a text encoder so byte counts are rendered with
+// ByteSize.String instead of as plain numbers.
+type humanEncoder struct {
+	zapcore.Encoder
+}
+
+func (e *humanEncoder) Clone() zapcore.Encoder {
+	return &humanEncoder{e.Encoder.Clone()}
+}
+
+func (e *humanEncoder) AddReflected(key string, v interface{}) error {
+	if b, ok := v.(ByteSize); ok {
+		e.Encoder.AddString(key, b.String())
+		return nil
+	}
+	return e.Encoder.AddReflected(key, v)
+}
+
+func (e *humanEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	copied := false
+	for i, f := range fields {
+		b, ok := f.Interface.(ByteSize)
+		if !ok || f.Type != zapcore.ReflectType {
+			continue
+		}
+		// The caller's slice is not ours to modify.
+		if !copied {
+			fields = append([]zapcore.Field(nil), fields...)
+			copied = true
+		}
+		fields[i] = zap.String(f.Key, b.String())
+	}
+	return e.Encoder.EncodeEntry(ent, fields)
+}
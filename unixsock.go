@@ -0,0 +1,117 @@
+package log
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// UnixSocketConfig describes a unix domain socket output.
+type UnixSocketConfig struct {
+	// Path is the socket the collector listens on.
+	Path string
+	// Datagram selects a datagram socket, one entry per datagram. By
+	// default a stream socket is used, with entries terminated by a
+	// newline as written by the encoder.
+	Datagram bool
+	// WriteTimeout bounds a single write. Defaults to 1 second.
+	WriteTimeout time.Duration
+	// ReconnectWait is the delay before reconnecting after a failure. It
+	// doubles, with random jitter, after each failed attempt up to
+	// MaxReconnectWait. Default to 100 milliseconds and 10 seconds.
+	ReconnectWait    time.Duration
+	MaxReconnectWait time.Duration
+}
+
+// UnixSocketSink is a zapcore.WriteSyncer writing entries to a unix domain
+// socket, e.g. a local collector listening on a custom path. The
+// connection is established on first use and again after a failure;
+// entries written while it cannot be established fail, so that WithFallback
+// can route them elsewhere. Attach it with WithSink.
+type UnixSocketSink struct {
+	cfg     UnixSocketConfig
+	network string
+
+	mu    sync.Mutex
+	conn  net.Conn
+	wait  *backoff
+	retry time.Time
+	err   error
+}
+
+// NewUnixSocketSink returns a sink writing to cfg.Path.
+func NewUnixSocketSink(cfg UnixSocketConfig) (*UnixSocketSink, error) {
+	if cfg.Path == "" {
+		return nil, errors.New("log: unix socket path is required")
+	}
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = time.Second
+	}
+	if cfg.ReconnectWait <= 0 {
+		cfg.ReconnectWait = 100 * time.Millisecond
+	}
+	if cfg.MaxReconnectWait <= 0 {
+		cfg.MaxReconnectWait = 10 * time.Second
+	}
+	s := &UnixSocketSink{
+		cfg:     cfg,
+		network: "unix",
+		wait:    newBackoff(cfg.ReconnectWait, cfg.MaxReconnectWait),
+	}
+	if cfg.Datagram {
+		s.network = "unixgram"
+	}
+	return s, nil
+}
+
+// Write sends one encoded entry.
+func (s *UnixSocketSink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		if time.Now().Before(s.retry) {
+			return 0, s.err
+		}
+		conn, err := net.DialTimeout(s.network, s.cfg.Path, s.cfg.WriteTimeout)
+		if err != nil {
+			s.fail(err)
+			return 0, err
+		}
+		s.conn = conn
+	}
+	_ = s.conn.SetWriteDeadline(time.Now().Add(s.cfg.WriteTimeout))
+	n, err := s.conn.Write(p)
+	if err != nil {
+		s.conn.Close()
+		s.conn = nil
+		s.fail(err)
+		return n, err
+	}
+	s.wait.reset()
+	return n, nil
+}
+
+// fail records err and schedules the next connection attempt. The caller
+// holds s.mu.
+func (s *UnixSocketSink) fail(err error) {
+	s.err = err
+	s.retry = time.Now().Add(s.wait.next())
+}
+
+// Sync does nothing: entries are not buffered.
+func (s *UnixSocketSink) Sync() error {
+	return nil
+}
+
+// Close closes the connection.
+func (s *UnixSocketSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}